@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type cliMockDB struct {
+	database.DBInterface
+	tasks       map[string]database.Task
+	audit       []database.AuditEntry
+	attachments []database.Attachment
+	blobs       storage.BlobStore
+}
+
+func (db *cliMockDB) CloseConnection() {}
+
+func (db *cliMockDB) GetTasks() ([]database.Task, error) {
+	tasks := []database.Task{}
+	for _, t := range db.tasks {
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+func (db *cliMockDB) GetTaskByID(taskID string) (database.Task, error) {
+	return db.tasks[taskID], nil
+}
+
+func (db *cliMockDB) InsertSingleTask(task database.Task) error {
+	db.tasks[task.ID.Hex()] = task
+	return nil
+}
+
+func (db *cliMockDB) UpdateTaskID(taskID string, task database.Task) error {
+	db.tasks[taskID] = task
+	return nil
+}
+
+func (db *cliMockDB) DeleteTaskByID(taskID string) (int64, error) {
+	delete(db.tasks, taskID)
+	return 1, nil
+}
+
+func (db *cliMockDB) GetSubtasks(parentID string) ([]database.Task, error) {
+	subtasks := []database.Task{}
+	for _, t := range db.tasks {
+		if t.ParentID == parentID {
+			subtasks = append(subtasks, t)
+		}
+	}
+	return subtasks, nil
+}
+
+func (db *cliMockDB) InsertAuditEntry(entry database.AuditEntry) error {
+	db.audit = append(db.audit, entry)
+	return nil
+}
+
+func (db *cliMockDB) InsertAttachment(attachment database.Attachment) error {
+	db.attachments = append(db.attachments, attachment)
+	return nil
+}
+
+func (db *cliMockDB) GetAttachment(attachmentID string) (database.Attachment, error) {
+	for _, a := range db.attachments {
+		if a.ID.Hex() == attachmentID {
+			return a, nil
+		}
+	}
+	return database.Attachment{}, nil
+}
+
+func (db *cliMockDB) GetAttachments(taskID string) ([]database.Attachment, error) {
+	attachments := []database.Attachment{}
+	for _, a := range db.attachments {
+		if a.TaskID == taskID {
+			attachments = append(attachments, a)
+		}
+	}
+	return attachments, nil
+}
+
+func (db *cliMockDB) DeleteAttachment(attachmentID string) (int64, error) {
+	before := len(db.attachments)
+	db.attachments = slices.DeleteFunc(db.attachments, func(a database.Attachment) bool {
+		return a.ID.Hex() == attachmentID
+	})
+	return int64(before - len(db.attachments)), nil
+}
+
+func runCLI(t *testing.T, db *cliMockDB, args ...string) (string, error) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("db:\n  uri: mongodb://localhost:27017\n"), 0o600))
+
+	connectDB = func(cfg *config.Config) database.DBInterface {
+		return db
+	}
+	openBlobs = func(cfg *config.Config, _ database.DBInterface) (storage.BlobStore, error) {
+		return db.blobs, nil
+	}
+	database.Attachments = db
+
+	var out bytes.Buffer
+	root := newRootCmd()
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(append([]string{"--config", path}, args...))
+
+	err := root.Execute()
+	return out.String(), err
+}
+
+func TestCLI_TasksCreateAndList(t *testing.T) {
+	db := &cliMockDB{tasks: map[string]database.Task{}}
+
+	out, err := runCLI(t, db, "tasks", "create", "--name", "Write docs")
+	assert.NoError(t, err)
+	taskID := strings.TrimSpace(out)
+	assert.Equal(t, "Write docs", db.tasks[taskID].Name)
+	assert.Equal(t, database.AuditCreate, db.audit[0].Action)
+
+	out, err = runCLI(t, db, "tasks", "list")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "ID")
+	assert.Contains(t, out, taskID)
+	assert.Contains(t, out, "incomplete")
+}
+
+func TestCLI_TasksCreateRequiresName(t *testing.T) {
+	db := &cliMockDB{tasks: map[string]database.Task{}}
+
+	_, err := runCLI(t, db, "tasks", "create")
+
+	assert.ErrorContains(t, err, "--name is required")
+	assert.Empty(t, db.tasks)
+}
+
+func TestCLI_TasksCompleteAndDelete(t *testing.T) {
+	id := primitive.NewObjectID()
+	db := &cliMockDB{tasks: map[string]database.Task{
+		id.Hex(): {ID: id, Name: "Write docs", Status: 0},
+	}}
+
+	_, err := runCLI(t, db, "tasks", "complete", id.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, db.tasks[id.Hex()].Status)
+	assert.Equal(t, 0, db.audit[0].Before.Status)
+	assert.Equal(t, 1, db.audit[0].After.Status)
+
+	_, err = runCLI(t, db, "tasks", "delete", id.Hex())
+	assert.NoError(t, err)
+	assert.Empty(t, db.tasks)
+
+	_, err = runCLI(t, db, "tasks", "delete", id.Hex())
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestCLI_TasksCompleteBlocked(t *testing.T) {
+	blocker, blocked := primitive.NewObjectID(), primitive.NewObjectID()
+	db := &cliMockDB{tasks: map[string]database.Task{
+		blocker.Hex(): {ID: blocker, Name: "Review docs"},
+		blocked.Hex(): {ID: blocked, Name: "Publish docs", BlockedBy: []string{blocker.Hex()}},
+	}}
+
+	_, err := runCLI(t, db, "tasks", "complete", blocked.Hex())
+
+	assert.ErrorContains(t, err, "blocked")
+	assert.Equal(t, 0, db.tasks[blocked.Hex()].Status)
+	assert.Empty(t, db.audit)
+}
+
+func TestCLI_TasksCompleteRecurring(t *testing.T) {
+	id := primitive.NewObjectID()
+	due := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	db := &cliMockDB{tasks: map[string]database.Task{
+		id.Hex(): {ID: id, Name: "Standup", Recurrence: "FREQ=DAILY", Due: &due},
+	}}
+
+	_, err := runCLI(t, db, "tasks", "complete", id.Hex())
+	assert.NoError(t, err)
+
+	assert.Len(t, db.tasks, 2)
+	for taskID, task := range db.tasks {
+		if taskID != id.Hex() {
+			assert.Equal(t, due.AddDate(0, 0, 1), *task.Due)
+			assert.Equal(t, 0, task.Status)
+		}
+	}
+}
+
+func TestCLI_TasksDeleteAttachments(t *testing.T) {
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+
+	id := primitive.NewObjectID()
+	attachment := database.Attachment{ID: primitive.NewObjectID(), TaskID: id.Hex(), Filename: "notes.txt"}
+	_, err = blobs.Put(context.Background(), attachment.ID.Hex(), strings.NewReader("notes"))
+	assert.NoError(t, err)
+
+	db := &cliMockDB{
+		tasks:       map[string]database.Task{id.Hex(): {ID: id, Name: "Write docs"}},
+		attachments: []database.Attachment{attachment},
+		blobs:       blobs,
+	}
+
+	_, err = runCLI(t, db, "tasks", "delete", id.Hex())
+	assert.NoError(t, err)
+
+	assert.Empty(t, db.attachments)
+	_, err = blobs.Open(context.Background(), attachment.ID.Hex())
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestCLI_ConfigValidate(t *testing.T) {
+	out, err := runCLI(t, nil, "config", "validate")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "config is valid")
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("server:\n  port: 0\n"), 0o600))
+
+	root := newRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"config", "validate", "--config", path})
+
+	assert.ErrorContains(t, root.Execute(), "db.uri is required")
+}
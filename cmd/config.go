@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+func newConfigCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	validate := &cobra.Command{
+		Use:   "validate",
+		Short: "Check that the config loads and is valid",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+			fmt.Fprintln(cmd.OutOrStdout(), cfg)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(validate)
+
+	return cmd
+}
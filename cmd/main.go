@@ -1,29 +1,11 @@
 package main
 
 import (
-	"github.com/spf13/viper"
-	"github.com/tiffany831101/bs_pretest.git/config"
-	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"os"
 )
 
-func init() {
-	config.LoadConfig()
-
-	initMongoDB()
-
-}
-
 func main() {
-
-	server := StartServer()
-	server.SetUpRoutes()
-
-	server.RunSwagger()
-	server.Run()
-}
-
-func initMongoDB() {
-	dbURI := viper.GetString("db.localURI")
-	dbName := viper.GetString("db.name")
-	database.NewDB(dbURI, dbName)
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
 }
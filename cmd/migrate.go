@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+)
+
+func newMigrateCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage database migrations",
+	}
+
+	connect := func() (*database.DB, error) {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			return nil, err
+		}
+		return database.NewDB(cfg.DB), nil
+	}
+
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := connect()
+			if err != nil {
+				return err
+			}
+			defer db.CloseConnection()
+
+			applied, err := db.MigrateUp()
+			for _, m := range applied {
+				fmt.Fprintf(cmd.OutOrStdout(), "applied %d: %s\n", m.Version, m.Name)
+			}
+			if err == nil && len(applied) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no pending migrations")
+			}
+			return err
+		},
+	}
+
+	var steps int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the most recently applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := connect()
+			if err != nil {
+				return err
+			}
+			defer db.CloseConnection()
+
+			reverted, err := db.MigrateDown(steps)
+			for _, m := range reverted {
+				fmt.Fprintf(cmd.OutOrStdout(), "reverted %d: %s\n", m.Version, m.Name)
+			}
+			return err
+		},
+	}
+	down.Flags().IntVar(&steps, "steps", 1, "number of migrations to revert")
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show which migrations are applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := connect()
+			if err != nil {
+				return err
+			}
+			defer db.CloseConnection()
+
+			statuses, err := db.MigrationStatus()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+			for _, s := range statuses {
+				applied := "pending"
+				if s.Applied {
+					applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.AddCommand(up, down, status)
+
+	return cmd
+}
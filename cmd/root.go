@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/storage"
+)
+
+// connectDB opens the database for the commands that need one. Tests replace
+// it with a mock.
+var connectDB = func(cfg *config.Config) database.DBInterface {
+	return database.NewDB(cfg.DB)
+}
+
+// openBlobs opens the store of attachment contents for the commands that
+// delete attachments. Tests replace it with a mock.
+var openBlobs = func(cfg *config.Config, db database.DBInterface) (storage.BlobStore, error) {
+	mongoDB, ok := db.(*database.DB)
+	if !ok {
+		return nil, errors.New("attachments need a MongoDB database")
+	}
+	return storage.New(cfg.Attachments, mongoDB.Database())
+}
+
+func newRootCmd() *cobra.Command {
+	var configPath string
+
+	root := &cobra.Command{
+		Use:           "pretest-go",
+		Short:         "Tasks API server and admin tool",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+
+	root.PersistentFlags().StringVar(&configPath, "config", "", "path to the config file (default: config.yml in ../, /app or .)")
+
+	root.AddCommand(
+		newServeCmd(&configPath),
+		newMigrateCmd(&configPath),
+		newTasksCmd(&configPath),
+		newConfigCmd(&configPath),
+	)
+
+	return root
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/storage"
+)
+
+func newServeCmd(configPath *string) *cobra.Command {
+	var migrate bool
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP and gRPC API servers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			live, err := config.LoadLive(*configPath)
+			if err != nil {
+				return err
+			}
+			live.Watch()
+			log.SetOutput(live.LogWriter(os.Stderr))
+
+			cfg := live.Config()
+			log.Println("Loaded config: ", cfg)
+
+			db := database.NewDB(cfg.DB)
+
+			if migrate {
+				if _, err := db.MigrateUp(); err != nil {
+					return err
+				}
+			}
+
+			blobs, err := storage.New(cfg.Attachments, db.Database())
+			if err != nil {
+				return err
+			}
+
+			server := StartServer(live)
+			server.UseBlobStore(blobs)
+			server.SetUpRoutes()
+			server.StartWorkers()
+
+			if err := server.RunGRPC(); err != nil {
+				return err
+			}
+
+			server.RunSwagger()
+			server.Run()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&migrate, "migrate", true, "apply pending migrations before serving")
+
+	return cmd
+}
@@ -1,42 +1,152 @@
 package main
 
 import (
+	"context"
+	"log"
+	"net"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
-	"github.com/spf13/viper"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
+	"github.com/tiffany831101/bs_pretest.git/config"
 	"github.com/tiffany831101/bs_pretest.git/docs"
 	"github.com/tiffany831101/bs_pretest.git/internal/controller"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/internal/notify"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
+	"github.com/tiffany831101/bs_pretest.git/internal/reminder"
+	"github.com/tiffany831101/bs_pretest.git/internal/storage"
+	"github.com/tiffany831101/bs_pretest.git/internal/watchers"
+	"github.com/tiffany831101/bs_pretest.git/internal/webhook"
 )
 
 type Server struct {
 	engine *gin.Engine
+	config *config.Live
+	blobs  storage.BlobStore
 }
 
-func StartServer() *Server {
-	router := gin.Default()
+func StartServer(live *config.Live) *Server {
+	router := gin.New()
+	router.Use(gin.LoggerWithWriter(live.LogWriter(gin.DefaultWriter)), gin.Recovery())
+
+	// The rate limit is per client IP, which only trusted proxies may set.
+	if err := router.SetTrustedProxies(live.Config().Server.TrustedProxies); err != nil {
+		log.Fatal(err)
+	}
+
+	router.Use(middleware.RequestContext())
+	router.Use(middleware.CORS(live))
+	router.Use(middleware.RateLimit(live))
+	router.Use(middleware.Feature(live, "graphql", "/graphql"))
 
 	router.GET("/ping", func(c *gin.Context) {
 		c.String(200, "pong")
 	})
 	return &Server{
 		engine: router,
+		config: live,
 	}
 }
 
 func (s *Server) Run() {
-	port := viper.GetString("server.port")
-	s.engine.Run(":" + port)
+	s.engine.Run(":" + strconv.Itoa(s.config.Config().Server.Port))
+}
+
+// RunGRPC serves the gRPC TaskService on server.grpcPort in the background.
+// It only returns an error when the port cannot be bound.
+func (s *Server) RunGRPC() error {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(s.config.Config().Server.GRPCPort))
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := controller.NewGRPCServer().Serve(listener); err != nil {
+			log.Println("Error Serving gRPC: ", err)
+		}
+	}()
+
+	return nil
+}
+
+// UseBlobStore keeps the contents of task attachments in blobs. The
+// attachment routes are only set up when a store is given.
+func (s *Server) UseBlobStore(blobs storage.BlobStore) {
+	s.blobs = blobs
 }
 
 func (s *Server) SetUpRoutes() {
 
 	controller.NewTasksController()
+	controller.UseStatusWorkflow(s.config)
 	controller.SetUpTasksRoutes(s.engine)
+	controller.SetUpWSRoutes(s.engine, s.config)
+	controller.SetUpGraphQLRoutes(s.engine)
+
+	controller.NewProjectController()
+	controller.SetUpProjectRoutes(s.engine)
+
+	controller.NewLabelController()
+	controller.SetUpLabelRoutes(s.engine)
+
+	controller.NewCommentController()
+	controller.SetUpCommentRoutes(s.engine)
+
+	if s.blobs != nil {
+		controller.NewAttachmentController(s.blobs, s.config.Config().Attachments)
+		controller.SetUpAttachmentRoutes(s.engine)
+	}
+
+	controller.NewAuditController()
+	controller.SetUpAuditRoutes(s.engine)
+
+	controller.NewWebhookController()
+	controller.SetUpWebhookRoutes(s.engine)
+
+	controller.NewAdminController(s.config)
+	controller.SetUpAdminRoutes(s.engine)
+}
+
+// StartWorkers starts the background consumers of the event bus, the
+// scheduler of recurring tasks, the due-date reminders, the notifications to
+// the watchers of changed tasks, and the change stream watcher that feeds the
+// bus with other replicas' changes when events.changeStream is enabled. It
+// must be called after SetUpRoutes.
+func (s *Server) StartWorkers() {
+	ctx := context.Background()
+	cfg := s.config.Config()
+
+	webhook.NewDispatcher(database.MongoDB).Start(ctx, events.Default)
+	controller.StartScheduler(ctx, recurrence.SystemClock{})
+
+	notifier, err := notify.New(cfg.Notifications)
+	if err != nil {
+		log.Println("Error Starting Notifications: ", err)
+	} else {
+		reminders := reminder.NewWorker(database.MongoDB, notifier, recurrence.SystemClock{}, cfg.Reminders.Interval, cfg.Reminders.Lead)
+		reminders.MarkedOverdue = controller.TaskMarkedOverdue
+		reminders.Start(ctx)
+		watchers.NewWorker(notifier).Start(ctx, events.Default)
+	}
+
+	if cfg.Events.ChangeStream {
+		go func() {
+			err := database.MongoDB.WatchTasks(ctx, cfg.Events.InstanceID, func(change database.TaskChange) {
+				events.Default.Publish(events.NewRemoteTaskEvent(change))
+			})
+			if err != nil {
+				log.Println("Error Watching Task Changes: ", err)
+			}
+		}()
+	}
 }
 
 func (s *Server) RunSwagger() {
-	docs.SwaggerInfo.BasePath = "/api/" + viper.GetString("api.version")
+	docs.SwaggerInfo.BasePath = "/api/" + s.config.Config().API.Version
 	s.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 }
@@ -1,26 +1,48 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
 )
 
+type mockDB struct {
+	database.DBInterface
+}
+
+func (db *mockDB) GetTasks() ([]database.Task, error) {
+	return []database.Task{}, nil
+}
+
+func (db *mockDB) FindTasks(ctx context.Context, filter database.TaskFilter, offset, limit int64) (*database.TaskCursor, error) {
+	return database.NewTaskCursor(nil)
+}
+
+var testConfig = &config.Config{
+	DB:     config.DBConfig{Name: "pretest", URI: "mongodb://localhost:27017"},
+	Server: config.ServerConfig{Port: 8080},
+	API:    config.APIConfig{Version: "v1"},
+}
+
 func TestStartServer(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	s := StartServer()
+	s := StartServer(config.NewLive(testConfig))
 
 	assert.NotNil(t, s)
 	assert.NotNil(t, s.engine)
+	assert.Equal(t, testConfig, s.config.Config())
 }
 
 func TestServer_Run(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
-	s := StartServer()
+	s := StartServer(config.NewLive(testConfig))
 
 	w := httptest.NewRecorder()
 
@@ -35,7 +57,8 @@ func TestServer_Run(t *testing.T) {
 func TestServer_SetUpRoutes(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
-	s := StartServer()
+	database.MongoDB = &mockDB{}
+	s := StartServer(config.NewLive(testConfig))
 	s.SetUpRoutes()
 	w := httptest.NewRecorder()
 
@@ -0,0 +1,12 @@
+// Command taskctl manages tasks on a running server through the HTTP API.
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/controller"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/pkg/client"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type taskctlMockDB struct {
+	database.DBInterface
+	tasks map[string]database.Task
+}
+
+func (db *taskctlMockDB) GetTasks() ([]database.Task, error) {
+	tasks := []database.Task{}
+	for _, t := range db.tasks {
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+func (db *taskctlMockDB) FindTasks(ctx context.Context, filter database.TaskFilter, offset, limit int64) (*database.TaskCursor, error) {
+	all, _ := db.GetTasks()
+	sort.Slice(all, func(i, j int) bool { return all[i].ID.Hex() < all[j].ID.Hex() })
+
+	tasks := []database.Task{}
+	for _, t := range all {
+		if filter.Matches(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	tasks = tasks[min(offset, int64(len(tasks))):]
+	if limit > 0 {
+		tasks = tasks[:min(limit, int64(len(tasks)))]
+	}
+	return database.NewTaskCursor(tasks)
+}
+
+func (db *taskctlMockDB) GetSubtasks(parentID string) ([]database.Task, error) {
+	subtasks := []database.Task{}
+	for _, t := range db.tasks {
+		if t.ParentID == parentID {
+			subtasks = append(subtasks, t)
+		}
+	}
+	return subtasks, nil
+}
+
+func (db *taskctlMockDB) GetTaskByID(taskID string) (database.Task, error) {
+	task, ok := db.tasks[taskID]
+	if !ok {
+		return task, mongo.ErrNoDocuments
+	}
+	return task, nil
+}
+
+func (db *taskctlMockDB) InsertSingleTask(task database.Task) error {
+	db.tasks[task.ID.Hex()] = task
+	return nil
+}
+
+func (db *taskctlMockDB) UpdateTaskID(taskID string, task database.Task) error {
+	db.tasks[taskID] = task
+	return nil
+}
+
+func (db *taskctlMockDB) DeleteTaskByID(taskID string) (int64, error) {
+	if _, ok := db.tasks[taskID]; !ok {
+		return 0, nil
+	}
+	delete(db.tasks, taskID)
+	return 1, nil
+}
+
+func (db *taskctlMockDB) InsertAuditEntry(entry database.AuditEntry) error {
+	return nil
+}
+
+func (db *taskctlMockDB) InsertIdempotencyRecord(record database.IdempotencyRecord) error {
+	return nil
+}
+
+func (db *taskctlMockDB) SaveIdempotencyRecord(record database.IdempotencyRecord) error {
+	return nil
+}
+
+// newTestServer serves the real task routes backed by an in-memory database
+// and records the Authorization header of the last request.
+func newTestServer(t *testing.T) (*httptest.Server, *taskctlMockDB, *string) {
+	gin.SetMode(gin.TestMode)
+	db := &taskctlMockDB{tasks: map[string]database.Task{}}
+	database.MongoDB = db
+
+	var auth string
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		auth = c.GetHeader("Authorization")
+	})
+	r.Use(middleware.RequestContext())
+	controller.NewTasksController()
+	controller.SetUpTasksRoutes(r)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, db, &auth
+}
+
+func runTaskctl(t *testing.T, configPath string, args ...string) (string, error) {
+	var out bytes.Buffer
+	root := newRootCmd()
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(append([]string{"--config", configPath}, args...))
+
+	err := root.Execute()
+	return out.String(), err
+}
+
+func TestTaskctl_LoginAndAuthToken(t *testing.T) {
+	srv, _, auth := newTestServer(t)
+	configPath := filepath.Join(t.TempDir(), "taskctl", "config.yaml")
+
+	_, err := runTaskctl(t, configPath, "login", "--server", srv.URL, "--token", "s3cret", "--user", "alice")
+	assert.NoError(t, err)
+
+	info, err := os.Stat(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, err = runTaskctl(t, configPath, "list")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer s3cret", *auth)
+}
+
+func TestTaskctl_AddDoneRm(t *testing.T) {
+	srv, db, _ := newTestServer(t)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, saveSettings(configPath, settings{Server: srv.URL}))
+
+	out, err := runTaskctl(t, configPath, "add", "Write docs")
+	assert.NoError(t, err)
+	taskID := strings.TrimSpace(out)
+	assert.Equal(t, "Write docs", db.tasks[taskID].Name)
+
+	_, err = runTaskctl(t, configPath, "done", taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, int(client.Completed), db.tasks[taskID].Status)
+
+	out, err = runTaskctl(t, configPath, "list")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Write docs")
+	assert.Contains(t, out, "completed")
+
+	_, err = runTaskctl(t, configPath, "rm", taskID)
+	assert.NoError(t, err)
+	assert.Empty(t, db.tasks)
+
+	_, err = runTaskctl(t, configPath, "get", taskID)
+	var apiErr *client.Error
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 404, apiErr.StatusCode)
+}
+
+func TestTaskctl_OutputFormats(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, saveSettings(configPath, settings{Server: srv.URL}))
+
+	out, err := runTaskctl(t, configPath, "add", "Write docs")
+	assert.NoError(t, err)
+	taskID := strings.TrimSpace(out)
+
+	out, err = runTaskctl(t, configPath, "get", taskID, "-o", "json")
+	assert.NoError(t, err)
+	var task client.Task
+	assert.NoError(t, json.Unmarshal([]byte(out), &task))
+	assert.Equal(t, client.Task{ID: taskID, Name: "Write docs", Status: client.Incomplete}, task)
+
+	out, err = runTaskctl(t, configPath, "list", "-o", "yaml")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "- id: "+taskID)
+
+	_, err = runTaskctl(t, configPath, "list", "-o", "xml")
+	assert.Error(t, err)
+}
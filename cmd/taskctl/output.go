@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/tiffany831101/bs_pretest.git/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML}
+
+func validOutput(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, use table, json or yaml", format)
+}
+
+func printTasks(w io.Writer, format string, tasks []client.Task) error {
+	switch format {
+	case outputJSON:
+		return printJSON(w, tasks)
+	case outputYAML:
+		return yaml.NewEncoder(w).Encode(tasks)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTATUS")
+	for _, t := range tasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", t.ID, t.Name, t.Status)
+	}
+	return tw.Flush()
+}
+
+// printEvent writes a single event: one line for table and JSON output, one
+// document for YAML, so that `watch` output can be piped.
+func printEvent(w io.Writer, format string, e client.Event) error {
+	switch format {
+	case outputJSON:
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case outputYAML:
+		b, err := yaml.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", b)
+		return err
+	}
+
+	if e.Type == client.Reset {
+		_, err := fmt.Fprintln(w, "events were missed, reload the task list")
+		return err
+	}
+
+	name, status := "", ""
+	if e.Task != nil {
+		name, status = e.Task.Name, e.Task.Status.String()
+	}
+	_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", e.Seq, e.Type, e.TaskID, name, status, e.Actor)
+	return err
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"github.com/tiffany831101/bs_pretest.git/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// globalOptions are the flags shared by every command. Server and token
+// override the values from the config file; TASKCTL_SERVER and TASKCTL_TOKEN
+// do the same from the environment.
+type globalOptions struct {
+	configPath string
+	server     string
+	token      string
+	output     string
+}
+
+func (o *globalOptions) settings() (settings, error) {
+	s, err := loadSettings(o.configPath)
+	if err != nil {
+		return s, fmt.Errorf("read %s: %w", o.configPath, err)
+	}
+
+	if v := os.Getenv("TASKCTL_SERVER"); v != "" {
+		s.Server = v
+	}
+	if v := os.Getenv("TASKCTL_TOKEN"); v != "" {
+		s.Token = v
+	}
+	if o.server != "" {
+		s.Server = o.server
+	}
+	if o.token != "" {
+		s.Token = o.token
+	}
+	return s, nil
+}
+
+func (o *globalOptions) client() (*client.Client, error) {
+	s, err := o.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Option{client.WithToken(s.Token)}
+	if s.User != "" {
+		opts = append(opts, client.WithUser(s.User))
+	}
+	return client.New(s.Server, opts...), nil
+}
+
+func newRootCmd() *cobra.Command {
+	opts := &globalOptions{}
+
+	root := &cobra.Command{
+		Use:          "taskctl",
+		Short:        "Manage tasks on a running server",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validOutput(opts.output)
+		},
+	}
+
+	root.PersistentFlags().StringVar(&opts.configPath, "config", defaultSettingsPath(), "path to the taskctl config file")
+	root.PersistentFlags().StringVar(&opts.server, "server", "", "URL of the server (overrides the config file)")
+	root.PersistentFlags().StringVar(&opts.token, "token", "", "auth token (overrides the config file)")
+	root.PersistentFlags().StringVarP(&opts.output, "output", "o", outputTable, "output format: table, json or yaml")
+	root.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return outputFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	root.AddCommand(
+		newLoginCmd(opts),
+		newListCmd(opts),
+		newGetCmd(opts),
+		newAddCmd(opts),
+		newDoneCmd(opts),
+		newRmCmd(opts),
+		newWatchCmd(opts),
+	)
+
+	return root
+}
+
+func newLoginCmd(opts *globalOptions) *cobra.Command {
+	var user string
+
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Save the server URL and auth token to the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := opts.settings()
+			if err != nil {
+				return err
+			}
+			if opts.token == "" {
+				return errors.New("--token is required")
+			}
+			if user != "" {
+				s.User = user
+			}
+
+			if err := saveSettings(opts.configPath, s); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "saved", opts.configPath)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&user, "user", "", "user recorded as the actor of your changes")
+
+	return cmd
+}
+
+func newListCmd(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			tasks, err := c.List(cmd.Context(), nil).All()
+			if err != nil {
+				return err
+			}
+			return printTasks(cmd.OutOrStdout(), opts.output, tasks)
+		},
+	}
+}
+
+func newGetCmd(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:               "get <id>",
+		Short:             "Show a task",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTaskIDs(opts),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			task, err := c.Get(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			switch opts.output {
+			case outputJSON:
+				return printJSON(cmd.OutOrStdout(), task)
+			case outputYAML:
+				return yaml.NewEncoder(cmd.OutOrStdout()).Encode(task)
+			}
+			return printTasks(cmd.OutOrStdout(), opts.output, []client.Task{task})
+		},
+	}
+}
+
+func newAddCmd(opts *globalOptions) *cobra.Command {
+	var completed bool
+
+	cmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Create a task and print its ID",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			in := client.TaskInput{Name: args[0], Status: client.Incomplete}
+			if completed {
+				in.Status = client.Completed
+			}
+
+			id, err := c.Create(cmd.Context(), in)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), id)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&completed, "completed", false, "create the task as completed")
+
+	return cmd
+}
+
+func newDoneCmd(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:               "done <id>",
+		Short:             "Mark a task as completed",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTaskIDs(opts),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			status := client.Completed
+			if _, err := c.Patch(cmd.Context(), args[0], client.TaskPatch{Status: &status}); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "completed", args[0])
+			return nil
+		},
+	}
+}
+
+func newRmCmd(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:               "rm <id>",
+		Short:             "Delete a task",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTaskIDs(opts),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			if err := c.Delete(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "deleted", args[0])
+			return nil
+		},
+	}
+}
+
+func newWatchCmd(opts *globalOptions) *cobra.Command {
+	var since uint64
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Print task changes as they happen, until interrupted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+
+			err = c.Watch(ctx, since, func(e client.Event) error {
+				return printEvent(cmd.OutOrStdout(), opts.output, e)
+			})
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		},
+	}
+	cmd.Flags().Uint64Var(&since, "since", 0, "replay the buffered events after this sequence number first")
+
+	return cmd
+}
+
+// completeTaskIDs completes task IDs from the server, showing each task's
+// name as the description.
+func completeTaskIDs(opts *globalOptions) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		c, err := opts.client()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		tasks, err := c.List(cmd.Context(), nil).All()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		ids := make([]string, 0, len(tasks))
+		for _, t := range tasks {
+			ids = append(ids, t.ID+"\t"+t.Name)
+		}
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	}
+}
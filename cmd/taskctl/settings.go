@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// settings is the local taskctl config file, written by `taskctl login`.
+type settings struct {
+	Server string `yaml:"server"`
+	Token  string `yaml:"token,omitempty"`
+	User   string `yaml:"user,omitempty"`
+}
+
+const defaultServer = "http://localhost:8080"
+
+// defaultSettingsPath is $XDG_CONFIG_HOME/taskctl/config.yaml, or the
+// platform equivalent.
+func defaultSettingsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "taskctl.yaml"
+	}
+	return filepath.Join(dir, "taskctl", "config.yaml")
+}
+
+// loadSettings reads the config file at path. A missing file is not an
+// error: taskctl then talks to the default server without a token.
+func loadSettings(path string) (settings, error) {
+	s := settings{Server: defaultServer}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		return s, err
+	}
+	if s.Server == "" {
+		s.Server = defaultServer
+	}
+	return s, nil
+}
+
+// saveSettings writes the config file readable by the current user only, as
+// it holds the token.
+func saveSettings(path string, s settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
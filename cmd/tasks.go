@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/controller"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTasksCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tasks",
+		Short: "Manage tasks directly in the database",
+	}
+
+	// connect opens the database and sets up the task controller, through
+	// which every change is made so that it gets the same checks and
+	// follow-up as one made through the API.
+	connect := func() (*config.Config, database.DBInterface, error) {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		db := connectDB(cfg)
+		database.MongoDB = db
+		controller.NewTasksController()
+		controller.UseStatusWorkflow(config.NewLive(cfg))
+		return cfg, db, nil
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List all tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, db, err := connect()
+			if err != nil {
+				return err
+			}
+			defer db.CloseConnection()
+
+			tasks, err := db.GetTasks()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tSTATUS")
+			for _, t := range tasks {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", t.ID.Hex(), t.Name, statusName(t.Status))
+			}
+			return w.Flush()
+		},
+	}
+
+	var name string
+	var status int
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a task",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return errors.New("--name is required")
+			}
+			if status != int(controller.Incomplete) && status != int(controller.Completed) {
+				return fmt.Errorf("--status must be %d or %d", controller.Incomplete, controller.Completed)
+			}
+
+			_, db, err := connect()
+			if err != nil {
+				return err
+			}
+			defer db.CloseConnection()
+
+			task, err := controller.CreateTask(cliActor(), name, controller.TaskStatus(status))
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), task.ID.Hex())
+			return nil
+		},
+	}
+	create.Flags().StringVar(&name, "name", "", "name of the task")
+	create.Flags().IntVar(&status, "status", int(controller.Incomplete), "status of the task (0 incomplete, 1 completed)")
+
+	complete := &cobra.Command{
+		Use:   "complete <id>",
+		Short: "Mark a task as completed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, db, err := connect()
+			if err != nil {
+				return err
+			}
+			defer db.CloseConnection()
+
+			if _, err := findTask(db, args[0]); err != nil {
+				return err
+			}
+
+			if _, err := controller.CompleteTask(cliActor(), args[0]); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "completed", args[0])
+			return nil
+		},
+	}
+
+	remove := &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, db, err := connect()
+			if err != nil {
+				return err
+			}
+			defer db.CloseConnection()
+
+			if _, err := findTask(db, args[0]); err != nil {
+				return err
+			}
+
+			// The attachments of the task are deleted with it.
+			blobs, err := openBlobs(cfg, db)
+			if err != nil {
+				return err
+			}
+			controller.NewAttachmentController(blobs, cfg.Attachments)
+
+			if err := controller.DeleteTask(cliActor(), args[0]); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "deleted", args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(list, create, complete, remove)
+
+	return cmd
+}
+
+func findTask(db database.DBInterface, taskID string) (database.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return database.Task{}, fmt.Errorf("invalid task ID %q, should be in hex format", taskID)
+	}
+
+	task, err := db.GetTaskByID(taskID)
+	if err != nil || task.ID.IsZero() {
+		return database.Task{}, fmt.Errorf("task %s not found", taskID)
+	}
+
+	return task, nil
+}
+
+// cliActor is the actor changes made from the command line are attributed to
+// in the audit log: the operating system user.
+func cliActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
+
+func statusName(status int) string {
+	if status == int(controller.Completed) {
+		return "completed"
+	}
+	return "incomplete"
+}
@@ -1,15 +1,299 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
-func LoadConfig() {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yml")
-	viper.AddConfigPath("../")
-	viper.AddConfigPath("/app")
-	if err := viper.ReadInConfig(); err != nil {
-		panic(err)
+// EnvPrefix is the prefix of environment variables overriding config keys,
+// e.g. APP_DB_URI overrides db.uri.
+const EnvPrefix = "APP"
+
+type Config struct {
+	DB      DBConfig      `mapstructure:"db" json:"db"`
+	Server  ServerConfig  `mapstructure:"server" json:"server"`
+	API     APIConfig     `mapstructure:"api" json:"api"`
+	Events  EventsConfig  `mapstructure:"events" json:"events"`
+	Runtime RuntimeConfig `mapstructure:"runtime" json:"runtime"`
+
+	Notifications NotificationsConfig `mapstructure:"notifications" json:"notifications"`
+	Reminders     RemindersConfig     `mapstructure:"reminders" json:"reminders"`
+	Attachments   AttachmentsConfig   `mapstructure:"attachments" json:"attachments"`
+}
+
+type DBConfig struct {
+	Name     string `mapstructure:"name" json:"name"`
+	Username string `mapstructure:"username" json:"username"`
+	Password string `mapstructure:"password" json:"password" secret:"true"`
+	URI      string `mapstructure:"uri" json:"uri" secret:"uri"`
+}
+
+type ServerConfig struct {
+	Port int `mapstructure:"port" json:"port"`
+	// GRPCPort serves the gRPC TaskService next to the HTTP API.
+	GRPCPort int `mapstructure:"grpcPort" json:"grpcPort"`
+	// TrustedProxies are the IPs and CIDR ranges of the reverse proxies whose
+	// X-Forwarded-For header gives the client IP. None are trusted by default,
+	// so the client IP is the address of the connection.
+	TrustedProxies []string `mapstructure:"trustedProxies" json:"trustedProxies"`
+}
+
+type APIConfig struct {
+	Version string `mapstructure:"version" json:"version"`
+}
+
+type EventsConfig struct {
+	ChangeStream bool `mapstructure:"changeStream" json:"changeStream"`
+	// InstanceID names this replica in the change stream; its resume token
+	// is stored under it. It must stay the same across restarts of a replica
+	// and differ between replicas.
+	InstanceID string `mapstructure:"instanceID" json:"instanceID"`
+}
+
+// NotificationsConfig selects the channel reminders are sent through: log,
+// webhook or smtp.
+type NotificationsConfig struct {
+	Channel string                `mapstructure:"channel" json:"channel"`
+	Webhook WebhookNotifierConfig `mapstructure:"webhook" json:"webhook"`
+	SMTP    SMTPConfig            `mapstructure:"smtp" json:"smtp"`
+}
+
+type WebhookNotifierConfig struct {
+	URL string `mapstructure:"url" json:"url"`
+	// Secret signs the body like webhook deliveries; empty sends it unsigned.
+	Secret string `mapstructure:"secret" json:"secret" secret:"true"`
+}
+
+type SMTPConfig struct {
+	Host     string   `mapstructure:"host" json:"host"`
+	Port     int      `mapstructure:"port" json:"port"`
+	Username string   `mapstructure:"username" json:"username"`
+	Password string   `mapstructure:"password" json:"password" secret:"true"`
+	From     string   `mapstructure:"from" json:"from"`
+	To       []string `mapstructure:"to" json:"to"`
+}
+
+type RemindersConfig struct {
+	// Interval between checks for tasks nearing or past their due date; 0
+	// disables reminders.
+	Interval time.Duration `mapstructure:"interval" json:"interval"`
+	// Lead is how long before its due date a task is reminded of.
+	Lead time.Duration `mapstructure:"lead" json:"lead"`
+}
+
+// AttachmentsConfig selects where attachments are stored, local or gridfs,
+// and limits what can be uploaded.
+type AttachmentsConfig struct {
+	Store string `mapstructure:"store" json:"store"`
+	// Dir is the directory of the local store.
+	Dir string `mapstructure:"dir" json:"dir"`
+	// Bucket is the GridFS bucket of the gridfs store.
+	Bucket string `mapstructure:"bucket" json:"bucket"`
+	// MaxSize is the largest attachment accepted, in bytes.
+	MaxSize int64 `mapstructure:"maxSize" json:"maxSize"`
+	// AllowedTypes are the accepted media types, as detected from the
+	// content; "image/*" accepts every image type.
+	AllowedTypes []string `mapstructure:"allowedTypes" json:"allowedTypes"`
+}
+
+// RuntimeConfig holds the settings that are reloaded while the server is
+// running. Everything outside of it needs a restart to change.
+type RuntimeConfig struct {
+	CORS      CORSConfig      `mapstructure:"cors" json:"cors"`
+	RateLimit RateLimitConfig `mapstructure:"rateLimit" json:"rateLimit"`
+	Features  map[string]bool `mapstructure:"features" json:"features"`
+	// LogLevel is info to log everything, or error to only log errors.
+	LogLevel string `mapstructure:"logLevel" json:"logLevel"`
+	// StatusWorkflow restricts which status changes tasks may make.
+	StatusWorkflow StatusWorkflow `mapstructure:"statusWorkflow" json:"statusWorkflow"`
+}
+
+type CORSConfig struct {
+	// Origins allowed to call the API from a browser; "*" allows any.
+	Origins []string `mapstructure:"origins" json:"origins"`
+}
+
+type RateLimitConfig struct {
+	// RequestsPerSecond per client IP; 0 disables rate limiting.
+	RequestsPerSecond float64 `mapstructure:"requestsPerSecond" json:"requestsPerSecond"`
+	Burst             int     `mapstructure:"burst" json:"burst"`
+}
+
+// FeatureEnabled reports whether the named feature flag is on. Flag names are
+// case-insensitive.
+func (c *Config) FeatureEnabled(name string) bool {
+	return c.Runtime.Features[strings.ToLower(name)]
+}
+
+// LoadConfig reads the config file at path, or config.yml from the default
+// locations when path is empty, applies environment overrides, resolves
+// file:// and env:// secret references and validates the result.
+func LoadConfig(path string) (*Config, error) {
+	v := newViper(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	return decode(v)
+}
+
+func newViper(path string) *viper.Viper {
+	v := viper.New()
+
+	v.SetDefault("db.name", "pretest")
+	v.SetDefault("db.username", "")
+	v.SetDefault("db.password", "")
+	v.SetDefault("db.uri", "")
+	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.grpcPort", 9090)
+	v.SetDefault("api.version", "v1")
+	v.SetDefault("events.changeStream", false)
+	v.SetDefault("events.instanceID", "")
+	v.SetDefault("notifications.channel", "log")
+	v.SetDefault("notifications.smtp.port", 25)
+	v.SetDefault("reminders.interval", time.Minute)
+	v.SetDefault("reminders.lead", time.Hour)
+	v.SetDefault("attachments.store", "local")
+	v.SetDefault("attachments.dir", "attachments")
+	v.SetDefault("attachments.bucket", "attachments")
+	v.SetDefault("attachments.maxSize", 10<<20)
+	v.SetDefault("attachments.allowedTypes", []string{"image/*", "text/plain", "application/pdf", "application/zip"})
+	v.SetDefault("runtime.rateLimit.requestsPerSecond", 0)
+	v.SetDefault("runtime.rateLimit.burst", 0)
+	v.SetDefault("runtime.features.graphql", true)
+	v.SetDefault("runtime.logLevel", LogLevelInfo)
+
+	if path != "" {
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName("config")
+		v.SetConfigType("yml")
+		v.AddConfigPath("../")
+		v.AddConfigPath("/app")
+		v.AddConfigPath(".")
 	}
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	return v
+}
+
+func decode(v *viper.Viper) (*Config, error) {
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("decoding config: %w", err)
+	}
+
+	// db.localURI is the key used before db.uri.
+	if cfg.DB.URI == "" {
+		cfg.DB.URI = v.GetString("db.localURI")
+	}
+
+	if err := resolveSecrets(&cfg); err != nil {
+		return nil, fmt.Errorf("resolving secrets: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.DB.URI == "" {
+		errs = append(errs, errors.New("db.uri is required (or set APP_DB_URI)"))
+	}
+	if c.DB.Name == "" {
+		errs = append(errs, errors.New("db.name is required (or set APP_DB_NAME)"))
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	if c.Server.GRPCPort < 1 || c.Server.GRPCPort > 65535 {
+		errs = append(errs, fmt.Errorf("server.grpcPort must be between 1 and 65535, got %d", c.Server.GRPCPort))
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("server.trustedProxies: %q is not an IP or CIDR range", proxy))
+			}
+		}
+	}
+	if c.Events.ChangeStream && c.Events.InstanceID == "" {
+		errs = append(errs, errors.New("events.instanceID is required when events.changeStream is on (or set APP_EVENTS_INSTANCEID)"))
+	}
+
+	if c.API.Version == "" {
+		errs = append(errs, errors.New("api.version is required"))
+	}
+
+	switch c.Notifications.Channel {
+	case "log":
+	case "webhook":
+		if u, err := url.Parse(c.Notifications.Webhook.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("notifications.webhook.url must be an absolute URL, got %q", c.Notifications.Webhook.URL))
+		}
+	case "smtp":
+		if c.Notifications.SMTP.Host == "" || c.Notifications.SMTP.From == "" || len(c.Notifications.SMTP.To) == 0 {
+			errs = append(errs, errors.New("notifications.smtp.host, from and to are required for the smtp channel"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("notifications.channel must be log, webhook or smtp, got %q", c.Notifications.Channel))
+	}
+	if c.Reminders.Interval < 0 || c.Reminders.Lead < 0 {
+		errs = append(errs, errors.New("reminders.interval and reminders.lead must not be negative"))
+	}
+
+	switch c.Attachments.Store {
+	case "local":
+		if c.Attachments.Dir == "" {
+			errs = append(errs, errors.New("attachments.dir is required for the local store"))
+		}
+	case "gridfs":
+	default:
+		errs = append(errs, fmt.Errorf("attachments.store must be local or gridfs, got %q", c.Attachments.Store))
+	}
+	if c.Attachments.MaxSize < 1 {
+		errs = append(errs, fmt.Errorf("attachments.maxSize must be at least 1, got %d", c.Attachments.MaxSize))
+	}
+
+	for _, origin := range c.Runtime.CORS.Origins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			errs = append(errs, fmt.Errorf("runtime.cors.origins: %q is not an origin like https://example.com", origin))
+		}
+	}
+	if c.Runtime.RateLimit.RequestsPerSecond < 0 {
+		errs = append(errs, fmt.Errorf("runtime.rateLimit.requestsPerSecond must not be negative, got %g", c.Runtime.RateLimit.RequestsPerSecond))
+	}
+	if c.Runtime.RateLimit.RequestsPerSecond > 0 && c.Runtime.RateLimit.Burst < 1 {
+		errs = append(errs, fmt.Errorf("runtime.rateLimit.burst must be at least 1 when rate limiting is enabled, got %d", c.Runtime.RateLimit.Burst))
+	}
+
+	if !slices.Contains(logLevels, c.Runtime.LogLevel) {
+		errs = append(errs, fmt.Errorf("runtime.logLevel must be %s, got %q", strings.Join(logLevels, " or "), c.Runtime.LogLevel))
+	}
+	errs = append(errs, c.Runtime.StatusWorkflow.validate()...)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+
+	return nil
 }
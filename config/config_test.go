@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+db:
+  name: pretest
+  uri: mongodb://mongodb:27017
+server:
+  port: 9090
+events:
+  changeStream: true
+  instanceID: app-1
+`)
+
+	cfg, err := LoadConfig(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pretest", cfg.DB.Name)
+	assert.Equal(t, "mongodb://mongodb:27017", cfg.DB.URI)
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, 9090, cfg.Server.GRPCPort)
+	assert.Equal(t, "v1", cfg.API.Version)
+	assert.True(t, cfg.Events.ChangeStream)
+	assert.Equal(t, "app-1", cfg.Events.InstanceID)
+	assert.Equal(t, "log", cfg.Notifications.Channel)
+	assert.Equal(t, time.Minute, cfg.Reminders.Interval)
+	assert.Equal(t, time.Hour, cfg.Reminders.Lead)
+	assert.Equal(t, "local", cfg.Attachments.Store)
+	assert.Equal(t, int64(10<<20), cfg.Attachments.MaxSize)
+	assert.Contains(t, cfg.Attachments.AllowedTypes, "image/*")
+	assert.Empty(t, cfg.Server.TrustedProxies)
+	assert.Equal(t, LogLevelInfo, cfg.Runtime.LogLevel)
+	assert.True(t, cfg.FeatureEnabled("graphql"))
+}
+
+func TestLoadConfig_LegacyURIKey(t *testing.T) {
+	path := writeConfig(t, `
+db:
+  localURI: mongodb://legacy:27017
+`)
+
+	cfg, err := LoadConfig(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mongodb://legacy:27017", cfg.DB.URI)
+}
+
+func TestLoadConfig_EnvOverrides(t *testing.T) {
+	path := writeConfig(t, `
+db:
+  uri: mongodb://mongodb:27017
+`)
+	t.Setenv("APP_DB_URI", "mongodb://override:27017")
+	t.Setenv("APP_SERVER_PORT", "9191")
+	t.Setenv("APP_EVENTS_INSTANCEID", "app-2")
+
+	cfg, err := LoadConfig(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mongodb://override:27017", cfg.DB.URI)
+	assert.Equal(t, 9191, cfg.Server.Port)
+	assert.Equal(t, "app-2", cfg.Events.InstanceID)
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := writeConfig(t, `
+db:
+  name: ""
+server:
+  port: 70000
+  grpcPort: 0
+  trustedProxies: ["10.0.0.0/8", "proxy.internal"]
+events:
+  changeStream: true
+notifications:
+  channel: webhook
+reminders:
+  interval: -1s
+attachments:
+  store: s3
+runtime:
+  logLevel: debug
+`)
+
+	_, err := LoadConfig(path)
+
+	assert.ErrorContains(t, err, "db.uri is required")
+	assert.ErrorContains(t, err, "db.name is required")
+	assert.ErrorContains(t, err, "server.port must be between 1 and 65535, got 70000")
+	assert.ErrorContains(t, err, "server.grpcPort must be between 1 and 65535, got 0")
+	assert.ErrorContains(t, err, "notifications.webhook.url must be an absolute URL")
+	assert.ErrorContains(t, err, "reminders.interval and reminders.lead must not be negative")
+	assert.ErrorContains(t, err, `attachments.store must be local or gridfs, got "s3"`)
+	assert.ErrorContains(t, err, `server.trustedProxies: "proxy.internal" is not an IP or CIDR range`)
+	assert.ErrorContains(t, err, `runtime.logLevel must be info or error, got "debug"`)
+	assert.ErrorContains(t, err, "events.instanceID is required when events.changeStream is on")
+}
+
+func TestLoadConfig_MissingFile(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yml"))
+
+	assert.ErrorContains(t, err, "reading config")
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Snapshot is one version of the active configuration.
+type Snapshot struct {
+	Config   *Config   `json:"config"`
+	Version  int       `json:"version"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// Live holds the active configuration. Reloads only change the runtime
+// section; the new file is validated as a whole first and the active config is
+// swapped atomically, so readers never see a partially applied change.
+type Live struct {
+	v       *viper.Viper
+	mu      sync.Mutex
+	current atomic.Pointer[Snapshot]
+}
+
+// NewLive wraps a config that is never reloaded.
+func NewLive(cfg *Config) *Live {
+	l := &Live{}
+	l.current.Store(&Snapshot{Config: cfg, Version: 1, LoadedAt: time.Now().UTC()})
+	return l
+}
+
+// LoadLive loads the config like LoadConfig and keeps the file around so it
+// can be reloaded.
+func LoadLive(path string) (*Live, error) {
+	v := newViper(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	cfg, err := decode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewLive(cfg)
+	l.v = v
+
+	return l, nil
+}
+
+// Config returns the active configuration. Callers must not modify it.
+func (l *Live) Config() *Config {
+	return l.current.Load().Config
+}
+
+func (l *Live) Snapshot() Snapshot {
+	return *l.current.Load()
+}
+
+// Reload re-reads the config file and applies its runtime section. An invalid
+// file leaves the active config untouched.
+func (l *Live) Reload() error {
+	if l.v == nil {
+		return fmt.Errorf("config was not loaded from a file")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.v.ReadInConfig(); err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	next, err := decode(l.v)
+	if err != nil {
+		return err
+	}
+
+	active := l.current.Load()
+
+	if !sameStructure(active.Config, next) {
+		log.Println("Config changes outside of runtime need a restart and were not applied")
+	}
+
+	if reflect.DeepEqual(active.Config.Runtime, next.Runtime) {
+		return nil
+	}
+
+	merged := *active.Config
+	merged.Runtime = next.Runtime
+
+	l.current.Store(&Snapshot{
+		Config:   &merged,
+		Version:  active.Version + 1,
+		LoadedAt: time.Now().UTC(),
+	})
+
+	log.Printf("Config reloaded to version %d", active.Version+1)
+
+	return nil
+}
+
+// Watch reloads the config whenever its file changes.
+func (l *Live) Watch() {
+	if l.v == nil {
+		return
+	}
+
+	l.v.OnConfigChange(func(e fsnotify.Event) {
+		if err := l.Reload(); err != nil {
+			log.Println("Error Reloading Config, keeping the active one: ", err)
+		}
+	})
+	l.v.WatchConfig()
+}
+
+func sameStructure(a, b *Config) bool {
+	x, y := *a, *b
+	x.Runtime, y.Runtime = RuntimeConfig{}, RuntimeConfig{}
+	return reflect.DeepEqual(x, y)
+}
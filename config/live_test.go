@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const liveConfig = `
+db:
+  uri: mongodb://mongodb:27017
+runtime:
+  cors:
+    origins: ["https://a.example.com"]
+  features:
+    betaUI: true
+`
+
+func TestLive_Reload(t *testing.T) {
+	path := writeConfig(t, liveConfig)
+
+	live, err := LoadLive(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, live.Snapshot().Version)
+	assert.True(t, live.Config().FeatureEnabled("betaUI"))
+	assert.True(t, live.Config().FeatureEnabled("graphql"))
+
+	assert.NoError(t, os.WriteFile(path, []byte(`
+db:
+  uri: mongodb://elsewhere:27017
+runtime:
+  cors:
+    origins: ["https://b.example.com"]
+  rateLimit:
+    requestsPerSecond: 5
+    burst: 10
+  features:
+    graphql: false
+  logLevel: error
+  statusWorkflow:
+    completed: []
+`), 0o600))
+	assert.NoError(t, live.Reload())
+
+	cfg := live.Config()
+	assert.Equal(t, 2, live.Snapshot().Version)
+	assert.Equal(t, []string{"https://b.example.com"}, cfg.Runtime.CORS.Origins)
+	assert.Equal(t, 5.0, cfg.Runtime.RateLimit.RequestsPerSecond)
+	assert.False(t, cfg.FeatureEnabled("betaUI"))
+	assert.False(t, cfg.FeatureEnabled("graphql"))
+	assert.Equal(t, LogLevelError, cfg.Runtime.LogLevel)
+	assert.False(t, cfg.Runtime.StatusWorkflow.Allows(1, 0))
+	assert.True(t, cfg.Runtime.StatusWorkflow.Allows(0, 1))
+	// Structural settings need a restart.
+	assert.Equal(t, "mongodb://mongodb:27017", cfg.DB.URI)
+}
+
+func TestLive_ReloadUnchanged(t *testing.T) {
+	path := writeConfig(t, liveConfig)
+
+	live, err := LoadLive(path)
+	assert.NoError(t, err)
+	assert.NoError(t, live.Reload())
+
+	assert.Equal(t, 1, live.Snapshot().Version)
+}
+
+func TestLive_ReloadInvalid(t *testing.T) {
+	path := writeConfig(t, liveConfig)
+
+	live, err := LoadLive(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`
+db:
+  uri: mongodb://mongodb:27017
+runtime:
+  cors:
+    origins: ["not an origin"]
+  rateLimit:
+    requestsPerSecond: 5
+  statusWorkflow:
+    completed: [archived]
+`), 0o600))
+
+	err = live.Reload()
+	assert.ErrorContains(t, err, `runtime.cors.origins: "not an origin"`)
+	assert.ErrorContains(t, err, "runtime.rateLimit.burst must be at least 1")
+	assert.ErrorContains(t, err, `runtime.statusWorkflow: unknown status "archived"`)
+	assert.Equal(t, 1, live.Snapshot().Version)
+	assert.Equal(t, []string{"https://a.example.com"}, live.Config().Runtime.CORS.Origins)
+}
+
+func TestNewLive(t *testing.T) {
+	live := NewLive(&Config{})
+
+	assert.Equal(t, 1, live.Snapshot().Version)
+	assert.Error(t, live.Reload())
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// The values of runtime.logLevel.
+const (
+	LogLevelInfo  = "info"
+	LogLevelError = "error"
+)
+
+var logLevels = []string{LogLevelInfo, LogLevelError}
+
+// logTimestamp is the date and time the standard logger starts lines with.
+const logTimestamp = "2006/01/02 15:04:05"
+
+// LogWriter returns a writer for the standard logger and the request log that
+// passes to w the lines runtime.logLevel lets through. The level is read on
+// every line, so config reloads apply immediately.
+func (l *Live) LogWriter(w io.Writer) io.Writer {
+	return &levelWriter{live: l, w: w}
+}
+
+type levelWriter struct {
+	live *Live
+	w    io.Writer
+}
+
+func (lw *levelWriter) Write(p []byte) (int, error) {
+	if lw.live.Config().Runtime.LogLevel == LogLevelError && !isErrorLine(p) {
+		return len(p), nil
+	}
+	return lw.w.Write(p)
+}
+
+// isErrorLine reports whether a log line is an error, which by convention
+// starts with "Error" after the date and time.
+func isErrorLine(p []byte) bool {
+	if len(p) > len(logTimestamp) {
+		if _, err := time.Parse(logTimestamp, string(p[:len(logTimestamp)])); err == nil {
+			p = bytes.TrimLeft(p[len(logTimestamp):], " ")
+		}
+	}
+	return bytes.HasPrefix(p, []byte("Error"))
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLive_LogWriter(t *testing.T) {
+	cfg := &Config{Runtime: RuntimeConfig{LogLevel: LogLevelError}}
+	live := NewLive(cfg)
+
+	var out bytes.Buffer
+	logger := log.New(live.LogWriter(&out), "", log.LstdFlags)
+
+	logger.Println("Loaded config")
+	logger.Println("Error Insert Task: ", "timeout")
+	live.LogWriter(&out).Write([]byte("[GIN] 200 | GET /ping\n"))
+
+	assert.NotContains(t, out.String(), "Loaded config")
+	assert.Contains(t, out.String(), "Error Insert Task:  timeout")
+	assert.NotContains(t, out.String(), "[GIN]")
+
+	cfg.Runtime.LogLevel = LogLevelInfo
+	logger.Println("Loaded config")
+
+	assert.Contains(t, out.String(), "Loaded config")
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	fileRefPrefix = "file://"
+	envRefPrefix  = "env://"
+
+	redacted = "REDACTED"
+)
+
+// resolveSecrets replaces every string setting written as a reference with the
+// value it points to:
+//
+//	file:///run/secrets/db_password   contents of the file, trailing newline trimmed
+//	env://MONGO_PASSWORD              value of the environment variable
+func resolveSecrets(cfg *Config) error {
+	return walkStrings(reflect.ValueOf(cfg).Elem(), "", func(key string, field reflect.StructField, value reflect.Value) error {
+		resolved, err := resolveRef(value.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		value.SetString(resolved)
+		return nil
+	})
+}
+
+func resolveRef(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, fileRefPrefix):
+		path := strings.TrimPrefix(value, fileRefPrefix)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading secret file: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+
+	case strings.HasPrefix(value, envRefPrefix):
+		name := strings.TrimPrefix(value, envRefPrefix)
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return resolved, nil
+	}
+
+	return value, nil
+}
+
+// Redacted returns a copy of the config that is safe to log: fields tagged
+// secret:"true" are masked and passwords embedded in URIs are removed.
+func (c Config) Redacted() Config {
+	walkStrings(reflect.ValueOf(&c).Elem(), "", func(key string, field reflect.StructField, value reflect.Value) error {
+		if value.String() == "" {
+			return nil
+		}
+
+		switch field.Tag.Get("secret") {
+		case "true":
+			value.SetString(redacted)
+		case "uri":
+			if u, err := url.Parse(value.String()); err == nil && u.User != nil {
+				if _, ok := u.User.Password(); ok {
+					u.User = url.UserPassword(u.User.Username(), redacted)
+					value.SetString(u.String())
+				}
+			}
+		}
+		return nil
+	})
+
+	return c
+}
+
+// String renders the redacted config, so logging a Config never leaks secrets.
+func (c Config) String() string {
+	out, _ := json.Marshal(c.Redacted())
+	return string(out)
+}
+
+func walkStrings(v reflect.Value, prefix string, fn func(key string, field reflect.StructField, value reflect.Value) error) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		key := field.Tag.Get("mapstructure")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			if err := walkStrings(value, key, fn); err != nil {
+				return err
+			}
+		case reflect.String:
+			if err := fn(key, field, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
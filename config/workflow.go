@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// taskStatuses names the task statuses in the order of their values.
+var taskStatuses = []string{"incomplete", "completed"}
+
+// StatusWorkflow lists, for a task status, the statuses a task in it may
+// change to. A status that is not listed may change to any other.
+type StatusWorkflow map[string][]string
+
+// Allows reports whether a task may change from status from to status to.
+// Keeping the same status is always allowed.
+func (w StatusWorkflow) Allows(from, to int) bool {
+	if from == to || from < 0 || from >= len(taskStatuses) || to < 0 || to >= len(taskStatuses) {
+		return true
+	}
+
+	next, ok := w[taskStatuses[from]]
+	return !ok || slices.Contains(next, taskStatuses[to])
+}
+
+func (w StatusWorkflow) validate() []error {
+	var errs []error
+
+	for from, next := range w {
+		for _, status := range append([]string{from}, next...) {
+			if !slices.Contains(taskStatuses, status) {
+				errs = append(errs, fmt.Errorf("runtime.statusWorkflow: unknown status %q, must be %s", status, strings.Join(taskStatuses, " or ")))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusWorkflow_Allows(t *testing.T) {
+	assert.True(t, StatusWorkflow(nil).Allows(1, 0))
+
+	noReopen := StatusWorkflow{"completed": {}}
+	assert.False(t, noReopen.Allows(1, 0))
+	assert.True(t, noReopen.Allows(0, 1))
+	assert.True(t, noReopen.Allows(1, 1))
+
+	both := StatusWorkflow{"incomplete": {"completed"}, "completed": {"incomplete"}}
+	assert.True(t, both.Allows(0, 1))
+	assert.True(t, both.Allows(1, 0))
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+type AdminController struct {
+	config *config.Live
+}
+
+var adC *AdminController
+
+func SetUpAdminRoutes(r *gin.Engine) {
+
+	adminGroup := r.Group("/admin")
+	{
+		adminGroup.GET("/config", adC.getConfig)
+	}
+}
+
+func NewAdminController(live *config.Live) {
+	adC = &AdminController{config: live}
+}
+
+// getConfig returns the effective configuration with secrets redacted, along
+// with its version, which goes up on every reload that changed it. It lives
+// outside of the versioned API, so it is not part of the Swagger docs.
+func (ac *AdminController) getConfig(c *gin.Context) {
+
+	snapshot := ac.config.Snapshot()
+
+	redacted := snapshot.Config.Redacted()
+	snapshot.Config = &redacted
+
+	c.JSON(http.StatusOK, snapshot)
+}
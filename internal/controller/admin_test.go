@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+func Test_GetConfig(t *testing.T) {
+	NewAdminController(config.NewLive(&config.Config{
+		DB: config.DBConfig{Name: "pretest", Password: "examplepassword"},
+	}))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	adC.getConfig(c)
+
+	var res config.Snapshot
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, 1, res.Version)
+	assert.Equal(t, "pretest", res.Config.DB.Name)
+	assert.Equal(t, "REDACTED", res.Config.DB.Password)
+}
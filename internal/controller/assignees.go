@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// taskUsers selects a list of user IDs on a task: its assignees or its
+// watchers.
+type taskUsers func(task *database.Task) *[]string
+
+func assignees(task *database.Task) *[]string { return &task.AssigneeIDs }
+
+func watchers(task *database.Task) *[]string { return &task.WatcherIDs }
+
+// putAssignee assigns a user to a task.
+// @Summary Assign a user to a task
+// @Description Add a user to the assignees of a task. Assigning a user twice changes nothing.
+// @ID putAssignee
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param userId path string true "ID of the user"
+// @Success 200 {object} TaskResponse "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/assignees/{userId} [put]
+// @Tags tasks
+func (tc *TaskController) putAssignee(c *gin.Context) {
+	tc.setTaskUser(c, assignees, true)
+}
+
+// deleteAssignee unassigns a user from a task.
+// @Summary Unassign a user from a task
+// @Description Remove a user from the assignees of a task.
+// @ID deleteAssignee
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param userId path string true "ID of the user"
+// @Success 200 {object} TaskResponse "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/assignees/{userId} [delete]
+// @Tags tasks
+func (tc *TaskController) deleteAssignee(c *gin.Context) {
+	tc.setTaskUser(c, assignees, false)
+}
+
+// putWatcher makes a user watch a task.
+// @Summary Watch a task
+// @Description Add a user to the watchers of a task. Watchers are notified through the configured notification channel when the task is changed by someone else.
+// @ID putWatcher
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param userId path string true "ID of the user"
+// @Success 200 {object} TaskResponse "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/watchers/{userId} [put]
+// @Tags tasks
+func (tc *TaskController) putWatcher(c *gin.Context) {
+	tc.setTaskUser(c, watchers, true)
+}
+
+// deleteWatcher makes a user stop watching a task.
+// @Summary Unwatch a task
+// @Description Remove a user from the watchers of a task.
+// @ID deleteWatcher
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param userId path string true "ID of the user"
+// @Success 200 {object} TaskResponse "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/watchers/{userId} [delete]
+// @Tags tasks
+func (tc *TaskController) deleteWatcher(c *gin.Context) {
+	tc.setTaskUser(c, watchers, false)
+}
+
+// getMyTasks retrieves the tasks assigned to the caller.
+// @Summary Retrieve my tasks
+// @Description Get the tasks assigned to the caller identified by X-User-ID, ordered by ID. The list is streamed from the database as it is read; send Accept: application/x-ndjson to receive one task per line instead of a JSON array.
+// @ID getMyTasks
+// @Accept json
+// @Produce json,application/x-ndjson
+// @Param X-User-ID header string true "ID of the caller"
+// @Success 200 {array} TaskResponse "OK"
+// @Failure 401 {object} middleware.Problem "Unauthorized"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /me/tasks [get]
+// @Tags tasks
+func (tc *TaskController) getMyTasks(c *gin.Context) {
+
+	actor := middleware.Actor(c)
+	if actor == middleware.AnonymousActor {
+		middleware.AbortWithProblem(c, http.StatusUnauthorized, middleware.ActorHeader+" is required")
+		return
+	}
+
+	cursor, err := database.MongoDB.FindTasks(c.Request.Context(), database.TaskFilter{AssigneeID: actor}, 0, 0)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer cursor.Close(c)
+
+	streamTasks(c, cursor, c.NegotiateFormat(gin.MIMEJSON, mimeNDJSON) == mimeNDJSON)
+}
+
+// setTaskUser adds the user in the path to, or removes it from, the list of
+// the task in the path that users selects, and answers with the task.
+func (tc *TaskController) setTaskUser(c *gin.Context, users taskUsers, add bool) {
+
+	taskID := c.Param("id")
+
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	task, err := tc.updateTaskUsers(originOf(c), taskID, c.Param("userId"), users, add)
+
+	if errors.Is(err, errTaskNotFound) {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, taskResponse(task))
+}
+
+// updateTaskUsers adds userID to, or removes it from, the list of the task
+// with taskID that users selects. The task is only saved and the change
+// reported when the list changes.
+func (tc *TaskController) updateTaskUsers(origin changeOrigin, taskID, userID string, users taskUsers, add bool) (database.Task, error) {
+
+	before, err := database.MongoDB.GetTaskByID(taskID)
+
+	if err != nil || before.ID.IsZero() {
+		return database.Task{}, errTaskNotFound
+	}
+
+	updated := before
+	list := users(&updated)
+	has := slices.Contains(*list, userID)
+
+	switch {
+	case add && !has:
+		*list = append(slices.Clone(*list), userID)
+	case !add && has:
+		*list = slices.DeleteFunc(slices.Clone(*list), func(id string) bool { return id == userID })
+	default:
+		return before, nil
+	}
+
+	err = database.MongoDB.UpdateTaskID(taskID, updated)
+
+	if err != nil {
+		return database.Task{}, err
+	}
+
+	tc.taskChanged(origin, database.AuditUpdate, taskID, &before, &updated)
+
+	return updated, nil
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+)
+
+func serveAs(mock *GRPCMockDB, method, target, actor string) *httptest.ResponseRecorder {
+	database.MongoDB = mock
+	NewTasksController()
+
+	r := gin.New()
+	r.Use(middleware.RequestContext())
+	SetUpTasksRoutes(r)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, nil)
+	if actor != "" {
+		req.Header.Set(middleware.ActorHeader, actor)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func Test_AssignAndUnassign(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "design", "build")
+
+	for _, target := range []string{
+		"/api/v1/tasks/" + ids[0] + "/assignees/alice",
+		"/api/v1/tasks/" + ids[0] + "/assignees/alice",
+		"/api/v1/tasks/" + ids[0] + "/assignees/bob",
+		"/api/v1/tasks/" + ids[1] + "/assignees/alice",
+	} {
+		w := serveAs(mock, http.MethodPut, target, "carol")
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Equal(t, []string{"alice", "bob"}, mock.tasks[0].AssigneeIDs, "assigning twice changes nothing")
+
+	w := serveAs(mock, http.MethodDelete, "/api/v1/tasks/"+ids[1]+"/assignees/alice", "carol")
+
+	var task TaskResponse
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+	assert.Empty(t, task.AssigneeIDs)
+
+	w = serveAs(mock, http.MethodPut, "/api/v1/tasks/65a000000000000000000001/assignees/alice", "carol")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveAs(mock, http.MethodGet, "/api/v1/me/tasks", "alice")
+
+	var mine []TaskResponse
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &mine))
+	require.Len(t, mine, 1)
+	assert.Equal(t, ids[0], mine[0].ID)
+
+	w = serveAs(mock, http.MethodGet, "/api/v1/me/tasks", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func Test_WatchAndUnwatch(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "design")
+	path := "/api/v1/tasks/" + ids[0] + "/watchers/"
+
+	ch, unsubscribe := events.Default.Subscribe(8)
+	defer unsubscribe()
+
+	w := serveAs(mock, http.MethodPut, path+"alice", "alice")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	e := <-ch
+	assert.Equal(t, events.TaskUpdated, e.Type)
+	require.NotNil(t, e.Task)
+	assert.Equal(t, []string{"alice"}, e.Task.WatcherIDs)
+
+	w = serveAs(mock, http.MethodDelete, path+"bob", "bob")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, ch, "unwatching a task that is not watched changes nothing")
+
+	w = serveAs(mock, http.MethodDelete, path+"alice", "alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, mock.tasks[0].WatcherIDs)
+}
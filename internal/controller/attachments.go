@@ -0,0 +1,326 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sniffLen is how much of an upload http.DetectContentType looks at.
+const sniffLen = 512
+
+type AttachmentController struct {
+	blobs        storage.BlobStore
+	maxSize      int64
+	allowedTypes []string
+}
+
+var atC *AttachmentController
+
+func SetUpAttachmentRoutes(r *gin.Engine) {
+
+	attachmentGroup := r.Group("/api/v1/tasks/:id/attachments")
+	{
+		attachmentGroup.GET("", atC.getAttachments)
+		attachmentGroup.POST("", atC.postAttachment)
+		attachmentGroup.GET("/:attachmentId", atC.downloadAttachment)
+		attachmentGroup.DELETE("/:attachmentId", atC.deleteAttachment)
+	}
+}
+
+// NewAttachmentController stores the contents of attachments in blobs, within
+// the size and type limits of cfg.
+func NewAttachmentController(blobs storage.BlobStore, cfg config.AttachmentsConfig) {
+	atC = &AttachmentController{
+		blobs:        blobs,
+		maxSize:      cfg.MaxSize,
+		allowedTypes: cfg.AllowedTypes,
+	}
+}
+
+// getAttachments retrieves the attachments of a task.
+// @Summary Retrieve the attachments of a task
+// @Description Get the metadata of the files attached to a task, oldest first.
+// @ID getAttachments
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {array} database.Attachment "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/attachments [get]
+// @Tags attachments
+func (ac *AttachmentController) getAttachments(c *gin.Context) {
+
+	taskID, ok := pathTask(c)
+	if !ok {
+		return
+	}
+
+	attachments, err := database.Attachments.GetAttachments(taskID)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// postAttachment uploads a file to a task.
+// @Summary Attach a file to a task
+// @Description Upload the "file" part of a multipart form. Its type is sniffed from its contents and must be one of attachments.allowedTypes, and it may not be larger than attachments.maxSize.
+// @ID postAttachment
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param file formData file true "File to attach"
+// @Success 201 {object} database.Attachment "Created"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 413 {object} middleware.Problem "Request Entity Too Large"
+// @Failure 415 {object} middleware.Problem "Unsupported Media Type"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/attachments [post]
+// @Tags attachments
+func (ac *AttachmentController) postAttachment(c *gin.Context) {
+
+	taskID, ok := pathTask(c)
+	if !ok {
+		return
+	}
+
+	part, err := filePart(c.Request)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer part.Close()
+
+	// Read the upload as a stream rather than through a parsed form, so that
+	// large files are neither buffered in memory nor spooled to disk twice.
+	content := bufio.NewReaderSize(part, sniffLen)
+	head, err := content.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	contentType := http.DetectContentType(head)
+	if !ac.allowed(contentType) {
+		middleware.AbortWithProblem(c, http.StatusUnsupportedMediaType, "Attachments of type "+contentType+" are not allowed")
+		return
+	}
+
+	attachment := database.Attachment{
+		ID:          primitive.NewObjectID(),
+		TaskID:      taskID,
+		Filename:    filepath.Base(filepath.Clean("/" + part.FileName())),
+		ContentType: contentType,
+		Uploader:    middleware.Actor(c),
+		CreatedAt:   time.Now().UTC(),
+	}
+	key := attachment.ID.Hex()
+
+	// Read one byte past the limit to tell a file of exactly maxSize from a
+	// larger one.
+	size, err := ac.blobs.Put(c.Request.Context(), key, io.LimitReader(content, ac.maxSize+1))
+	if err == nil && size > ac.maxSize {
+		err = errTooLarge
+	}
+	if err != nil {
+		if deleteErr := ac.blobs.Delete(context.Background(), key); deleteErr != nil {
+			log.Println("Error Deleting Attachment Blob: ", deleteErr)
+		}
+		if errors.Is(err, errTooLarge) {
+			middleware.AbortWithProblem(c, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	attachment.Size = size
+
+	err = database.Attachments.InsertAttachment(attachment)
+	if err != nil {
+		ac.blobs.Delete(context.Background(), key)
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Location", c.Request.URL.Path+"/"+key)
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// downloadAttachment sends the contents of an attachment.
+// @Summary Download an attachment
+// @Description Download the contents of an attachment. Range requests are supported.
+// @ID downloadAttachment
+// @Produce octet-stream
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param attachmentId path string true "ID of the attachment" Pattern("^[0-9a-fA-F]{24}$")
+// @Param Range header string false "Byte range to download, e.g. bytes=0-1023"
+// @Success 200 {file} file "OK"
+// @Success 206 {file} file "Partial Content"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 416 {string} string "Requested Range Not Satisfiable"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/attachments/{attachmentId} [get]
+// @Tags attachments
+func (ac *AttachmentController) downloadAttachment(c *gin.Context) {
+
+	attachment, ok := taskAttachment(c)
+	if !ok {
+		return
+	}
+
+	blob, err := ac.blobs.Open(c.Request.Context(), attachment.ID.Hex())
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(c.Writer, c.Request, attachment.Filename, attachment.CreatedAt, blob)
+}
+
+// deleteAttachment deletes an attachment.
+// @Summary Delete an attachment
+// @Description Delete an attachment and its contents.
+// @ID deleteAttachment
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param attachmentId path string true "ID of the attachment" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {string} string "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/attachments/{attachmentId} [delete]
+// @Tags attachments
+func (ac *AttachmentController) deleteAttachment(c *gin.Context) {
+
+	attachment, ok := taskAttachment(c)
+	if !ok {
+		return
+	}
+
+	err := ac.remove(c.Request.Context(), attachment)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, "OK")
+}
+
+var errTooLarge = errors.New("attachment is larger than the allowed size")
+
+// allowed reports whether attachments of contentType may be uploaded. A
+// pattern such as "image/*" allows every subtype; no patterns allow any type.
+func (ac *AttachmentController) allowed(contentType string) bool {
+	if len(ac.allowedTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range ac.allowedTypes {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if strings.EqualFold(mediaType, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove deletes the metadata of attachment, then its contents.
+func (ac *AttachmentController) remove(ctx context.Context, attachment database.Attachment) error {
+	if _, err := database.Attachments.DeleteAttachment(attachment.ID.Hex()); err != nil {
+		return err
+	}
+	return ac.blobs.Delete(ctx, attachment.ID.Hex())
+}
+
+// deleteTaskAttachments removes the attachments of a deleted task. It does
+// nothing when attachments are not set up.
+func deleteTaskAttachments(taskID string) {
+	if atC == nil || atC.blobs == nil || database.Attachments == nil {
+		return
+	}
+
+	attachments, err := database.Attachments.GetAttachments(taskID)
+	if err != nil {
+		log.Println("Error Getting Task Attachments: ", err)
+		return
+	}
+
+	for _, attachment := range attachments {
+		if err := atC.remove(context.Background(), attachment); err != nil {
+			log.Println("Error Deleting Attachment: ", err)
+		}
+	}
+}
+
+// filePart returns the "file" part of a multipart request.
+func filePart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing file part")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// taskAttachment returns the attachment in the path if it belongs to the task
+// in the path. Otherwise it answers 404 and reports false.
+func taskAttachment(c *gin.Context) (database.Attachment, bool) {
+
+	attachment, err := database.Attachments.GetAttachment(c.Param("attachmentId"))
+
+	if err != nil || attachment.ID.IsZero() || attachment.TaskID != c.Param("id") {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return database.Attachment{}, false
+	}
+
+	return attachment, true
+}
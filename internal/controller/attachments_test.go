@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/internal/storage"
+)
+
+type AttachmentMockDB struct {
+	attachments []database.Attachment
+}
+
+func (db *AttachmentMockDB) InsertAttachment(attachment database.Attachment) error {
+	db.attachments = append(db.attachments, attachment)
+	return nil
+}
+
+func (db *AttachmentMockDB) GetAttachment(attachmentID string) (database.Attachment, error) {
+	for _, attachment := range db.attachments {
+		if attachment.ID.Hex() == attachmentID {
+			return attachment, nil
+		}
+	}
+	return database.Attachment{}, nil
+}
+
+func (db *AttachmentMockDB) GetAttachments(taskID string) ([]database.Attachment, error) {
+	attachments := []database.Attachment{}
+	for _, attachment := range db.attachments {
+		if attachment.TaskID == taskID {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments, nil
+}
+
+func (db *AttachmentMockDB) DeleteAttachment(attachmentID string) (int64, error) {
+	for i, attachment := range db.attachments {
+		if attachment.ID.Hex() == attachmentID {
+			db.attachments = append(db.attachments[:i], db.attachments[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+var testAttachmentsConfig = config.AttachmentsConfig{
+	MaxSize:      64,
+	AllowedTypes: []string{"image/*", "text/plain"},
+}
+
+func serveAttachments(tasks *GRPCMockDB, attachments *AttachmentMockDB, blobs storage.BlobStore, req *http.Request) *httptest.ResponseRecorder {
+	database.MongoDB = tasks
+	database.Attachments = attachments
+	NewTasksController()
+	NewAttachmentController(blobs, testAttachmentsConfig)
+
+	r := gin.New()
+	r.Use(middleware.RequestContext())
+	SetUpTasksRoutes(r)
+	SetUpAttachmentRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func uploadRequest(target, filename string, content []byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set(middleware.ActorHeader, "alice")
+	return req
+}
+
+func Test_Attachments(t *testing.T) {
+	tasks, attachments := &GRPCMockDB{}, &AttachmentMockDB{}
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	ids := newTasks(tasks, "release", "other")
+	path := "/api/v1/tasks/" + ids[0] + "/attachments"
+
+	w := serveAttachments(tasks, attachments, blobs, uploadRequest(path, "../notes.txt", []byte("release notes")))
+
+	var created database.Attachment
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "notes.txt", created.Filename)
+	assert.Equal(t, "text/plain; charset=utf-8", created.ContentType)
+	assert.Equal(t, int64(13), created.Size)
+	assert.Equal(t, "alice", created.Uploader)
+	assert.Equal(t, path+"/"+created.ID.Hex(), w.Header().Get("Location"))
+
+	w = serveAttachments(tasks, attachments, blobs, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var list []database.Attachment
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+
+	req := httptest.NewRequest(http.MethodGet, path+"/"+created.ID.Hex(), nil)
+	req.Header.Set("Range", "bytes=8-")
+	w = serveAttachments(tasks, attachments, blobs, req)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "notes", w.Body.String())
+	assert.Equal(t, "bytes 8-12/13", w.Header().Get("Content-Range"))
+	assert.Equal(t, `attachment; filename=notes.txt`, w.Header().Get("Content-Disposition"))
+
+	w = serveAttachments(tasks, attachments, blobs, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+ids[1]+"/attachments/"+created.ID.Hex(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "attachment of another task")
+
+	w = serveAttachments(tasks, attachments, blobs, httptest.NewRequest(http.MethodDelete, path+"/"+created.ID.Hex(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, attachments.attachments)
+
+	_, err = blobs.Open(context.Background(), created.ID.Hex())
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func Test_AttachmentLimits(t *testing.T) {
+	tasks, attachments := &GRPCMockDB{}, &AttachmentMockDB{}
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	ids := newTasks(tasks, "release")
+	path := "/api/v1/tasks/" + ids[0] + "/attachments"
+
+	w := serveAttachments(tasks, attachments, blobs, uploadRequest(path, "exact.txt", bytes.Repeat([]byte("a"), 64)))
+	assert.Equal(t, http.StatusCreated, w.Code, "exactly the maximum size")
+
+	w = serveAttachments(tasks, attachments, blobs, uploadRequest(path, "big.txt", bytes.Repeat([]byte("a"), 65)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = serveAttachments(tasks, attachments, blobs, uploadRequest(path, "doc.pdf", []byte("%PDF-1.4\n")))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = serveAttachments(tasks, attachments, blobs, httptest.NewRequest(http.MethodPost, path, nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAttachments(tasks, attachments, blobs, uploadRequest("/api/v1/tasks/65a000000000000000000001/attachments", "notes.txt", []byte("hi")))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.Len(t, attachments.attachments, 1)
+}
+
+func Test_AttachmentsDeletedWithTask(t *testing.T) {
+	tasks, attachments := &GRPCMockDB{}, &AttachmentMockDB{}
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	ids := newTasks(tasks, "release")
+
+	w := serveAttachments(tasks, attachments, blobs, uploadRequest("/api/v1/tasks/"+ids[0]+"/attachments", "notes.txt", []byte("release notes")))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created database.Attachment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	w = serveAttachments(tasks, attachments, blobs, httptest.NewRequest(http.MethodDelete, "/api/v1/tasks/"+ids[0], nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, attachments.attachments)
+
+	_, err = blobs.Open(context.Background(), created.ID.Hex())
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditController struct{}
+
+type AuditVerifyResponse struct {
+	Valid   bool `json:"valid"`
+	Entries int  `json:"entries"`
+}
+
+var aC *AuditController
+
+func SetUpAuditRoutes(r *gin.Engine) {
+
+	auditGroup := r.Group("/api/v1/audit")
+	{
+		auditGroup.GET("", aC.getAuditLog)
+		auditGroup.GET("/verify", aC.verifyAuditLog)
+	}
+}
+
+func NewAuditController() {
+	aC = &AuditController{}
+}
+
+// recordAudit appends a mutation to the audit log. The mutation has already
+// been applied at this point, so a failure is logged rather than returned.
+func (tc *TaskController) recordAudit(origin changeOrigin, action database.AuditAction, taskID string, before, after *database.Task) {
+
+	entry := database.AuditEntry{
+		TaskID:    taskID,
+		Action:    action,
+		Actor:     origin.actor,
+		RequestID: origin.requestID,
+		Timestamp: time.Now(),
+		Before:    before,
+		After:     after,
+	}
+
+	if err := database.MongoDB.InsertAuditEntry(entry); err != nil {
+		log.Println("Error Record Audit Entry: ", err)
+	}
+}
+
+// getAuditLog retrieves the audit history.
+// @Summary Retrieve the audit log
+// @Description Get every recorded task mutation, oldest first, optionally limited to a single task.
+// @ID getAuditLog
+// @Accept json
+// @Produce json
+// @Param task_id query string false "ID of the task to retrieve history for" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {array} database.AuditEntry "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /audit [get]
+// @Tags audit
+func (ac *AuditController) getAuditLog(c *gin.Context) {
+
+	taskID := c.Query("task_id")
+
+	if taskID != "" {
+		if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+			middleware.AbortWithProblem(c, http.StatusBadRequest, "Invalid Task ID, should be in hex format")
+			return
+		}
+	}
+
+	entries, err := database.MongoDB.GetAuditEntries(taskID)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// verifyAuditLog checks the audit log hash chain.
+// @Summary Verify the audit log
+// @Description Recompute the hash chain over the whole audit log and report whether it is intact.
+// @ID verifyAuditLog
+// @Accept json
+// @Produce json
+// @Success 200 {object} AuditVerifyResponse "OK"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /audit/verify [get]
+// @Tags audit
+func (ac *AuditController) verifyAuditLog(c *gin.Context) {
+
+	entries, err := database.MongoDB.GetAuditEntries("")
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, AuditVerifyResponse{
+		Valid:   database.VerifyAuditChain(entries),
+		Entries: len(entries),
+	})
+}
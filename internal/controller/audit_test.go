@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditMockDB struct {
+	MockDB
+	entries []database.AuditEntry
+}
+
+func (db *AuditMockDB) InsertAuditEntry(entry database.AuditEntry) error {
+	if len(db.entries) > 0 {
+		entry.PrevHash = db.entries[len(db.entries)-1].Hash
+	}
+	entry.Hash = database.ComputeAuditHash(entry)
+	db.entries = append(db.entries, entry)
+	return nil
+}
+
+func (db *AuditMockDB) GetAuditEntries(taskID string) ([]database.AuditEntry, error) {
+	results := []database.AuditEntry{}
+	for _, e := range db.entries {
+		if taskID == "" || e.TaskID == taskID {
+			results = append(results, e)
+		}
+	}
+	return results, nil
+}
+
+func Test_PostTaskRecordsAudit(t *testing.T) {
+	mock := &AuditMockDB{}
+	database.MongoDB = mock
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newJSONRequest(`{"name": "Test Task", "status": 0}`)
+
+	tC := &TaskController{}
+	tC.postTask(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Len(t, mock.entries, 1)
+	assert.Equal(t, database.AuditCreate, mock.entries[0].Action)
+	assert.Equal(t, "anonymous", mock.entries[0].Actor)
+	assert.Nil(t, mock.entries[0].Before)
+	assert.Equal(t, "Test Task", mock.entries[0].After.Name)
+}
+
+func Test_GetAuditLog(t *testing.T) {
+	taskID := primitive.NewObjectID().Hex()
+	mock := &AuditMockDB{}
+	mock.InsertAuditEntry(database.AuditEntry{TaskID: taskID, Action: database.AuditCreate, Timestamp: time.Now()})
+	mock.InsertAuditEntry(database.AuditEntry{TaskID: primitive.NewObjectID().Hex(), Action: database.AuditCreate, Timestamp: time.Now()})
+	database.MongoDB = mock
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/audit?task_id="+taskID, nil)
+
+	aC := &AuditController{}
+	aC.getAuditLog(c)
+
+	var entries []database.AuditEntry
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, taskID, entries[0].TaskID)
+}
+
+func Test_GetAuditLogInvalidTaskID(t *testing.T) {
+	database.MongoDB = &AuditMockDB{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/audit?task_id=nope", nil)
+
+	aC := &AuditController{}
+	aC.getAuditLog(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_VerifyAuditLog(t *testing.T) {
+	mock := &AuditMockDB{}
+	for i := 0; i < 3; i++ {
+		mock.InsertAuditEntry(database.AuditEntry{TaskID: primitive.NewObjectID().Hex(), Action: database.AuditUpdate, Timestamp: time.Now()})
+	}
+	database.MongoDB = mock
+
+	aC := &AuditController{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	aC.verifyAuditLog(c)
+	assert.JSONEq(t, `{"valid": true, "entries": 3}`, w.Body.String())
+
+	mock.entries[1].Actor = "mallory"
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	aC.verifyAuditLog(c)
+	assert.JSONEq(t, `{"valid": false, "entries": 3}`, w.Body.String())
+}
+
+func newJSONRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The functions below let the command line change tasks with the same checks,
+// audit entries, completion rollup, recurring occurrences and attachment
+// cleanup as the API. Unlike API changes, they send no webhooks or watcher
+// notifications: the command runs no dispatcher or watcher worker, and servers
+// only deliver those for their own changes. Servers following the change
+// stream still publish them to their event subscribers. They must be called
+// after NewTasksController.
+
+// cliOrigin attributes a command line change to actor, under a request ID of
+// its own.
+func cliOrigin(actor string) changeOrigin {
+	return changeOrigin{actor: actor, requestID: primitive.NewObjectID().Hex()}
+}
+
+// CreateTask creates a task on behalf of actor and returns it.
+func CreateTask(actor, name string, status TaskStatus) (database.Task, error) {
+
+	taskID := primitive.NewObjectID().Hex()
+	taskReq := TaskRequest{Name: name, Status: &status}
+
+	if err := tC.createTask(cliOrigin(actor), taskReq, taskID); err != nil {
+		return database.Task{}, err
+	}
+
+	return *taskFromRequest(taskReq, taskID), nil
+}
+
+// CompleteTask marks the task with taskID completed on behalf of actor and
+// returns it. A task that is still blocked is refused. The next occurrence of
+// a recurring task is created before it returns, as the process may exit
+// right after.
+func CompleteTask(actor, taskID string) (database.Task, error) {
+
+	if tC.scheduler == nil {
+		tC.scheduler = recurrence.NewScheduler(recurrence.SystemClock{}, tC.spawnOccurrence)
+	}
+
+	completed := Completed
+	task, err := tC.applyPatch(cliOrigin(actor), taskID, TaskPatch{Status: &completed})
+	if err != nil {
+		return database.Task{}, err
+	}
+
+	tC.scheduler.Drain()
+
+	return task, nil
+}
+
+// DeleteTask deletes the task with taskID and its attachments on behalf of
+// actor. Its subtasks become top-level tasks, as with the API's default
+// policy.
+func DeleteTask(actor, taskID string) error {
+	return tC.removeTask(cliOrigin(actor), taskID, OrphanSubtasks)
+}
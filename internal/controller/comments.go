@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CommentController struct{}
+
+type CommentRequest struct {
+	// Body is Markdown; it is stored as sent and rendered by the client.
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+var cC *CommentController
+
+func SetUpCommentRoutes(r *gin.Engine) {
+
+	commentGroup := r.Group("/api/v1/tasks/:id/comments")
+	{
+		commentGroup.GET("", cC.getComments)
+		commentGroup.POST("", middleware.Idempotency(), cC.postComment)
+		commentGroup.PATCH("/:commentId", cC.patchComment)
+		commentGroup.DELETE("/:commentId", cC.deleteComment)
+	}
+}
+
+func NewCommentController() {
+	cC = &CommentController{}
+}
+
+// getComments retrieves the comments on a task.
+// @Summary Retrieve the comments on a task
+// @Description Get the comment thread of a task, oldest first.
+// @ID getComments
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {array} database.Comment "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/comments [get]
+// @Tags comments
+func (cc *CommentController) getComments(c *gin.Context) {
+
+	taskID, ok := pathTask(c)
+	if !ok {
+		return
+	}
+
+	comments, err := database.Comments.GetComments(taskID)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// postComment adds a comment to a task.
+// @Summary Comment on a task
+// @Description Add a Markdown comment to a task. The caller from X-User-ID is its author.
+// @ID postComment
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param body body CommentRequest true "Comment to add"
+// @Param Idempotency-Key header string false "Replay the stored response when a request is retried with the same key"
+// @Success 201 {object} database.Comment "Created"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/comments [post]
+// @Tags comments
+func (cc *CommentController) postComment(c *gin.Context) {
+
+	var req CommentRequest
+
+	err := c.BindJSON(&req)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	taskID, ok := pathTask(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	comment := database.Comment{
+		ID:        primitive.NewObjectID(),
+		TaskID:    taskID,
+		Author:    middleware.Actor(c),
+		Body:      req.Body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = database.Comments.InsertComment(comment)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// patchComment edits a comment.
+// @Summary Edit a comment
+// @Description Replace the body of a comment. Only its author can edit it; the previous body is kept in its edits.
+// @ID patchComment
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param commentId path string true "ID of the comment" Pattern("^[0-9a-fA-F]{24}$")
+// @Param body body CommentRequest true "New body"
+// @Success 200 {object} database.Comment "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 403 {object} middleware.Problem "Forbidden"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/comments/{commentId} [patch]
+// @Tags comments
+func (cc *CommentController) patchComment(c *gin.Context) {
+
+	var req CommentRequest
+
+	err := c.BindJSON(&req)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comment, ok := authoredComment(c)
+	if !ok {
+		return
+	}
+
+	if req.Body == comment.Body {
+		c.JSON(http.StatusOK, comment)
+		return
+	}
+
+	now := time.Now().UTC()
+	comment.Edits = append(comment.Edits, database.CommentEdit{Body: comment.Body, EditedAt: now})
+	comment.Body = req.Body
+	comment.UpdatedAt = now
+
+	err = database.Comments.UpdateComment(comment)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// deleteComment deletes a comment.
+// @Summary Delete a comment
+// @Description Delete a comment. Only its author can delete it.
+// @ID deleteComment
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param commentId path string true "ID of the comment" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {string} string "OK"
+// @Failure 403 {object} middleware.Problem "Forbidden"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/comments/{commentId} [delete]
+// @Tags comments
+func (cc *CommentController) deleteComment(c *gin.Context) {
+
+	comment, ok := authoredComment(c)
+	if !ok {
+		return
+	}
+
+	deleteCount, err := database.Comments.DeleteComment(comment.ID.Hex())
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if deleteCount == 0 {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	c.JSON(http.StatusOK, "OK")
+}
+
+// deleteTaskComments removes the comments of a deleted task. It does nothing
+// when comments are not set up.
+func deleteTaskComments(taskID string) {
+	if database.Comments == nil {
+		return
+	}
+
+	if _, err := database.Comments.DeleteTaskComments(taskID); err != nil {
+		log.Println("Error Delete Task Comments: ", err)
+	}
+}
+
+// pathTask returns the ID of the task in the path, answering 404 and
+// reporting false when there is no such task.
+func pathTask(c *gin.Context) (string, bool) {
+
+	taskID := c.Param("id")
+
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return "", false
+	}
+
+	task, err := database.MongoDB.GetTaskByID(taskID)
+
+	if err != nil || task.ID.IsZero() {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return "", false
+	}
+
+	return taskID, true
+}
+
+// authoredComment returns the comment in the path if it belongs to the task in
+// the path and the caller wrote it. Otherwise it answers 404 or 403 and
+// reports false.
+func authoredComment(c *gin.Context) (database.Comment, bool) {
+
+	comment, err := database.Comments.GetComment(c.Param("commentId"))
+
+	if err != nil || comment.ID.IsZero() || comment.TaskID != c.Param("id") {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return database.Comment{}, false
+	}
+
+	if comment.Author != middleware.Actor(c) {
+		middleware.AbortWithProblem(c, http.StatusForbidden, "Only the author can change a comment")
+		return database.Comment{}, false
+	}
+
+	return comment, true
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+)
+
+type CommentMockDB struct {
+	comments []database.Comment
+}
+
+func (db *CommentMockDB) InsertComment(comment database.Comment) error {
+	db.comments = append(db.comments, comment)
+	return nil
+}
+
+func (db *CommentMockDB) GetComment(commentID string) (database.Comment, error) {
+	for _, comment := range db.comments {
+		if comment.ID.Hex() == commentID {
+			return comment, nil
+		}
+	}
+	return database.Comment{}, nil
+}
+
+func (db *CommentMockDB) GetComments(taskID string) ([]database.Comment, error) {
+	comments := []database.Comment{}
+	for _, comment := range db.comments {
+		if comment.TaskID == taskID {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+func (db *CommentMockDB) UpdateComment(comment database.Comment) error {
+	for i, c := range db.comments {
+		if c.ID == comment.ID {
+			db.comments[i] = comment
+		}
+	}
+	return nil
+}
+
+func (db *CommentMockDB) DeleteComment(commentID string) (int64, error) {
+	for i, comment := range db.comments {
+		if comment.ID.Hex() == commentID {
+			db.comments = append(db.comments[:i], db.comments[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (db *CommentMockDB) DeleteTaskComments(taskID string) (int64, error) {
+	kept := db.comments[:0]
+	for _, comment := range db.comments {
+		if comment.TaskID != taskID {
+			kept = append(kept, comment)
+		}
+	}
+	deleted := int64(len(db.comments) - len(kept))
+	db.comments = kept
+	return deleted, nil
+}
+
+func serveComments(tasks *GRPCMockDB, comments *CommentMockDB, method, target, actor, body string) *httptest.ResponseRecorder {
+	database.MongoDB = tasks
+	database.Comments = comments
+	NewTasksController()
+	NewCommentController()
+
+	r := gin.New()
+	r.Use(middleware.RequestContext())
+	SetUpTasksRoutes(r)
+	SetUpCommentRoutes(r)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.ActorHeader, actor)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func Test_CommentThread(t *testing.T) {
+	tasks, comments := &GRPCMockDB{}, &CommentMockDB{}
+	ids := newTasks(tasks, "release", "other")
+	path := "/api/v1/tasks/" + ids[0] + "/comments"
+
+	w := serveComments(tasks, comments, http.MethodPost, path, "alice", `{"body": "Ship it **today**"}`)
+
+	var created database.Comment
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "alice", created.Author)
+	assert.Equal(t, ids[0], created.TaskID)
+
+	w = serveComments(tasks, comments, http.MethodPost, path, "bob", `{"body": ""}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveComments(tasks, comments, http.MethodPatch, path+"/"+created.ID.Hex(), "bob", `{"body": "hijacked"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serveComments(tasks, comments, http.MethodPatch, path+"/"+created.ID.Hex(), "alice", `{"body": "Ship it tomorrow"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serveComments(tasks, comments, http.MethodGet, path, "bob", "")
+
+	var thread []database.Comment
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &thread))
+	require.Len(t, thread, 1)
+	assert.Equal(t, "Ship it tomorrow", thread[0].Body)
+	require.Len(t, thread[0].Edits, 1)
+	assert.Equal(t, "Ship it **today**", thread[0].Edits[0].Body)
+
+	w = serveComments(tasks, comments, http.MethodDelete, "/api/v1/tasks/"+ids[1]+"/comments/"+created.ID.Hex(), "alice", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "comment of another task")
+
+	w = serveComments(tasks, comments, http.MethodDelete, path+"/"+created.ID.Hex(), "alice", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, comments.comments)
+}
+
+func Test_CommentsOnMissingTask(t *testing.T) {
+	tasks, comments := &GRPCMockDB{}, &CommentMockDB{}
+
+	w := serveComments(tasks, comments, http.MethodGet, "/api/v1/tasks/not-hex/comments", "alice", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveComments(tasks, comments, http.MethodPost, "/api/v1/tasks/65a000000000000000000001/comments", "alice", `{"body": "hi"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, comments.comments)
+}
+
+func Test_CommentsDeletedWithTask(t *testing.T) {
+	tasks, comments := &GRPCMockDB{}, &CommentMockDB{}
+	ids := newTasks(tasks, "release", "other")
+
+	for _, id := range ids {
+		w := serveComments(tasks, comments, http.MethodPost, "/api/v1/tasks/"+id+"/comments", "alice", `{"body": "Ship it"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	w := serveComments(tasks, comments, http.MethodDelete, "/api/v1/tasks/"+ids[0], "alice", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Len(t, comments.comments, 1)
+	assert.Equal(t, ids[1], comments.comments[0].TaskID)
+}
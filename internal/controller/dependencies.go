@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errInvalidBlocker  = errors.New("blocked_by must list other existing tasks, each once")
+	errDependencyCycle = errors.New("blocked_by would create a dependency cycle")
+	errBlocked         = errors.New("task is blocked by an incomplete task")
+)
+
+// getTaskOrder sorts tasks so that every task comes after its blockers.
+// @Summary Order tasks by their dependencies
+// @Description Get the requested tasks in an order in which they can be worked on: every task comes after the tasks that block it, directly or through other tasks. Tasks that do not depend on each other keep their ID order.
+// @ID getTaskOrder
+// @Accept json
+// @Produce json
+// @Param ids query []string true "IDs of the tasks to order, repeated or comma separated" collectionFormat(multi)
+// @Success 200 {array} TaskResponse "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/order [get]
+// @Tags tasks
+func (tc *TaskController) getTaskOrder(c *gin.Context) {
+
+	ids := queryList(c, "ids")
+	for _, id := range ids {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			middleware.AbortWithProblem(c, http.StatusBadRequest, errInvalidTaskID.Error())
+			return
+		}
+	}
+
+	if len(ids) == 0 {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, "ids is required")
+		return
+	}
+
+	ordered, err := orderTasks(ids)
+
+	if errors.Is(err, errTaskNotFound) {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	if errors.Is(err, errDependencyCycle) {
+		middleware.AbortWithProblem(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	results := make([]TaskResponse, 0, len(ordered))
+	for _, t := range ordered {
+		results = append(results, taskResponse(t))
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// orderTasks loads the tasks with ids and everything that blocks them, and
+// returns the requested ones sorted so that blockers come first. Blockers that
+// no longer exist are ignored.
+func orderTasks(ids []string) ([]database.Task, error) {
+
+	tasks := map[string]database.Task{}
+	requested := map[string]bool{}
+
+	for _, id := range ids {
+		task, err := database.MongoDB.GetTaskByID(id)
+		if err != nil || task.ID.IsZero() {
+			return nil, errTaskNotFound
+		}
+		tasks[id] = task
+		requested[id] = true
+	}
+
+	pending := append([]string(nil), ids...)
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for _, blockerID := range tasks[id].BlockedBy {
+			if _, ok := tasks[blockerID]; ok {
+				continue
+			}
+			blocker, err := database.MongoDB.GetTaskByID(blockerID)
+			if err != nil || blocker.ID.IsZero() {
+				continue
+			}
+			tasks[blockerID] = blocker
+			pending = append(pending, blockerID)
+		}
+	}
+
+	// Kahn's algorithm, taking the ready task with the lowest ID each time.
+	waiting := map[string]int{}
+	blocks := map[string][]string{}
+	var ready []string
+	for id, task := range tasks {
+		for _, blockerID := range task.BlockedBy {
+			if _, ok := tasks[blockerID]; ok {
+				waiting[id]++
+				blocks[blockerID] = append(blocks[blockerID], id)
+			}
+		}
+		if waiting[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	var ordered []database.Task
+	visited := 0
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		id := ready[0]
+		ready = ready[1:]
+		visited++
+
+		if requested[id] {
+			ordered = append(ordered, tasks[id])
+		}
+		for _, blockedID := range blocks[id] {
+			waiting[blockedID]--
+			if waiting[blockedID] == 0 {
+				ready = append(ready, blockedID)
+			}
+		}
+	}
+
+	if visited < len(tasks) {
+		return nil, errDependencyCycle
+	}
+
+	return ordered, nil
+}
+
+// checkDependencies reports whether taskID can be blocked by blockers: each
+// must be another existing task, listed once, and none may already depend on
+// taskID, directly or through other tasks.
+func checkDependencies(taskID string, blockers []string) error {
+
+	seen := map[string]bool{}
+	for _, id := range blockers {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil || id == taskID || seen[id] {
+			return fmt.Errorf("%w: %q", errInvalidBlocker, id)
+		}
+		seen[id] = true
+
+		task, err := database.MongoDB.GetTaskByID(id)
+		if err != nil || task.ID.IsZero() {
+			return fmt.Errorf("%w: %q", errInvalidBlocker, id)
+		}
+	}
+
+	visited := map[string]bool{}
+	pending := append([]string(nil), blockers...)
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if id == taskID {
+			return errDependencyCycle
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		task, err := database.MongoDB.GetTaskByID(id)
+		if err != nil || task.ID.IsZero() {
+			continue
+		}
+		pending = append(pending, task.BlockedBy...)
+	}
+
+	return nil
+}
+
+// checkBlockers reports whether a task can be saved as after: a completed task
+// must not have an incomplete blocker. It is only checked when the task is
+// being completed or its blockers change, and blockers that no longer exist
+// are ignored.
+func checkBlockers(before, after database.Task) error {
+
+	if after.Status != int(Completed) {
+		return nil
+	}
+	if before.Status == int(Completed) && sameBlockers(before.BlockedBy, after.BlockedBy) {
+		return nil
+	}
+
+	for _, id := range after.BlockedBy {
+		blocker, err := database.MongoDB.GetTaskByID(id)
+		if err != nil || blocker.ID.IsZero() {
+			continue
+		}
+		if blocker.Status != int(Completed) {
+			return fmt.Errorf("%w: %q", errBlocked, id)
+		}
+	}
+
+	return nil
+}
+
+func sameBlockers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
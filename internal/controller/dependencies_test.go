@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTasks stores a top-level task for every name and returns their IDs in
+// the same order.
+func newTasks(mock *GRPCMockDB, names ...string) []string {
+	var ids []string
+	for _, name := range names {
+		task := database.Task{ID: primitive.NewObjectID(), Name: name}
+		mock.tasks = append(mock.tasks, task)
+		ids = append(ids, task.ID.Hex())
+	}
+	return ids
+}
+
+func Test_PatchTaskBlockedBy(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "design", "build", "ship")
+
+	w := serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[1], `{"blocked_by": ["`+ids[0]+`"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{ids[0]}, mock.tasks[1].BlockedBy)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[2], `{"blocked_by": ["`+ids[1]+`"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for name, blockedBy := range map[string]string{
+		"cycle":     `["` + ids[2] + `"]`,
+		"self":      `["` + ids[0] + `"]`,
+		"duplicate": `["` + ids[1] + `", "` + ids[1] + `"]`,
+		"missing":   `["` + primitive.NewObjectID().Hex() + `"]`,
+		"not hex":   `["abc"]`,
+	} {
+		w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"blocked_by": `+blockedBy+`}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+	assert.Empty(t, mock.tasks[0].BlockedBy)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[2], `{"blocked_by": []}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, mock.tasks[2].BlockedBy)
+}
+
+func Test_CompleteBlockedTask(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "design", "build")
+	mock.tasks[1].BlockedBy = []string{ids[0]}
+
+	w := serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[1], `{"status": 1}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serveTasks(mock, http.MethodPut, "/api/v1/tasks/"+ids[1], `{"name": "build", "status": 1}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, int(Incomplete), mock.tasks[1].Status)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"status": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serveTasks(mock, http.MethodPut, "/api/v1/tasks/"+ids[1], `{"name": "build it", "status": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int(Completed), mock.tasks[1].Status)
+	assert.Equal(t, []string{ids[0]}, mock.tasks[1].BlockedBy, "PUT keeps the blockers")
+}
+
+func Test_GetTaskOrder(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "ship", "test", "build", "docs")
+	mock.tasks[0].BlockedBy = []string{ids[1], ids[3]}
+	mock.tasks[1].BlockedBy = []string{ids[2]}
+
+	w := serveTasks(mock, http.MethodGet, "/api/v1/tasks/order?ids="+ids[0]+","+ids[1]+"&ids="+ids[3], "")
+
+	var ordered []TaskResponse
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ordered))
+
+	var names []string
+	for _, task := range ordered {
+		names = append(names, task.Name)
+	}
+	assert.Equal(t, []string{"test", "docs", "ship"}, names)
+
+	w = serveTasks(mock, http.MethodGet, "/api/v1/tasks/order?ids="+primitive.NewObjectID().Hex(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveTasks(mock, http.MethodGet, "/api/v1/tasks/order", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mock.tasks[2].BlockedBy = []string{ids[0]}
+	w = serveTasks(mock, http.MethodGet, "/api/v1/tasks/order?ids="+ids[0], "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+
+	// resetEvent tells a resuming client that some events were missed and it
+	// should reload the task list before applying the ones that follow.
+	resetEvent = "reset"
+
+	sseBuffer    = 64
+	sseHeartbeat = 15 * time.Second
+)
+
+// streamTaskEvents streams task changes as Server-Sent Events.
+// @Summary Stream task events
+// @Description Stream task.created, task.updated and task.deleted events as Server-Sent Events. Clients resuming with Last-Event-ID receive the buffered events they missed; a "reset" event is sent first when that is no longer possible. Deletions carry the task as it was and are filtered by it; those made on another replica carry no task, so they only pass filters without status, labels or assignees.
+// @ID streamTaskEvents
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Param types query []string false "Only events of these types" collectionFormat(csv)
+// @Param task_ids query []string false "Only events of these tasks" collectionFormat(csv)
+// @Param status query int false "Only events of tasks with this status" Enums(0, 1)
+// @Param labels query []string false "Only events of tasks with every one of these label IDs" collectionFormat(csv)
+// @Param assignees query []string false "Only events of tasks assigned to every one of these users" collectionFormat(csv)
+// @Success 200 {object} events.Event "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Router /tasks/events [get]
+// @Tags tasks
+func (tc *TaskController) streamTaskEvents(c *gin.Context) {
+
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	lastID, _ := strconv.ParseUint(c.GetHeader(lastEventIDHeader), 10, 64)
+
+	missed, complete, live, unsubscribe := events.Default.SubscribeSince(lastID, sseBuffer)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if lastID > 0 && !complete {
+		c.Render(-1, sse.Event{Event: resetEvent, Data: gin.H{}})
+	}
+
+	for _, e := range missed {
+		if filter.Matches(e) {
+			renderEvent(c, e)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-live:
+			if !ok {
+				return
+			}
+			if !filter.Matches(e) {
+				continue
+			}
+			renderEvent(c, e)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			c.Writer.WriteString(": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// parseEventFilter reads the events a stream is limited to from the query.
+func parseEventFilter(c *gin.Context) (events.Filter, error) {
+	filter := events.Filter{
+		TaskIDs:     queryList(c, "task_ids"),
+		LabelIDs:    queryList(c, "labels"),
+		AssigneeIDs: queryList(c, "assignees"),
+	}
+
+	for _, t := range queryList(c, "types") {
+		if !slices.Contains(events.Types, events.Type(t)) {
+			return events.Filter{}, fmt.Errorf("unknown event type %q", t)
+		}
+		filter.Types = append(filter.Types, events.Type(t))
+	}
+
+	if value := c.Query("status"); value != "" {
+		status, err := strconv.Atoi(value)
+		if err != nil || (TaskStatus(status) != Incomplete && TaskStatus(status) != Completed) {
+			return events.Filter{}, fmt.Errorf("status must be %d or %d", Incomplete, Completed)
+		}
+		filter.Status = &status
+	}
+
+	return filter, nil
+}
+
+func renderEvent(c *gin.Context, e events.Event) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(e.Seq, 10),
+		Event: string(e.Type),
+		Data:  e,
+	})
+}
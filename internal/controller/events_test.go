@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+)
+
+func Test_StreamTaskEventsResume(t *testing.T) {
+	events.Default = events.NewBus(10)
+	for _, taskID := range []string{"a", "b", "c"} {
+		events.Default.Publish(events.NewTaskEvent(events.TaskCreated, taskID, nil, ""))
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/events", nil).WithContext(ctx)
+	c.Request.Header.Set("Last-Event-ID", "1")
+	cancel()
+
+	tC := &TaskController{}
+	tC.streamTaskEvents(c)
+
+	body := w.Body.String()
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.NotContains(t, body, "id:1\n")
+	assert.Contains(t, body, "id:2\nevent:task.created\n")
+	assert.Contains(t, body, "id:3\nevent:task.created\n")
+	assert.NotContains(t, body, "event:reset")
+}
+
+func Test_StreamTaskEventsReset(t *testing.T) {
+	events.Default = events.NewBus(1)
+	for _, taskID := range []string{"a", "b", "c"} {
+		events.Default.Publish(events.NewTaskEvent(events.TaskCreated, taskID, nil, ""))
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/events", nil).WithContext(ctx)
+	c.Request.Header.Set("Last-Event-ID", "1")
+	cancel()
+
+	tC := &TaskController{}
+	tC.streamTaskEvents(c)
+
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "event:reset\n"))
+	assert.Contains(t, body, "id:3\n")
+}
+
+func Test_StreamTaskEventsLive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	events.Default = events.NewBus(10)
+
+	r := gin.New()
+	NewTasksController()
+	SetUpTasksRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/tasks/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	events.Default.Publish(events.NewTaskEvent(events.TaskDeleted, "abc", nil, ""))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "id:1\n", line)
+
+	line, _ = reader.ReadString('\n')
+	assert.Equal(t, "event:task.deleted\n", line)
+
+	line, _ = reader.ReadString('\n')
+	assert.Contains(t, line, `"task_id":"abc"`)
+}
+
+func Test_StreamTaskEventsFilter(t *testing.T) {
+	events.Default = events.NewBus(10)
+	events.Default.Publish(events.NewTaskEvent(events.TaskCreated, "a", &database.Task{LabelIDs: []string{"bug"}, AssigneeIDs: []string{"alice"}}, ""))
+	events.Default.Publish(events.NewTaskEvent(events.TaskCreated, "b", &database.Task{LabelIDs: []string{"bug"}}, ""))
+	events.Default.Publish(events.NewTaskEvent(events.TaskCreated, "c", &database.Task{AssigneeIDs: []string{"alice"}}, ""))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/events?labels=bug&assignees=alice", nil).WithContext(ctx)
+	c.Request.Header.Set("Last-Event-ID", "0")
+	cancel()
+
+	tC := &TaskController{}
+	tC.streamTaskEvents(c)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `"task_id":"a"`)
+	assert.NotContains(t, body, `"task_id":"b"`)
+	assert.NotContains(t, body, `"task_id":"c"`)
+}
+
+// deletedTaskMockDB holds a single task to be deleted.
+type deletedTaskMockDB struct {
+	MockDB
+	task database.Task
+}
+
+func (db *deletedTaskMockDB) GetTaskByID(taskID string) (database.Task, error) {
+	return db.task, nil
+}
+
+func Test_StreamTaskEventsFilterDeleted(t *testing.T) {
+	events.Default = events.NewBus(10)
+	tC := &TaskController{}
+
+	for taskID, labels := range map[string][]string{"a": {"bug"}, "b": {"docs"}} {
+		database.MongoDB = &deletedTaskMockDB{task: database.Task{Name: taskID, LabelIDs: labels}}
+		assert.NoError(t, tC.removeTask(changeOrigin{}, taskID, OrphanSubtasks))
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/events?types=task.deleted&labels=bug", nil).WithContext(ctx)
+	c.Request.Header.Set("Last-Event-ID", "0")
+	cancel()
+
+	tC.streamTaskEvents(c)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `"task_id":"a"`)
+	assert.NotContains(t, body, `"task_id":"b"`)
+}
+
+func Test_StreamTaskEventsInvalidFilter(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/events?types=task.archived", nil)
+
+	tC := &TaskController{}
+	tC.streamTaskEvents(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown event type \"task.archived\"`)
+}
@@ -0,0 +1,574 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+
+	// exportFlushEvery is how many tasks are written between flushes of the
+	// response, so that a large export reaches the client progressively.
+	exportFlushEvery = 100
+
+	// maxImportLine bounds a single JSON Lines record.
+	maxImportLine = 1 << 20
+
+	// maxImportErrors bounds the errors listed in an import result; the
+	// Failed count stays exact.
+	maxImportErrors = 1000
+)
+
+var csvHeader = []string{
+	"id", "name", "status", "parent_id", "blocked_by", "due", "recurrence",
+	"project_id", "label_ids", "assignee_ids", "watcher_ids",
+}
+
+// csvListSeparator separates the IDs of a list column in CSV.
+const csvListSeparator = ";"
+
+// TaskRecord is one task in an export or import file.
+type TaskRecord struct {
+	ID          string      `json:"id,omitempty"`
+	Name        string      `json:"name"`
+	Status      *TaskStatus `json:"status"`
+	ParentID    string      `json:"parent_id,omitempty"`
+	BlockedBy   []string    `json:"blocked_by,omitempty"`
+	Due         *time.Time  `json:"due,omitempty"`
+	Recurrence  string      `json:"recurrence,omitempty"`
+	ProjectID   string      `json:"project_id,omitempty"`
+	LabelIDs    []string    `json:"label_ids,omitempty"`
+	AssigneeIDs []string    `json:"assignee_ids,omitempty"`
+	WatcherIDs  []string    `json:"watcher_ids,omitempty"`
+}
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult reports what an import did, or would do in a dry run.
+// Skipped counts records whose ID already exists or appeared earlier in the
+// file.
+type ImportResult struct {
+	DryRun  bool          `json:"dry_run"`
+	Created int           `json:"created"`
+	Skipped int           `json:"skipped"`
+	Failed  int           `json:"failed"`
+	Errors  []ImportError `json:"errors"`
+}
+
+// exportTasks streams every task as JSON Lines or CSV.
+// @Summary Export tasks
+// @Description Stream every task, ordered by ID, as JSON Lines (one TaskRecord object per line) or as CSV with an id,name,status,parent_id,blocked_by,due,recurrence,project_id,label_ids,assignee_ids,watcher_ids header. In CSV, due is an RFC 3339 time and the IDs of a list column are separated by semicolons.
+// @ID exportTasks
+// @Produce application/x-ndjson,text/csv
+// @Param format query string false "File format" Enums(jsonl, csv) default(jsonl)
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/export [get]
+// @Tags tasks
+func (tc *TaskController) exportTasks(c *gin.Context) {
+
+	format := c.DefaultQuery("format", formatJSONL)
+	if format != formatJSONL && format != formatCSV {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, "format must be jsonl or csv")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	cursor, err := database.MongoDB.IterateTasks(ctx)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer cursor.Close(ctx)
+
+	contentType := mimeNDJSON
+	if format == formatCSV {
+		contentType = "text/csv"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="tasks.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	write := newRecordWriter(format, c.Writer)
+
+	count := 0
+	for cursor.Next(ctx) {
+		task, err := cursor.Task()
+		if err == nil {
+			err = write(taskRecord(task))
+		}
+		if err != nil {
+			// The status line is already sent, so the export is cut short.
+			log.Println("Error Export Tasks: ", err)
+			return
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		log.Println("Error Export Tasks: ", err)
+		return
+	}
+
+	write(nil)
+	c.Writer.Flush()
+}
+
+func taskRecord(task database.Task) *TaskRecord {
+	status := TaskStatus(task.Status)
+	return &TaskRecord{
+		ID:          task.ID.Hex(),
+		Name:        task.Name,
+		Status:      &status,
+		ParentID:    task.ParentID,
+		BlockedBy:   task.BlockedBy,
+		Due:         task.Due,
+		Recurrence:  task.Recurrence,
+		ProjectID:   task.ProjectID,
+		LabelIDs:    task.LabelIDs,
+		AssigneeIDs: task.AssigneeIDs,
+		WatcherIDs:  task.WatcherIDs,
+	}
+}
+
+// request returns the fields of record that a new task is created from; the
+// parent, blockers, assignees and watchers are set separately.
+func (record TaskRecord) request() TaskRequest {
+	return TaskRequest{
+		Name:       record.Name,
+		Status:     record.Status,
+		Due:        record.Due,
+		Recurrence: record.Recurrence,
+		ProjectID:  record.ProjectID,
+		LabelIDs:   record.LabelIDs,
+	}
+}
+
+// newRecordWriter returns a function writing one record in format. It is
+// called with nil once at the end to flush buffered output.
+func newRecordWriter(format string, w io.Writer) func(*TaskRecord) error {
+	if format == formatJSONL {
+		enc := json.NewEncoder(w)
+		return func(record *TaskRecord) error {
+			if record == nil {
+				return nil
+			}
+			return enc.Encode(record)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	headerWritten := false
+	return func(record *TaskRecord) error {
+		if !headerWritten {
+			headerWritten = true
+			if err := cw.Write(csvHeader); err != nil {
+				return err
+			}
+		}
+		if record == nil {
+			cw.Flush()
+			return cw.Error()
+		}
+		due := ""
+		if record.Due != nil {
+			due = record.Due.Format(time.RFC3339Nano)
+		}
+		return cw.Write([]string{
+			record.ID,
+			record.Name,
+			strconv.Itoa(int(*record.Status)),
+			record.ParentID,
+			strings.Join(record.BlockedBy, csvListSeparator),
+			due,
+			record.Recurrence,
+			record.ProjectID,
+			strings.Join(record.LabelIDs, csvListSeparator),
+			strings.Join(record.AssigneeIDs, csvListSeparator),
+			strings.Join(record.WatcherIDs, csvListSeparator),
+		})
+	}
+}
+
+// importTasks creates tasks from a JSON Lines or CSV file.
+// @Summary Import tasks
+// @Description Create tasks from JSON Lines or CSV in the format produced by the export; unknown fields and columns are rejected. Records are validated one by one and the invalid ones are reported by line without stopping the import. Records with an ID that already exists, or that appeared earlier in the file, are skipped; records without an ID get a new one. Parents and blockers may refer to tasks later in the file: they are set once every record is read, and a task whose parent or blockers cannot be set is kept without them and reported as failed after the other errors.
+// @ID importTasks
+// @Accept application/x-ndjson,text/csv
+// @Produce json
+// @Param format query string false "File format, detected from Content-Type when omitted" Enums(jsonl, csv)
+// @Param dry_run query bool false "Validate and report without creating any task"
+// @Success 200 {object} ImportResult "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Router /tasks/import [post]
+// @Tags tasks
+func (tc *TaskController) importTasks(c *gin.Context) {
+
+	format := c.Query("format")
+	if format == "" {
+		format = importFormat(c.ContentType())
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	var next func() (int, TaskRecord, error)
+	switch format {
+	case formatJSONL:
+		next = jsonlRecords(c.Request.Body)
+	case formatCSV:
+		var err error
+		next, err = csvRecords(c.Request.Body)
+		if err != nil {
+			middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	default:
+		middleware.AbortWithProblem(c, http.StatusBadRequest, "format must be jsonl or csv")
+		return
+	}
+
+	origin := originOf(c)
+	result := ImportResult{DryRun: dryRun, Errors: []ImportError{}}
+	seen := map[string]bool{}
+	created := map[string]bool{}
+	var links []importLink
+
+	fail := func(line int, err error) {
+		result.Failed++
+		if len(result.Errors) < maxImportErrors {
+			result.Errors = append(result.Errors, ImportError{Line: line, Error: err.Error()})
+		}
+	}
+
+	for {
+		line, record, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var stop *importStop
+		if errors.As(err, &stop) {
+			fail(line, stop.err)
+			break
+		}
+		if err != nil {
+			fail(line, err)
+			continue
+		}
+
+		if err := validateRecord(record); err != nil {
+			fail(line, err)
+			continue
+		}
+
+		taskID := record.ID
+		if taskID == "" {
+			taskID = primitive.NewObjectID().Hex()
+		} else {
+			if seen[taskID] {
+				result.Skipped++
+				continue
+			}
+			seen[taskID] = true
+
+			existing, _ := database.MongoDB.GetTaskByID(taskID)
+			if !existing.ID.IsZero() {
+				result.Skipped++
+				continue
+			}
+		}
+
+		if err := checkTaskRequest(record.request()); err != nil {
+			fail(line, err)
+			continue
+		}
+
+		if !dryRun {
+			if err := tc.importTask(origin, record, taskID); err != nil {
+				fail(line, err)
+				continue
+			}
+		}
+
+		result.Created++
+		created[taskID] = true
+		if record.ParentID != "" || len(record.BlockedBy) > 0 {
+			links = append(links, importLink{line: line, taskID: taskID, parentID: record.ParentID, blockedBy: record.BlockedBy})
+		}
+	}
+
+	for _, link := range links {
+		var err error
+		if dryRun {
+			err = link.check(created)
+		} else {
+			_, err = tc.applyPatch(origin, link.taskID, link.patch())
+		}
+		if err != nil {
+			fail(link.line, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// importTask creates the task with taskID from record, without its parent and
+// blockers.
+func (tc *TaskController) importTask(origin changeOrigin, record TaskRecord, taskID string) error {
+
+	task := taskFromRequest(record.request(), taskID)
+	task.AssigneeIDs = record.AssigneeIDs
+	task.WatcherIDs = record.WatcherIDs
+
+	if err := database.MongoDB.InsertSingleTask(*task); err != nil {
+		return err
+	}
+
+	tc.taskChanged(origin, database.AuditCreate, taskID, nil, task)
+
+	return nil
+}
+
+// importLink is the parent and blockers of an imported task, which are set
+// after every record is read so that they may refer to tasks later in the
+// file.
+type importLink struct {
+	line      int
+	taskID    string
+	parentID  string
+	blockedBy []string
+}
+
+func (link importLink) patch() TaskPatch {
+	var patch TaskPatch
+	if link.parentID != "" {
+		patch.ParentID = &link.parentID
+	}
+	if len(link.blockedBy) > 0 {
+		patch.BlockedBy = &link.blockedBy
+	}
+	return patch
+}
+
+// check reports, for a dry run, whether the parent and blockers of link are
+// tasks that exist or would be created.
+func (link importLink) check(created map[string]bool) error {
+	exists := func(id string) bool {
+		if created[id] {
+			return true
+		}
+		task, err := database.MongoDB.GetTaskByID(id)
+		return err == nil && !task.ID.IsZero()
+	}
+
+	if link.parentID != "" && (link.parentID == link.taskID || !exists(link.parentID)) {
+		return errParentNotFound
+	}
+	for _, id := range link.blockedBy {
+		if id == link.taskID || !exists(id) {
+			return fmt.Errorf("%w: %q", errInvalidBlocker, id)
+		}
+	}
+	return nil
+}
+
+// importStop is returned by a record reader that cannot go on, after a read
+// error or an overlong line.
+type importStop struct {
+	err error
+}
+
+func (e *importStop) Error() string {
+	return e.err.Error()
+}
+
+func importFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case mimeNDJSON, "application/jsonl", "application/json-lines":
+		return formatJSONL
+	case "text/csv":
+		return formatCSV
+	}
+	return ""
+}
+
+func validateRecord(record TaskRecord) error {
+	if strings.TrimSpace(record.Name) == "" {
+		return errors.New("name is required")
+	}
+	if record.Status == nil {
+		return errors.New("status is required")
+	}
+	if *record.Status != Incomplete && *record.Status != Completed {
+		return fmt.Errorf("status must be %d or %d", Incomplete, Completed)
+	}
+	if record.ID != "" {
+		if _, err := primitive.ObjectIDFromHex(record.ID); err != nil {
+			return errors.New("id must be a 24 character hex string")
+		}
+	}
+	if record.ParentID != "" {
+		if _, err := primitive.ObjectIDFromHex(record.ParentID); err != nil {
+			return errors.New("parent_id must be a 24 character hex string")
+		}
+	}
+	for _, id := range record.BlockedBy {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			return errors.New("blocked_by must list 24 character hex strings")
+		}
+	}
+	return nil
+}
+
+// jsonlRecords reads one record per line, skipping blank lines.
+func jsonlRecords(r io.Reader) func() (int, TaskRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	line := 0
+
+	return func() (int, TaskRecord, error) {
+		for scanner.Scan() {
+			line++
+
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+
+			var record TaskRecord
+			dec := json.NewDecoder(strings.NewReader(text))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&record); err != nil {
+				return line, record, fmt.Errorf("invalid JSON: %w", err)
+			}
+			return line, record, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			return line + 1, TaskRecord{}, &importStop{err}
+		}
+		return line, TaskRecord{}, io.EOF
+	}
+}
+
+// csvRecords reads records from CSV with a header row naming the columns of
+// the export in any order; only name and status are required.
+func csvRecords(r io.Reader) (func() (int, TaskRecord, error), error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvHeader, name) {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "status"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must have a %s column", required)
+		}
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	list := func(row []string, name string) []string {
+		value := field(row, name)
+		if value == "" {
+			return nil
+		}
+		ids := strings.Split(value, csvListSeparator)
+		for i := range ids {
+			ids[i] = strings.TrimSpace(ids[i])
+		}
+		return ids
+	}
+
+	return func() (int, TaskRecord, error) {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return 0, TaskRecord{}, io.EOF
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, TaskRecord{}, parseErr.Err
+		}
+		if err != nil {
+			return 0, TaskRecord{}, &importStop{err}
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		record := TaskRecord{
+			ID:          field(row, "id"),
+			Name:        field(row, "name"),
+			ParentID:    field(row, "parent_id"),
+			BlockedBy:   list(row, "blocked_by"),
+			Recurrence:  field(row, "recurrence"),
+			ProjectID:   field(row, "project_id"),
+			LabelIDs:    list(row, "label_ids"),
+			AssigneeIDs: list(row, "assignee_ids"),
+			WatcherIDs:  list(row, "watcher_ids"),
+		}
+
+		if value := field(row, "status"); value != "" {
+			status, err := strconv.Atoi(value)
+			if err != nil {
+				return line, record, fmt.Errorf("status %q is not a number", value)
+			}
+			s := TaskStatus(status)
+			record.Status = &s
+		}
+
+		if value := field(row, "due"); value != "" {
+			due, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return line, record, fmt.Errorf("due %q is not an RFC 3339 time", value)
+			}
+			record.Due = &due
+		}
+
+		return line, record, nil
+	}, nil
+}
@@ -0,0 +1,282 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ExportMockDB struct {
+	MockDB
+	tasks    []database.Task
+	inserted []database.Task
+}
+
+func (db *ExportMockDB) IterateTasks(ctx context.Context) (*database.TaskCursor, error) {
+	return database.NewTaskCursor(db.tasks)
+}
+
+func (db *ExportMockDB) FindTasks(ctx context.Context, filter database.TaskFilter, offset, limit int64) (*database.TaskCursor, error) {
+	tasks := db.tasks[min(offset, int64(len(db.tasks))):]
+	if limit > 0 {
+		tasks = tasks[:min(limit, int64(len(tasks)))]
+	}
+	return database.NewTaskCursor(tasks)
+}
+
+func (db *ExportMockDB) GetTaskByID(taskID string) (database.Task, error) {
+	for _, t := range append(db.tasks, db.inserted...) {
+		if t.ID.Hex() == taskID {
+			return t, nil
+		}
+	}
+	return database.Task{}, nil
+}
+
+func (db *ExportMockDB) InsertSingleTask(task database.Task) error {
+	db.inserted = append(db.inserted, task)
+	return nil
+}
+
+func (db *ExportMockDB) UpdateTaskID(taskID string, task database.Task) error {
+	for i, t := range db.inserted {
+		if t.ID.Hex() == taskID {
+			db.inserted[i] = task
+		}
+	}
+	return nil
+}
+
+func exportTasksWith(tasks []database.Task, query string) *httptest.ResponseRecorder {
+	database.MongoDB = &ExportMockDB{tasks: tasks}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/export"+query, nil)
+
+	tC := &TaskController{}
+	tC.exportTasks(c)
+	return w
+}
+
+func importTasksWith(mock *ExportMockDB, query, contentType, body string) ImportResult {
+	database.MongoDB = mock
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/tasks/import"+query, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", contentType)
+
+	tC := &TaskController{}
+	tC.importTasks(c)
+
+	var result ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return result
+}
+
+func Test_ExportTasksJSONL(t *testing.T) {
+	id1, id2 := primitive.NewObjectID(), primitive.NewObjectID()
+
+	w := exportTasksWith([]database.Task{
+		{ID: id1, Name: "a", Status: 0},
+		{ID: id2, Name: "b", Status: 1},
+	}, "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t,
+		`{"id":"`+id1.Hex()+`","name":"a","status":0}`+"\n"+
+			`{"id":"`+id2.Hex()+`","name":"b","status":1}`+"\n",
+		w.Body.String())
+}
+
+func Test_ExportTasksCSV(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	w := exportTasksWith([]database.Task{{ID: id, Name: "a, with comma", Status: 1}}, "?format=csv")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t,
+		"id,name,status,parent_id,blocked_by,due,recurrence,project_id,label_ids,assignee_ids,watcher_ids\n"+
+			id.Hex()+`,"a, with comma",1,,,,,,,,`+"\n",
+		w.Body.String())
+}
+
+func Test_ExportTasksUnknownFormat(t *testing.T) {
+	w := exportTasksWith(nil, "?format=xml")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_ImportTasksJSONL(t *testing.T) {
+	existing := database.Task{ID: primitive.NewObjectID(), Name: "old", Status: 0}
+	newID := primitive.NewObjectID().Hex()
+	mock := &ExportMockDB{tasks: []database.Task{existing}}
+
+	body := strings.Join([]string{
+		`{"name":"no id","status":0}`,
+		`{"id":"` + newID + `","name":"with id","status":1}`,
+		``,
+		`{"id":"` + newID + `","name":"duplicate in file","status":0}`,
+		`{"id":"` + existing.ID.Hex() + `","name":"already stored","status":0}`,
+		`{"name":"","status":0}`,
+		`{"name":"bad status","status":7}`,
+		`not json`,
+	}, "\n")
+
+	result := importTasksWith(mock, "", "application/x-ndjson", body)
+
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, 2, result.Skipped)
+	assert.Equal(t, 3, result.Failed)
+	assert.Equal(t, []int{6, 7, 8}, []int{result.Errors[0].Line, result.Errors[1].Line, result.Errors[2].Line})
+	assert.Len(t, mock.inserted, 2)
+	assert.Equal(t, newID, mock.inserted[1].ID.Hex())
+}
+
+func Test_ImportTasksCSVDryRun(t *testing.T) {
+	mock := &ExportMockDB{}
+
+	body := "name,status,id\n" +
+		"a,0,\n" +
+		"b,x,\n" +
+		"c,1," + primitive.NewObjectID().Hex() + "\n"
+
+	result := importTasksWith(mock, "?format=csv&dry_run=true", "application/octet-stream", body)
+
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 3, result.Errors[0].Line)
+	assert.Empty(t, mock.inserted)
+}
+
+func Test_ImportTasksRoundTrip(t *testing.T) {
+	project := database.Project{ID: primitive.NewObjectID(), Name: "home"}
+	bug, urgent := database.Label{ID: primitive.NewObjectID(), Name: "bug"}, database.Label{ID: primitive.NewObjectID(), Name: "urgent"}
+	database.Projects = &ProjectMockDB{projects: []database.Project{project}, labels: []database.Label{bug, urgent}}
+	database.Labels = database.Projects.(*ProjectMockDB)
+
+	due := time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC)
+	parentID, blockerID := primitive.NewObjectID(), primitive.NewObjectID()
+	tasks := []database.Task{
+		// The subtask comes first, so its parent and blocker are set once
+		// the whole file is read.
+		{
+			ID:          primitive.NewObjectID(),
+			Name:        "a, with comma",
+			ParentID:    parentID.Hex(),
+			BlockedBy:   []string{blockerID.Hex()},
+			Due:         &due,
+			Recurrence:  "FREQ=WEEKLY",
+			ProjectID:   project.ID.Hex(),
+			LabelIDs:    []string{bug.ID.Hex(), urgent.ID.Hex()},
+			AssigneeIDs: []string{"alice", "bob"},
+			WatcherIDs:  []string{"carol"},
+		},
+		{ID: parentID, Name: "parent", Status: 0},
+		{ID: blockerID, Name: "blocker", Status: 1},
+	}
+
+	for _, format := range []string{"jsonl", "csv"} {
+		exported := exportTasksWith(tasks, "?format="+format).Body.String()
+
+		mock := &ExportMockDB{}
+		result := importTasksWith(mock, "?format="+format, "", exported)
+
+		assert.Equal(t, 3, result.Created, format)
+		assert.Empty(t, result.Errors, format)
+		assert.Equal(t, tasks, mock.inserted, format)
+	}
+}
+
+func Test_ImportTasksLinks(t *testing.T) {
+	taskID, missingID := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+
+	body := strings.Join([]string{
+		`{"id":"` + taskID + `","name":"a","status":0,"parent_id":"` + missingID + `"}`,
+		`{"name":"b","status":0,"blocked_by":["` + taskID + `"]}`,
+	}, "\n")
+
+	for _, query := range []string{"", "?dry_run=true"} {
+		mock := &ExportMockDB{}
+		result := importTasksWith(mock, query, "application/x-ndjson", body)
+
+		assert.Equal(t, 2, result.Created, query)
+		assert.Equal(t, 1, result.Failed, query)
+		assert.Equal(t, ImportError{Line: 1, Error: errParentNotFound.Error()}, result.Errors[0], query)
+	}
+}
+
+func Test_ImportTasksUnknownFields(t *testing.T) {
+	mock := &ExportMockDB{}
+
+	result := importTasksWith(mock, "", "application/x-ndjson", `{"name":"a","status":0,"owner":"alice"}`)
+	assert.Equal(t, 1, result.Failed)
+	assert.Empty(t, mock.inserted)
+
+	database.MongoDB = mock
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/tasks/import", strings.NewReader("name,status,owner\na,0,alice\n"))
+	c.Request.Header.Set("Content-Type", "text/csv")
+	(&TaskController{}).importTasks(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown CSV column \"owner\"`)
+}
+
+func listTasksWith(tasks []database.Task, accept string) *httptest.ResponseRecorder {
+	database.MongoDB = &ExportMockDB{tasks: tasks}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/", nil)
+	c.Request.Header.Set("Accept", accept)
+
+	tC := &TaskController{}
+	tC.getAllTasks(c)
+	return w
+}
+
+func Test_GetAllTasksStreamsJSONArray(t *testing.T) {
+	id1, id2 := primitive.NewObjectID(), primitive.NewObjectID()
+
+	w := listTasksWith([]database.Task{{ID: id1, Name: "a", Status: 0}, {ID: id2, Name: "b", Status: 1}}, "application/json")
+
+	var results []TaskResponse
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	assert.Equal(t, []TaskResponse{{ID: id1.Hex(), Name: "a", Status: 0}, {ID: id2.Hex(), Name: "b", Status: 1}}, results)
+}
+
+func Test_GetAllTasksEmptyArray(t *testing.T) {
+	w := listTasksWith(nil, "*/*")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+func Test_GetAllTasksNDJSON(t *testing.T) {
+	id1, id2 := primitive.NewObjectID(), primitive.NewObjectID()
+
+	w := listTasksWith([]database.Task{{ID: id1, Name: "a", Status: 0}, {ID: id2, Name: "b", Status: 1}}, "application/x-ndjson")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t,
+		`{"ID":"`+id1.Hex()+`","Name":"a","Status":0}`+"\n"+
+			`{"ID":"`+id2.Hex()+`","Name":"b","Status":1}`+"\n",
+		w.Body.String())
+}
@@ -0,0 +1,463 @@
+package controller
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+const (
+	mimeEventStream = "text/event-stream"
+
+	graphqlMaxLimit = 1000
+)
+
+var errInvalidTaskID = errors.New("Invalid Task ID, should be in hex format")
+
+// graphqlCodes are the codes of resolver errors in their extensions.
+var graphqlCodes = map[codes.Code]string{
+	codes.NotFound:           "NOT_FOUND",
+	codes.InvalidArgument:    "INVALID_ARGUMENT",
+	codes.FailedPrecondition: "FAILED_PRECONDITION",
+	codes.Internal:           "INTERNAL",
+}
+
+// resolverError is an error of a resolver that carries its code in the
+// extensions of the GraphQL error.
+type resolverError struct {
+	err  error
+	code codes.Code
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": graphqlCodes[e.code]}
+}
+
+// graphqlError gives err the code grpcError would give it.
+func graphqlError(err error) error {
+	return &resolverError{err: err, code: errorCode(err)}
+}
+
+// GraphQLRequest is the body of POST /graphql.
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// SetUpGraphQLRoutes mounts the GraphQL API at POST /graphql. The schema is in
+// schema.graphql.
+func SetUpGraphQLRoutes(r *gin.Engine) {
+	schema := graphql.MustParseSchema(graphqlSchema, &graphqlResolver{tc: tC})
+
+	r.POST("/graphql", tC.serveGraphQL(schema))
+}
+
+// serveGraphQL runs queries and mutations and answers with the JSON result.
+// With Accept: text/event-stream every result is sent as a "next" event
+// followed by "complete", which is how subscriptions are delivered; without
+// it, a subscription answers with its first event.
+func (tc *TaskController) serveGraphQL(schema *graphql.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var req GraphQLRequest
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			c.Error(err)
+			middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx, cancel := context.WithCancel(withOrigin(c.Request.Context(), originOf(c)))
+		defer cancel()
+
+		responses, err := schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+		if err != nil {
+			middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if c.NegotiateFormat(gin.MIMEJSON, mimeEventStream) != mimeEventStream {
+			c.JSON(http.StatusOK, <-responses)
+			return
+		}
+
+		c.Header("Content-Type", mimeEventStream)
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case res, ok := <-responses:
+				if !ok {
+					c.Render(-1, sse.Event{Event: "complete", Data: ""})
+					c.Writer.Flush()
+					return
+				}
+				c.Render(-1, sse.Event{Event: "next", Data: res})
+				c.Writer.Flush()
+			case <-heartbeat.C:
+				c.Writer.WriteString(": ping\n\n")
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+type originKey struct{}
+
+func withOrigin(ctx context.Context, origin changeOrigin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// originFrom returns the origin stored by withOrigin, or an anonymous one.
+func originFrom(ctx context.Context) changeOrigin {
+	if origin, ok := ctx.Value(originKey{}).(changeOrigin); ok {
+		return origin
+	}
+	return changeOrigin{actor: middleware.AnonymousActor}
+}
+
+// graphqlResolver resolves the Query, Mutation and Subscription types through
+// database.MongoDB and the same write paths as the REST handlers.
+type graphqlResolver struct {
+	tc *TaskController
+}
+
+func (r *graphqlResolver) Task(ctx context.Context, args struct{ ID graphql.ID }) (*taskResolver, error) {
+
+	taskID := string(args.ID)
+
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return nil, graphqlError(errInvalidTaskID)
+	}
+
+	task, err := database.MongoDB.GetTaskByID(taskID)
+
+	if err != nil || task.ID.IsZero() {
+		return nil, nil
+	}
+
+	return &taskResolver{task: task}, nil
+}
+
+type taskFilterInput struct {
+	Status       *string
+	NameContains *string
+}
+
+func (f *taskFilterInput) filter() database.TaskFilter {
+	var filter database.TaskFilter
+	if f == nil {
+		return filter
+	}
+	if f.Status != nil {
+		taskStatus := int(statusFromGraphQL(*f.Status))
+		filter.Status = &taskStatus
+	}
+	if f.NameContains != nil {
+		filter.NameContains = *f.NameContains
+	}
+	return filter
+}
+
+// Tasks returns up to limit of the tasks matching the filter in ID order,
+// after skipping offset of them.
+func (r *graphqlResolver) Tasks(ctx context.Context, args struct {
+	Filter *taskFilterInput
+	Offset int32
+	Limit  int32
+}) (*taskPageResolver, error) {
+
+	offset, limit := args.Offset, args.Limit
+	if offset < 0 || limit < 0 || limit > graphqlMaxLimit {
+		err := errors.New("offset must not be negative and limit must be between 0 and 1000")
+		return nil, &resolverError{err: err, code: codes.InvalidArgument}
+	}
+
+	// One task more than the page tells whether there are more.
+	cursor, err := database.MongoDB.FindTasks(ctx, args.Filter.filter(), int64(offset), int64(limit)+1)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	defer cursor.Close(ctx)
+
+	page := &taskPageResolver{tasks: []*taskResolver{}}
+	for cursor.Next(ctx) {
+		task, err := cursor.Task()
+		if err != nil {
+			return nil, graphqlError(err)
+		}
+		if int32(len(page.tasks)) == limit {
+			page.hasMore = true
+			break
+		}
+		page.tasks = append(page.tasks, &taskResolver{task: task})
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, graphqlError(err)
+	}
+
+	return page, nil
+}
+
+type taskInput struct {
+	Name   string
+	Status string
+}
+
+func (r *graphqlResolver) CreateTask(ctx context.Context, args struct{ Input taskInput }) (*taskResolver, error) {
+
+	taskStatus := statusFromGraphQL(args.Input.Status)
+	taskReq := TaskRequest{Name: args.Input.Name, Status: &taskStatus}
+
+	if err := binding.Validator.ValidateStruct(&taskReq); err != nil {
+		return nil, &resolverError{err: err, code: codes.InvalidArgument}
+	}
+
+	taskID := primitive.NewObjectID().Hex()
+
+	err := r.tc.createTask(originFrom(ctx), taskReq, taskID)
+
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+
+	return &taskResolver{task: *taskFromRequest(taskReq, taskID)}, nil
+}
+
+type taskPatchInput struct {
+	Name   *string
+	Status *string
+}
+
+func (r *graphqlResolver) UpdateTask(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input taskPatchInput
+}) (*taskResolver, error) {
+
+	taskID := string(args.ID)
+
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return nil, graphqlError(errInvalidTaskID)
+	}
+
+	patch := TaskPatch{Name: args.Input.Name}
+	if args.Input.Status != nil {
+		taskStatus := statusFromGraphQL(*args.Input.Status)
+		patch.Status = &taskStatus
+	}
+
+	updated, err := r.tc.applyPatch(originFrom(ctx), taskID, patch)
+
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+
+	return &taskResolver{task: updated}, nil
+}
+
+func (r *graphqlResolver) DeleteTask(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+
+	taskID := string(args.ID)
+
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return "", graphqlError(errInvalidTaskID)
+	}
+
+	err := r.tc.removeTask(originFrom(ctx), taskID, OrphanSubtasks)
+
+	if err != nil {
+		return "", graphqlError(err)
+	}
+
+	return args.ID, nil
+}
+
+type taskEventFilterInput struct {
+	Types       *[]string
+	TaskIds     *[]graphql.ID
+	Status      *string
+	LabelIds    *[]graphql.ID
+	AssigneeIds *[]string
+}
+
+func (f *taskEventFilterInput) filter() events.Filter {
+	var filter events.Filter
+	if f == nil {
+		return filter
+	}
+	if f.Types != nil {
+		for _, t := range *f.Types {
+			filter.Types = append(filter.Types, events.Type(t))
+		}
+	}
+	if f.TaskIds != nil {
+		for _, id := range *f.TaskIds {
+			filter.TaskIDs = append(filter.TaskIDs, string(id))
+		}
+	}
+	if f.Status != nil {
+		taskStatus := int(statusFromGraphQL(*f.Status))
+		filter.Status = &taskStatus
+	}
+	if f.LabelIds != nil {
+		for _, id := range *f.LabelIds {
+			filter.LabelIDs = append(filter.LabelIDs, string(id))
+		}
+	}
+	if f.AssigneeIds != nil {
+		filter.AssigneeIDs = *f.AssigneeIds
+	}
+	return filter
+}
+
+// TaskEvents bridges the event bus to a subscription until ctx is done.
+func (r *graphqlResolver) TaskEvents(ctx context.Context, args struct{ Filter *taskEventFilterInput }) (<-chan *taskEventResolver, error) {
+
+	filter := args.Filter.filter()
+	live, unsubscribe := events.Default.Subscribe(sseBuffer)
+
+	out := make(chan *taskEventResolver)
+
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-live:
+				if !ok {
+					return
+				}
+				if !filter.Matches(e) {
+					continue
+				}
+				select {
+				case out <- &taskEventResolver{event: e}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+type taskResolver struct {
+	task database.Task
+}
+
+func (r *taskResolver) ID() graphql.ID {
+	return graphql.ID(r.task.ID.Hex())
+}
+
+func (r *taskResolver) Name() string {
+	return r.task.Name
+}
+
+func (r *taskResolver) Status() string {
+	return statusToGraphQL(r.task.Status)
+}
+
+type taskPageResolver struct {
+	tasks   []*taskResolver
+	hasMore bool
+}
+
+func (r *taskPageResolver) Tasks() []*taskResolver {
+	return r.tasks
+}
+
+func (r *taskPageResolver) HasMore() bool {
+	return r.hasMore
+}
+
+type taskEventResolver struct {
+	event events.Event
+}
+
+func (r *taskEventResolver) Seq() string {
+	return strconv.FormatUint(r.event.Seq, 10)
+}
+
+func (r *taskEventResolver) Type() string {
+	return string(r.event.Type)
+}
+
+func (r *taskEventResolver) TaskID() graphql.ID {
+	return graphql.ID(r.event.TaskID)
+}
+
+func (r *taskEventResolver) Task() *taskResolver {
+	if r.event.Task == nil {
+		return nil
+	}
+
+	objectID, _ := primitive.ObjectIDFromHex(r.event.Task.ID)
+
+	return &taskResolver{task: database.Task{
+		ID:     objectID,
+		Name:   r.event.Task.Name,
+		Status: r.event.Task.Status,
+	}}
+}
+
+func (r *taskEventResolver) Actor() string {
+	return r.event.Actor
+}
+
+func (r *taskEventResolver) Timestamp() string {
+	return r.event.Timestamp.Format(time.RFC3339Nano)
+}
+
+func statusToGraphQL(status int) string {
+	if TaskStatus(status) == Completed {
+		return "COMPLETED"
+	}
+	return "INCOMPLETE"
+}
+
+func statusFromGraphQL(status string) TaskStatus {
+	if status == "COMPLETED" {
+		return Completed
+	}
+	return Incomplete
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func graphqlEngine(mock *GRPCMockDB) *gin.Engine {
+	database.MongoDB = mock
+
+	tc := &TaskController{}
+	schema := graphql.MustParseSchema(graphqlSchema, &graphqlResolver{tc: tc})
+
+	r := gin.New()
+	r.Use(middleware.RequestContext())
+	r.POST("/graphql", tc.serveGraphQL(schema))
+	return r
+}
+
+type graphqlResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, r *gin.Engine, query string, variables map[string]interface{}) graphqlResult {
+	body, _ := json.Marshal(GraphQLRequest{Query: query, Variables: variables})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.ActorHeader, "alice")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result graphqlResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	return result
+}
+
+func Test_GraphQLTasksFilterAndPage(t *testing.T) {
+	mock := &GRPCMockDB{}
+	for _, task := range []database.Task{
+		{Name: "Buy milk", Status: 0},
+		{Name: "Buy bread", Status: 1},
+		{Name: "Walk dog", Status: 0},
+		{Name: "Buy eggs", Status: 0},
+	} {
+		task.ID = primitive.NewObjectID()
+		mock.tasks = append(mock.tasks, task)
+	}
+	r := graphqlEngine(mock)
+
+	result := postGraphQL(t, r, `{
+		tasks(filter: {nameContains: "buy", status: INCOMPLETE}, limit: 1) { tasks { name status } hasMore }
+	}`, nil)
+
+	assert.Empty(t, result.Errors)
+	assert.JSONEq(t, `{"tasks": [{"name": "Buy milk", "status": "INCOMPLETE"}], "hasMore": true}`, string(result.Data["tasks"]))
+
+	result = postGraphQL(t, r, `{ tasks(filter: {nameContains: "buy", status: INCOMPLETE}, offset: 1) { tasks { name } hasMore } }`, nil)
+
+	assert.JSONEq(t, `{"tasks": [{"name": "Buy eggs"}], "hasMore": false}`, string(result.Data["tasks"]))
+}
+
+func Test_GraphQLMutations(t *testing.T) {
+	mock := &GRPCMockDB{}
+	r := graphqlEngine(mock)
+
+	result := postGraphQL(t, r, `mutation($name: String!) { createTask(input: {name: $name}) { id name status } }`,
+		map[string]interface{}{"name": "Write docs"})
+
+	require.Empty(t, result.Errors)
+	var created struct{ ID, Name, Status string }
+	json.Unmarshal(result.Data["createTask"], &created)
+	assert.Equal(t, "Write docs", created.Name)
+	assert.Equal(t, "INCOMPLETE", created.Status)
+	assert.Equal(t, "alice", mock.audit[0].Actor)
+
+	result = postGraphQL(t, r, `mutation($id: ID!) { updateTask(id: $id, input: {status: COMPLETED}) { name status } }`,
+		map[string]interface{}{"id": created.ID})
+
+	assert.JSONEq(t, `{"name": "Write docs", "status": "COMPLETED"}`, string(result.Data["updateTask"]))
+
+	result = postGraphQL(t, r, `query($id: ID!) { task(id: $id) { status } }`, map[string]interface{}{"id": created.ID})
+
+	assert.JSONEq(t, `{"status": "COMPLETED"}`, string(result.Data["task"]))
+
+	result = postGraphQL(t, r, `mutation($id: ID!) { deleteTask(id: $id) }`, map[string]interface{}{"id": created.ID})
+
+	assert.JSONEq(t, `"`+created.ID+`"`, string(result.Data["deleteTask"]))
+	assert.Empty(t, mock.tasks)
+
+	result = postGraphQL(t, r, `mutation($id: ID!) { deleteTask(id: $id) }`, map[string]interface{}{"id": created.ID})
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, errTaskNotFound.Error(), result.Errors[0].Message)
+	assert.Equal(t, "NOT_FOUND", result.Errors[0].Extensions.Code)
+}
+
+func Test_GraphQLValidation(t *testing.T) {
+	r := graphqlEngine(&GRPCMockDB{})
+
+	result := postGraphQL(t, r, `mutation { createTask(input: {name: "a", status: DONE}) { id } }`, nil)
+	assert.NotEmpty(t, result.Errors)
+
+	result = postGraphQL(t, r, `{ task(id: "not-hex") { id } }`, nil)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, errInvalidTaskID.Error(), result.Errors[0].Message)
+	assert.Equal(t, "INVALID_ARGUMENT", result.Errors[0].Extensions.Code)
+
+	result = postGraphQL(t, r, `{ tasks(limit: 5000) { hasMore } }`, nil)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "INVALID_ARGUMENT", result.Errors[0].Extensions.Code)
+
+	result = postGraphQL(t, r, `mutation { updateTask(id: "`+primitive.NewObjectID().Hex()+`", input: {name: ""}) { id } }`, nil)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "NOT_FOUND", result.Errors[0].Extensions.Code)
+}
+
+func Test_GraphQLSubscription(t *testing.T) {
+	mock := &GRPCMockDB{}
+	server := httptest.NewServer(graphqlEngine(mock))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/graphql",
+		strings.NewReader(`{"query": "subscription { taskEvents(filter: {types: [\"task.created\"]}) { type task { name } } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	// The subscription is registered before the response headers are sent.
+	r := graphqlEngine(mock)
+	postGraphQL(t, r, `mutation { createTask(input: {name: "streamed"}) { id } }`, nil)
+
+	scanner := bufio.NewScanner(res.Body)
+	var event, data string
+	for scanner.Scan() && data == "" {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event:") {
+			event = line[len("event:"):]
+		}
+		if strings.HasPrefix(line, "data:") {
+			data = line[len("data:"):]
+		}
+	}
+
+	assert.Equal(t, "next", event)
+	assert.JSONEq(t, `{"data": {"taskEvents": {"type": "task.created", "task": {"name": "streamed"}}}}`, data)
+}
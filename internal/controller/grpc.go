@@ -0,0 +1,464 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
+	"github.com/tiffany831101/bs_pretest.git/pkg/taskspb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	grpcDefaultPageSize = 100
+	grpcMaxPageSize     = 1000
+)
+
+// TaskGRPCServer implements the gRPC TaskService. It goes through the same
+// validation and write paths as the REST handlers, so changes made through
+// either API are audited and published alike.
+type TaskGRPCServer struct {
+	taskspb.UnimplementedTaskServiceServer
+
+	tc *TaskController
+}
+
+// NewGRPCServer returns a gRPC server with the TaskService, the standard
+// health service and server reflection registered. The TaskService shares the
+// controller of the REST routes, and with it the scheduler of recurring
+// tasks, so NewTasksController must be called first.
+func NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+
+	taskspb.RegisterTaskServiceServer(server, &TaskGRPCServer{tc: tC})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(taskspb.TaskService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server
+}
+
+func (s *TaskGRPCServer) ListTasks(ctx context.Context, req *taskspb.ListTasksRequest) (*taskspb.ListTasksResponse, error) {
+
+	pageSize := int64(req.GetPageSize())
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	}
+	if pageSize == 0 {
+		pageSize = grpcDefaultPageSize
+	}
+	pageSize = min(pageSize, grpcMaxPageSize)
+
+	var offset int64
+	if token := req.GetPageToken(); token != "" {
+		var err error
+		offset, err = strconv.ParseInt(token, 10, 64)
+		if err != nil || offset < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	// One extra task tells whether there is a next page.
+	cursor, err := database.MongoDB.FindTasks(ctx, database.TaskFilter{}, offset, pageSize+1)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	tasks, err := cursor.All(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	res := &taskspb.ListTasksResponse{}
+	if int64(len(tasks)) > pageSize {
+		tasks = tasks[:pageSize]
+		res.NextPageToken = strconv.FormatInt(offset+pageSize, 10)
+	}
+	for _, task := range tasks {
+		res.Tasks = append(res.Tasks, taskMessage(task))
+	}
+
+	return res, nil
+}
+
+func (s *TaskGRPCServer) GetTask(ctx context.Context, req *taskspb.GetTaskRequest) (*taskspb.Task, error) {
+
+	if err := checkTaskID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	task, err := database.MongoDB.GetTaskByID(req.GetId())
+
+	if err != nil || task.ID.IsZero() {
+		return nil, status.Error(codes.NotFound, errTaskNotFound.Error())
+	}
+
+	return taskMessage(task), nil
+}
+
+func (s *TaskGRPCServer) CreateTask(ctx context.Context, req *taskspb.CreateTaskRequest) (*taskspb.Task, error) {
+
+	taskReq, err := grpcTaskRequest(req, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	taskID := primitive.NewObjectID().Hex()
+
+	err = s.tc.createTask(grpcOrigin(ctx), taskReq, taskID)
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return taskMessage(*taskFromRequest(taskReq, taskID)), nil
+}
+
+func (s *TaskGRPCServer) UpdateTask(ctx context.Context, req *taskspb.UpdateTaskRequest) (*taskspb.UpdateTaskResponse, error) {
+
+	if err := checkTaskID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	taskReq, err := grpcTaskRequest(req, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, created, err := s.tc.saveTask(grpcOrigin(ctx), taskReq, req.GetId())
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &taskspb.UpdateTaskResponse{
+		Task:    taskMessage(saved),
+		Created: created,
+	}, nil
+}
+
+func (s *TaskGRPCServer) PatchTask(ctx context.Context, req *taskspb.PatchTaskRequest) (*taskspb.Task, error) {
+
+	if err := checkTaskID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	var patch TaskPatch
+	patch.Name = req.Name
+	if req.Status != nil {
+		taskStatus := TaskStatus(req.GetStatus())
+		patch.Status = &taskStatus
+	}
+	patch.ParentID = req.ParentId
+	if req.BlockedBy != nil {
+		blockedBy := req.BlockedBy.GetIds()
+		patch.BlockedBy = &blockedBy
+	}
+	if req.Due != nil {
+		due := req.Due.AsTime()
+		patch.Due = &due
+	}
+	patch.Recurrence = req.Recurrence
+	patch.ProjectID = req.ProjectId
+	if req.LabelIds != nil {
+		labelIDs := req.LabelIds.GetIds()
+		patch.LabelIDs = &labelIDs
+	}
+
+	updated, err := s.tc.applyPatch(grpcOrigin(ctx), req.GetId(), patch)
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return taskMessage(updated), nil
+}
+
+func (s *TaskGRPCServer) DeleteTask(ctx context.Context, req *taskspb.DeleteTaskRequest) (*taskspb.DeleteTaskResponse, error) {
+
+	if err := checkTaskID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	err := s.tc.removeTask(grpcOrigin(ctx), req.GetId(), OrphanSubtasks)
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &taskspb.DeleteTaskResponse{}, nil
+}
+
+func (s *TaskGRPCServer) GetTaskHistory(ctx context.Context, req *taskspb.GetTaskHistoryRequest) (*taskspb.GetTaskHistoryResponse, error) {
+
+	if err := checkTaskID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	history, err := database.MongoDB.GetTaskHistory(req.GetId())
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if len(history) == 0 {
+		return nil, status.Error(codes.NotFound, errTaskNotFound.Error())
+	}
+
+	res := &taskspb.GetTaskHistoryResponse{}
+	for _, version := range history {
+		res.Versions = append(res.Versions, &taskspb.TaskVersion{
+			TaskId:    version.TaskID,
+			Version:   int32(version.Version),
+			Name:      version.Task.Name,
+			Status:    taskspb.TaskStatus(version.Task.Status),
+			CreatedAt: timestamppb.New(version.CreatedAt),
+			Task:      taskMessage(version.Task),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *TaskGRPCServer) RevertTask(ctx context.Context, req *taskspb.RevertTaskRequest) (*taskspb.Task, error) {
+
+	if err := checkTaskID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	if req.GetVersion() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "version must be a positive integer")
+	}
+
+	reverted, err := s.tc.revertToVersion(grpcOrigin(ctx), req.GetId(), int(req.GetVersion()))
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return taskMessage(*reverted), nil
+}
+
+func (s *TaskGRPCServer) AddAssignee(ctx context.Context, req *taskspb.TaskUserRequest) (*taskspb.Task, error) {
+	return s.updateTaskUsers(ctx, req, assignees, true)
+}
+
+func (s *TaskGRPCServer) RemoveAssignee(ctx context.Context, req *taskspb.TaskUserRequest) (*taskspb.Task, error) {
+	return s.updateTaskUsers(ctx, req, assignees, false)
+}
+
+func (s *TaskGRPCServer) AddWatcher(ctx context.Context, req *taskspb.TaskUserRequest) (*taskspb.Task, error) {
+	return s.updateTaskUsers(ctx, req, watchers, true)
+}
+
+func (s *TaskGRPCServer) RemoveWatcher(ctx context.Context, req *taskspb.TaskUserRequest) (*taskspb.Task, error) {
+	return s.updateTaskUsers(ctx, req, watchers, false)
+}
+
+func (s *TaskGRPCServer) updateTaskUsers(ctx context.Context, req *taskspb.TaskUserRequest, users taskUsers, add bool) (*taskspb.Task, error) {
+
+	if err := checkTaskID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	task, err := s.tc.updateTaskUsers(grpcOrigin(ctx), req.GetId(), req.GetUserId(), users, add)
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return taskMessage(task), nil
+}
+
+// WatchTasks streams task changes like GET /tasks/events, resuming after
+// req.Since from the bus history.
+func (s *TaskGRPCServer) WatchTasks(req *taskspb.WatchTasksRequest, stream taskspb.TaskService_WatchTasksServer) error {
+
+	missed, complete, live, unsubscribe := events.Default.SubscribeSince(req.GetSince(), sseBuffer)
+	defer unsubscribe()
+
+	if req.GetSince() > 0 && !complete {
+		err := stream.Send(&taskspb.TaskEvent{Type: resetEvent, Timestamp: timestamppb.Now()})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, e := range missed {
+		if err := stream.Send(eventMessage(e)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e, ok := <-live:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(eventMessage(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcOrigin reads the caller and request ID from the x-user-id and
+// x-request-id metadata, the gRPC counterparts of the headers read by
+// middleware.RequestContext, and echoes the request ID back.
+func grpcOrigin(ctx context.Context) changeOrigin {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	first := func(key string) string {
+		if values := md.Get(strings.ToLower(key)); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	origin := changeOrigin{
+		actor:     first(middleware.ActorHeader),
+		requestID: first(middleware.RequestIDHeader),
+	}
+	if origin.actor == "" {
+		origin.actor = middleware.AnonymousActor
+	}
+	if origin.requestID == "" {
+		origin.requestID = primitive.NewObjectID().Hex()
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(middleware.RequestIDHeader), origin.requestID))
+
+	return origin
+}
+
+// taskFields is implemented by CreateTaskRequest and UpdateTaskRequest.
+type taskFields interface {
+	GetName() string
+	GetDue() *timestamppb.Timestamp
+	GetRecurrence() string
+	GetProjectId() string
+	GetLabelIds() []string
+}
+
+// grpcTaskRequest builds a TaskRequest and validates it with the rules of the
+// REST API.
+func grpcTaskRequest(req taskFields, taskStatus *taskspb.TaskStatus) (TaskRequest, error) {
+	taskReq := TaskRequest{
+		Name:       req.GetName(),
+		Recurrence: req.GetRecurrence(),
+		ProjectID:  req.GetProjectId(),
+		LabelIDs:   req.GetLabelIds(),
+	}
+	if req.GetDue() != nil {
+		due := req.GetDue().AsTime()
+		taskReq.Due = &due
+	}
+	if taskStatus != nil {
+		s := TaskStatus(*taskStatus)
+		taskReq.Status = &s
+	}
+
+	if err := binding.Validator.ValidateStruct(&taskReq); err != nil {
+		return taskReq, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return taskReq, nil
+}
+
+func checkTaskID(taskID string) error {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return status.Error(codes.InvalidArgument, errInvalidTaskID.Error())
+	}
+	return nil
+}
+
+func grpcError(err error) error {
+	return status.Error(errorCode(err), err.Error())
+}
+
+// errorCode classifies an error of the shared write paths, for the gRPC and
+// GraphQL APIs.
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, errTaskNotFound), errors.Is(err, errVersionNotFound):
+		return codes.NotFound
+	case errors.Is(err, errInvalidTaskID), errors.Is(err, errEmptyName), errors.Is(err, errParentNotFound), errors.Is(err, errParentCycle),
+		errors.Is(err, errInvalidBlocker), errors.Is(err, errDependencyCycle), errors.Is(err, recurrence.ErrInvalidRule),
+		errors.Is(err, errInvalidProject), errors.Is(err, errInvalidLabels):
+		return codes.InvalidArgument
+	case errors.Is(err, errHasSubtasks), errors.Is(err, errBlocked), errors.Is(err, errStatusChange):
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
+
+func taskMessage(task database.Task) *taskspb.Task {
+	msg := &taskspb.Task{
+		Id:          task.ID.Hex(),
+		Name:        task.Name,
+		Status:      taskspb.TaskStatus(task.Status),
+		ParentId:    task.ParentID,
+		BlockedBy:   task.BlockedBy,
+		Recurrence:  task.Recurrence,
+		ProjectId:   task.ProjectID,
+		LabelIds:    task.LabelIDs,
+		AssigneeIds: task.AssigneeIDs,
+		WatcherIds:  task.WatcherIDs,
+	}
+	if task.Due != nil {
+		msg.Due = timestamppb.New(*task.Due)
+	}
+	return msg
+}
+
+func eventMessage(e events.Event) *taskspb.TaskEvent {
+	msg := &taskspb.TaskEvent{
+		Seq:       e.Seq,
+		Type:      string(e.Type),
+		TaskId:    e.TaskID,
+		Actor:     e.Actor,
+		Timestamp: timestamppb.New(e.Timestamp),
+	}
+	if e.Task != nil {
+		msg.Task = &taskspb.Task{
+			Id:          e.Task.ID,
+			Name:        e.Task.Name,
+			Status:      taskspb.TaskStatus(e.Task.Status),
+			ParentId:    e.Task.ParentID,
+			BlockedBy:   e.Task.BlockedBy,
+			Recurrence:  e.Task.Recurrence,
+			ProjectId:   e.Task.ProjectID,
+			LabelIds:    e.Task.LabelIDs,
+			AssigneeIds: e.Task.AssigneeIDs,
+			WatcherIds:  e.Task.WatcherIDs,
+		}
+		if e.Task.Due != nil {
+			msg.Task.Due = timestamppb.New(*e.Task.Due)
+		}
+	}
+	return msg
+}
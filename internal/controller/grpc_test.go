@@ -0,0 +1,341 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
+	"github.com/tiffany831101/bs_pretest.git/pkg/taskspb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type GRPCMockDB struct {
+	MockDB
+	tasks []database.Task
+	audit []database.AuditEntry
+}
+
+func (db *GRPCMockDB) InsertSingleTask(task database.Task) error {
+	db.tasks = append(db.tasks, task)
+	return nil
+}
+
+func (db *GRPCMockDB) GetTaskByID(taskID string) (database.Task, error) {
+	for _, t := range db.tasks {
+		if t.ID.Hex() == taskID {
+			return t, nil
+		}
+	}
+	return database.Task{}, nil
+}
+
+func (db *GRPCMockDB) GetSubtasks(parentID string) ([]database.Task, error) {
+	subtasks := []database.Task{}
+	for _, t := range db.tasks {
+		if t.ParentID == parentID {
+			subtasks = append(subtasks, t)
+		}
+	}
+	return subtasks, nil
+}
+
+func (db *GRPCMockDB) GetDueTasks(before time.Time) ([]database.Task, error) {
+	due := []database.Task{}
+	for _, t := range db.tasks {
+		if t.Status != int(Completed) && t.Due != nil && !t.Due.After(before) {
+			due = append(due, t)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].Due.Before(*due[j].Due) })
+	return due, nil
+}
+
+func (db *GRPCMockDB) IterateTasks(ctx context.Context) (*database.TaskCursor, error) {
+	return database.NewTaskCursor(db.tasks)
+}
+
+func (db *GRPCMockDB) FindTasks(ctx context.Context, filter database.TaskFilter, offset, limit int64) (*database.TaskCursor, error) {
+	tasks := []database.Task{}
+	for _, t := range db.tasks {
+		if filter.Matches(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	if filter.DueBefore != nil {
+		sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Due.Before(*tasks[j].Due) })
+	}
+	tasks = tasks[min(offset, int64(len(tasks))):]
+	if limit > 0 {
+		tasks = tasks[:min(limit, int64(len(tasks)))]
+	}
+	return database.NewTaskCursor(tasks)
+}
+
+func (db *GRPCMockDB) UpdateTaskID(taskID string, task database.Task) error {
+	for i, t := range db.tasks {
+		if t.ID.Hex() == taskID {
+			db.tasks[i] = task
+		}
+	}
+	return nil
+}
+
+func (db *GRPCMockDB) DeleteTaskByID(taskID string) (int64, error) {
+	for i, t := range db.tasks {
+		if t.ID.Hex() == taskID {
+			db.tasks = append(db.tasks[:i], db.tasks[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (db *GRPCMockDB) InsertAuditEntry(entry database.AuditEntry) error {
+	db.audit = append(db.audit, entry)
+	return nil
+}
+
+// dialGRPC serves NewGRPCServer over an in-memory listener backed by mock.
+func dialGRPC(t *testing.T, mock *GRPCMockDB) *grpc.ClientConn {
+	database.MongoDB = mock
+	NewTasksController()
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func Test_GRPCTaskLifecycle(t *testing.T) {
+	mock := &GRPCMockDB{}
+	client := taskspb.NewTaskServiceClient(dialGRPC(t, mock))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "alice")
+
+	created, err := client.CreateTask(ctx, &taskspb.CreateTaskRequest{
+		Name:   "write report",
+		Status: taskspb.TaskStatus_TASK_STATUS_INCOMPLETE.Enum(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "write report", created.Name)
+	assert.Len(t, mock.tasks, 1)
+	assert.Equal(t, "alice", mock.audit[0].Actor)
+
+	got, err := client.GetTask(ctx, &taskspb.GetTaskRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(created, got))
+
+	updated, err := client.UpdateTask(ctx, &taskspb.UpdateTaskRequest{
+		Id:     created.Id,
+		Name:   "write the report",
+		Status: taskspb.TaskStatus_TASK_STATUS_INCOMPLETE.Enum(),
+	})
+	require.NoError(t, err)
+	assert.False(t, updated.Created)
+	assert.Equal(t, "write the report", mock.tasks[0].Name)
+	assert.True(t, proto.Equal(taskMessage(mock.tasks[0]), updated.Task))
+
+	patched, err := client.PatchTask(ctx, &taskspb.PatchTaskRequest{
+		Id:     created.Id,
+		Status: taskspb.TaskStatus_TASK_STATUS_COMPLETED.Enum(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "write the report", patched.Name)
+	assert.Equal(t, taskspb.TaskStatus_TASK_STATUS_COMPLETED, patched.Status)
+
+	_, err = client.DeleteTask(ctx, &taskspb.DeleteTaskRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.Empty(t, mock.tasks)
+
+	_, err = client.DeleteTask(ctx, &taskspb.DeleteTaskRequest{Id: created.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_GRPCTaskFields(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "design", "plan")
+	client := taskspb.NewTaskServiceClient(dialGRPC(t, mock))
+	ctx := context.Background()
+
+	due := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	created, err := client.CreateTask(ctx, &taskspb.CreateTaskRequest{
+		Name:       "build",
+		Status:     taskspb.TaskStatus_TASK_STATUS_INCOMPLETE.Enum(),
+		Due:        timestamppb.New(due),
+		Recurrence: "FREQ=WEEKLY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, due, created.Due.AsTime())
+	assert.Equal(t, "FREQ=WEEKLY", created.Recurrence)
+
+	patched, err := client.PatchTask(ctx, &taskspb.PatchTaskRequest{
+		Id:         created.Id,
+		ParentId:   proto.String(ids[1]),
+		BlockedBy:  &taskspb.IDList{Ids: []string{ids[0]}},
+		Recurrence: proto.String(""),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ids[1], patched.ParentId)
+	assert.Equal(t, []string{ids[0]}, patched.BlockedBy)
+	assert.Empty(t, patched.Recurrence)
+
+	patched, err = client.PatchTask(ctx, &taskspb.PatchTaskRequest{Id: created.Id, BlockedBy: &taskspb.IDList{}})
+	require.NoError(t, err)
+	assert.Empty(t, patched.BlockedBy)
+	assert.Equal(t, ids[1], patched.ParentId)
+
+	_, err = client.AddAssignee(ctx, &taskspb.TaskUserRequest{Id: created.Id, UserId: "alice"})
+	require.NoError(t, err)
+	_, err = client.AddWatcher(ctx, &taskspb.TaskUserRequest{Id: created.Id, UserId: "bob"})
+	require.NoError(t, err)
+
+	got, err := client.GetTask(ctx, &taskspb.GetTaskRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, got.AssigneeIds)
+	assert.Equal(t, []string{"bob"}, got.WatcherIds)
+
+	removed, err := client.RemoveAssignee(ctx, &taskspb.TaskUserRequest{Id: created.Id, UserId: "alice"})
+	require.NoError(t, err)
+	assert.Empty(t, removed.AssigneeIds)
+
+	_, err = client.AddWatcher(ctx, &taskspb.TaskUserRequest{Id: created.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_GRPCCompleteRecurringTask(t *testing.T) {
+	due := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	mock := &GRPCMockDB{}
+	task := database.Task{ID: primitive.NewObjectID(), Name: "standup", Recurrence: "FREQ=DAILY", Due: &due}
+	mock.tasks = append(mock.tasks, task)
+
+	client := taskspb.NewTaskServiceClient(dialGRPC(t, mock))
+
+	spawned := make(chan database.Task, 1)
+	tC.scheduler = recurrence.NewScheduler(fixedClock(due.Add(-time.Hour)), func(next database.Task) error {
+		spawned <- next
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tC.scheduler.Start(ctx)
+
+	_, err := client.PatchTask(ctx, &taskspb.PatchTaskRequest{
+		Id:     task.ID.Hex(),
+		Status: taskspb.TaskStatus_TASK_STATUS_COMPLETED.Enum(),
+	})
+	require.NoError(t, err)
+
+	select {
+	case next := <-spawned:
+		assert.Equal(t, due.AddDate(0, 0, 1), *next.Due)
+	case <-time.After(time.Second):
+		t.Fatal("no occurrence was scheduled")
+	}
+}
+
+func Test_GRPCErrorCodes(t *testing.T) {
+	client := taskspb.NewTaskServiceClient(dialGRPC(t, &GRPCMockDB{}))
+	ctx := context.Background()
+
+	_, err := client.CreateTask(ctx, &taskspb.CreateTaskRequest{Name: "no status"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.CreateTask(ctx, &taskspb.CreateTaskRequest{Status: taskspb.TaskStatus_TASK_STATUS_COMPLETED.Enum()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetTask(ctx, &taskspb.GetTaskRequest{Id: "not-hex"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetTask(ctx, &taskspb.GetTaskRequest{Id: primitive.NewObjectID().Hex()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.PatchTask(ctx, &taskspb.PatchTaskRequest{Id: primitive.NewObjectID().Hex()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.ListTasks(ctx, &taskspb.ListTasksRequest{PageToken: "abc"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_GRPCListTasksPages(t *testing.T) {
+	mock := &GRPCMockDB{}
+	for _, name := range []string{"a", "b", "c"} {
+		mock.tasks = append(mock.tasks, database.Task{ID: primitive.NewObjectID(), Name: name})
+	}
+	client := taskspb.NewTaskServiceClient(dialGRPC(t, mock))
+
+	var names []string
+	req := &taskspb.ListTasksRequest{PageSize: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+
+		res, err := client.ListTasks(context.Background(), req)
+		require.NoError(t, err)
+		for _, task := range res.Tasks {
+			names = append(names, task.Name)
+		}
+		if res.NextPageToken == "" {
+			break
+		}
+		req.PageToken = res.NextPageToken
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+}
+
+func Test_GRPCHealth(t *testing.T) {
+	client := healthpb.NewHealthClient(dialGRPC(t, &GRPCMockDB{}))
+
+	res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "tasks.v1.TaskService"})
+
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+}
+
+func Test_GRPCWatchTasks(t *testing.T) {
+	client := taskspb.NewTaskServiceClient(dialGRPC(t, &GRPCMockDB{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := client.CreateTask(ctx, &taskspb.CreateTaskRequest{Name: "before", Status: taskspb.TaskStatus_TASK_STATUS_INCOMPLETE.Enum()})
+	require.NoError(t, err)
+
+	// Resuming from the current sequence number delivers the next task even
+	// if it is created before the server subscribes.
+	stream, err := client.WatchTasks(ctx, &taskspb.WatchTasksRequest{Since: events.Default.Seq()})
+	require.NoError(t, err)
+
+	created, err := client.CreateTask(ctx, &taskspb.CreateTaskRequest{Name: "after", Status: taskspb.TaskStatus_TASK_STATUS_INCOMPLETE.Enum()})
+	require.NoError(t, err)
+
+	e, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, string(events.TaskCreated), e.Type)
+	assert.Equal(t, created.Id, e.TaskId)
+	assert.Equal(t, "after", e.Task.Name)
+}
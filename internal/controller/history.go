@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errVersionNotFound = errors.New("version not found")
+
+// getTaskHistory retrieves every version of a task.
+// @Summary Retrieve the history of a task
+// @Description Get every recorded version of a task, oldest first. The last entry is the current state.
+// @ID getTaskHistory
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {array} database.TaskVersion "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/history [get]
+// @Tags tasks
+func (tc *TaskController) getTaskHistory(c *gin.Context) {
+
+	taskID := c.Param("id")
+
+	_, err := primitive.ObjectIDFromHex(taskID)
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	history, err := database.MongoDB.GetTaskHistory(taskID)
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(history) == 0 {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// revertTask restores a previous version of a task.
+// @Summary Revert a task
+// @Description Restore a task as it was in a previous version. The revert is itself recorded as a new version.
+// @ID revertTask
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task to revert" Pattern("^[0-9a-fA-F]{24}$")
+// @Param version query int true "Version to restore"
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/revert [post]
+// @Tags tasks
+func (tc *TaskController) revertTask(c *gin.Context) {
+
+	taskID := c.Param("id")
+
+	_, err := primitive.ObjectIDFromHex(taskID)
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	version, err := strconv.Atoi(c.Query("version"))
+
+	if err != nil || version < 1 {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, "Invalid version, should be a positive integer")
+		return
+	}
+
+	_, err = tc.revertToVersion(originOf(c), taskID, version)
+
+	if errors.Is(err, errTaskNotFound) {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	if errors.Is(err, errVersionNotFound) {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Version Not Found")
+		return
+	}
+
+	// The parent, blockers, project or labels of the version may have been
+	// deleted since.
+	if errors.Is(err, errParentNotFound) || errors.Is(err, errParentCycle) || errors.Is(err, errInvalidBlocker) ||
+		errors.Is(err, errDependencyCycle) || errors.Is(err, recurrence.ErrInvalidRule) ||
+		errors.Is(err, errInvalidProject) || errors.Is(err, errInvalidLabels) {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, errBlocked) || errors.Is(err, errStatusChange) {
+		middleware.AbortWithProblem(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, "OK")
+}
+
+// revertToVersion restores the task as it was in version, checking the
+// references it had then like a new update.
+func (tc *TaskController) revertToVersion(origin changeOrigin, taskID string, version int) (*database.Task, error) {
+
+	task, err := database.MongoDB.GetTaskByID(taskID)
+
+	if err != nil || task.ID.IsZero() {
+		return nil, errTaskNotFound
+	}
+
+	target, err := database.MongoDB.GetTaskVersion(taskID, version)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errVersionNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := task
+
+	if err := restoreSnapshot(taskID, &reverted, target.Task); err != nil {
+		return nil, err
+	}
+
+	if err := checkStatusChange(task, reverted); err != nil {
+		return nil, err
+	}
+
+	if err := checkBlockers(task, reverted); err != nil {
+		return nil, err
+	}
+
+	err = database.MongoDB.UpdateTaskID(taskID, reverted)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tc.taskChanged(origin, database.AuditUpdate, taskID, &task, &reverted)
+
+	return &reverted, nil
+}
+
+// restoreSnapshot sets the fields of task to those of snapshot, after checking
+// that the parent, blockers, recurrence, project and labels of the snapshot
+// are still valid.
+func restoreSnapshot(taskID string, task *database.Task, snapshot database.Task) error {
+
+	if snapshot.ParentID != "" {
+		if err := checkParent(taskID, snapshot.ParentID); err != nil {
+			return err
+		}
+	}
+	if err := checkDependencies(taskID, snapshot.BlockedBy); err != nil {
+		return err
+	}
+	if err := checkRecurrence(snapshot.Recurrence); err != nil {
+		return err
+	}
+	if err := checkProject(snapshot.ProjectID); err != nil {
+		return err
+	}
+	if err := checkLabels(snapshot.LabelIDs); err != nil {
+		return err
+	}
+
+	task.Name = snapshot.Name
+	task.Status = snapshot.Status
+	task.ParentID = snapshot.ParentID
+	task.BlockedBy = snapshot.BlockedBy
+	setDue(task, snapshot.Due)
+	task.Recurrence = snapshot.Recurrence
+	task.SeriesStart = snapshot.SeriesStart
+	task.ProjectID = snapshot.ProjectID
+	task.LabelIDs = snapshot.LabelIDs
+	task.AssigneeIDs = snapshot.AssigneeIDs
+	task.WatcherIDs = snapshot.WatcherIDs
+
+	return nil
+}
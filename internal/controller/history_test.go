@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type HistoryMockDB struct {
+	MockDB
+	task       database.Task
+	versions   []database.TaskVersion
+	versionErr error
+}
+
+func newHistoryMockDB(names ...string) *HistoryMockDB {
+	db := &HistoryMockDB{task: database.Task{ID: primitive.NewObjectID()}}
+	for _, name := range names {
+		db.UpdateTaskID(db.task.ID.Hex(), database.Task{Name: name})
+	}
+	return db
+}
+
+func (db *HistoryMockDB) GetTaskByID(taskID string) (database.Task, error) {
+	return db.task, nil
+}
+
+func (db *HistoryMockDB) UpdateTaskID(taskID string, task database.Task) error {
+	task.ID = db.task.ID
+	db.task = task
+	db.versions = append(db.versions, database.TaskVersion{
+		TaskID:  taskID,
+		Version: len(db.versions) + 1,
+		Task:    task,
+	})
+	return nil
+}
+
+func (db *HistoryMockDB) GetTaskHistory(taskID string) ([]database.TaskVersion, error) {
+	return db.versions, nil
+}
+
+func (db *HistoryMockDB) GetTaskVersion(taskID string, version int) (database.TaskVersion, error) {
+	if db.versionErr != nil {
+		return database.TaskVersion{}, db.versionErr
+	}
+	if version > len(db.versions) {
+		return database.TaskVersion{}, mongo.ErrNoDocuments
+	}
+	return db.versions[version-1], nil
+}
+
+func Test_GetTaskHistory(t *testing.T) {
+	mock := newHistoryMockDB("first", "second")
+	database.MongoDB = mock
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: mock.task.ID.Hex()})
+
+	tC := &TaskController{}
+	tC.getTaskHistory(c)
+
+	var history []database.TaskVersion
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Len(t, history, 2)
+	assert.Equal(t, "first", history[0].Task.Name)
+}
+
+func Test_GetTaskHistoryNotFound(t *testing.T) {
+	database.MongoDB = &MockDB{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: primitive.NewObjectID().Hex()})
+
+	tC := &TaskController{}
+	tC.getTaskHistory(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_RevertTask(t *testing.T) {
+	mock := newHistoryMockDB("first", "second")
+	database.MongoDB = mock
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: mock.task.ID.Hex()})
+	c.Request = httptest.NewRequest(http.MethodPost, "/?version=1", nil)
+
+	tC := &TaskController{}
+	tC.revertTask(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "first", mock.task.Name)
+	assert.Len(t, mock.versions, 3)
+}
+
+func Test_RevertTaskInvalidVersion(t *testing.T) {
+	mock := newHistoryMockDB("first")
+	database.MongoDB = mock
+
+	for query, code := range map[string]int{
+		"/?version=0":   http.StatusBadRequest,
+		"/?version=abc": http.StatusBadRequest,
+		"/?version=5":   http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = append(c.Params, gin.Param{Key: "id", Value: mock.task.ID.Hex()})
+		c.Request = httptest.NewRequest(http.MethodPost, query, nil)
+
+		tC := &TaskController{}
+		tC.revertTask(c)
+
+		assert.Equal(t, code, w.Code, query)
+	}
+}
+
+func Test_RevertTaskVersionError(t *testing.T) {
+	mock := newHistoryMockDB("first")
+	mock.versionErr = errors.New("connection reset")
+	database.MongoDB = mock
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: mock.task.ID.Hex()})
+	c.Request = httptest.NewRequest(http.MethodPost, "/?version=1", nil)
+
+	tC := &TaskController{}
+	tC.revertTask(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func Test_RevertTaskRestoresWholeTask(t *testing.T) {
+	due := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	mock := newHistoryMockDB()
+	mock.UpdateTaskID(mock.task.ID.Hex(), database.Task{
+		Name:        "standup",
+		Due:         &due,
+		Recurrence:  "FREQ=DAILY",
+		AssigneeIDs: []string{"alice"},
+		WatcherIDs:  []string{"bob"},
+	})
+	mock.UpdateTaskID(mock.task.ID.Hex(), database.Task{Name: "renamed", Status: int(Completed)})
+	database.MongoDB = mock
+
+	reverted, err := (&TaskController{}).revertToVersion(changeOrigin{}, mock.task.ID.Hex(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, database.Task{
+		ID:          mock.task.ID,
+		Name:        "standup",
+		Due:         &due,
+		Recurrence:  "FREQ=DAILY",
+		AssigneeIDs: []string{"alice"},
+		WatcherIDs:  []string{"bob"},
+	}, *reverted)
+	assert.Equal(t, *reverted, mock.task)
+}
@@ -0,0 +1,268 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errInvalidLabels = errors.New("label_ids must list existing labels, each once")
+	errLabelInUse    = errors.New("label is used by tasks")
+)
+
+type LabelController struct{}
+
+type LabelRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// Color is a hex color such as #d73a4a, used by clients to draw the label.
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+var lC *LabelController
+
+func SetUpLabelRoutes(r *gin.Engine) {
+
+	labelGroup := r.Group("/api/v1/labels")
+	{
+		labelGroup.GET("", lC.getLabels)
+		labelGroup.POST("", middleware.Idempotency(), lC.postLabel)
+		labelGroup.GET("/:id", lC.getLabel)
+		labelGroup.PUT("/:id", lC.putLabel)
+		labelGroup.DELETE("/:id", lC.deleteLabel)
+	}
+}
+
+func NewLabelController() {
+	lC = &LabelController{}
+}
+
+// getLabels retrieves all labels.
+// @Summary Retrieve all labels
+// @Description Get every label, ordered by name.
+// @ID getLabels
+// @Accept json
+// @Produce json
+// @Success 200 {array} database.Label "OK"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /labels [get]
+// @Tags labels
+func (lc *LabelController) getLabels(c *gin.Context) {
+
+	labels, err := database.Labels.GetLabels()
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+// postLabel creates a label.
+// @Summary Create a label
+// @Description Create a label that tasks can be tagged with through their label_ids.
+// @ID postLabel
+// @Accept json
+// @Produce json
+// @Param body body LabelRequest true "Label to create"
+// @Param Idempotency-Key header string false "Replay the stored response when a request is retried with the same key"
+// @Success 201 {object} database.Label "Created"
+// @Header 201 {string} Location "URL of the created label"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /labels [post]
+// @Tags labels
+func (lc *LabelController) postLabel(c *gin.Context) {
+
+	var req LabelRequest
+
+	err := c.BindJSON(&req)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	label := database.Label{
+		ID:        primitive.NewObjectID(),
+		Name:      req.Name,
+		Color:     req.Color,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = database.Labels.InsertLabel(label)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Location", "/api/v1/labels/"+label.ID.Hex())
+	c.JSON(http.StatusCreated, label)
+}
+
+// getLabel retrieves a label by ID.
+// @Summary Retrieve a label by ID
+// @Description Get the details of a label.
+// @ID getLabel
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the label" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {object} database.Label "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Router /labels/{id} [get]
+// @Tags labels
+func (lc *LabelController) getLabel(c *gin.Context) {
+
+	label, ok := pathLabel(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+// putLabel updates a label.
+// @Summary Update a label
+// @Description Replace the name and color of a label.
+// @ID putLabel
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the label" Pattern("^[0-9a-fA-F]{24}$")
+// @Param body body LabelRequest true "New details of the label"
+// @Success 200 {object} database.Label "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /labels/{id} [put]
+// @Tags labels
+func (lc *LabelController) putLabel(c *gin.Context) {
+
+	var req LabelRequest
+
+	err := c.BindJSON(&req)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	label, ok := pathLabel(c)
+	if !ok {
+		return
+	}
+
+	label.Name = req.Name
+	label.Color = req.Color
+	label.UpdatedAt = time.Now().UTC()
+
+	err = database.Labels.UpdateLabel(label)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+// deleteLabel deletes a label.
+// @Summary Delete a label
+// @Description Delete a label. A label that tasks still have is kept (reject), or removed from those tasks first (detach).
+// @ID deleteLabel
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the label" Pattern("^[0-9a-fA-F]{24}$")
+// @Param tasks query string false "What to do with the tasks that have the label" Enums(reject, detach) default(reject)
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /labels/{id} [delete]
+// @Tags labels
+func (lc *LabelController) deleteLabel(c *gin.Context) {
+
+	policy := ReferencePolicy(c.DefaultQuery("tasks", string(RejectReferences)))
+
+	if !policy.valid() {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, "tasks must be reject or detach")
+		return
+	}
+
+	label, ok := pathLabel(c)
+	if !ok {
+		return
+	}
+	labelID := label.ID.Hex()
+
+	tasks, err := findTasks(c.Request.Context(), database.TaskFilter{LabelIDs: []string{labelID}})
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(tasks) > 0 && policy == RejectReferences {
+		middleware.AbortWithProblem(c, http.StatusConflict, errLabelInUse.Error())
+		return
+	}
+
+	err = tC.detachTasks(originOf(c), tasks, func(task *database.Task) {
+		task.LabelIDs = slices.DeleteFunc(slices.Clone(task.LabelIDs), func(id string) bool {
+			return id == labelID
+		})
+	})
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	deleteCount, err := database.Labels.DeleteLabel(labelID)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if deleteCount == 0 {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	c.JSON(http.StatusOK, "OK")
+}
+
+// pathLabel returns the label in the path, answering 404 and reporting false
+// when there is no such label.
+func pathLabel(c *gin.Context) (database.Label, bool) {
+
+	label, err := database.Labels.GetLabel(c.Param("id"))
+
+	if err != nil || label.ID.IsZero() {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return database.Label{}, false
+	}
+
+	return label, true
+}
+
+// checkLabels reports whether labelIDs are existing labels, none of them
+// listed twice.
+func checkLabels(labelIDs []string) error {
+	seen := map[string]bool{}
+	for _, id := range labelIDs {
+		if seen[id] {
+			return errInvalidLabels
+		}
+		seen[id] = true
+
+		label, err := database.Labels.GetLabel(id)
+		if err != nil || label.ID.IsZero() {
+			return errInvalidLabels
+		}
+	}
+	return nil
+}
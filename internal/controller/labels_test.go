@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+)
+
+func Test_DeleteLabelRejectsReferences(t *testing.T) {
+	tasks, projects := &GRPCMockDB{}, &ProjectMockDB{}
+	ids := newTasks(tasks, "design")
+
+	bugID := createResource(t, tasks, projects, "/api/v1/labels", `{"name": "bug"}`)
+
+	w := serveProjects(tasks, projects, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"label_ids": ["`+bugID+`"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = serveProjects(tasks, projects, http.MethodDelete, "/api/v1/labels/"+bugID, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = serveProjects(tasks, projects, http.MethodDelete, "/api/v1/labels/"+bugID+"?tasks=reject", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = serveProjects(tasks, projects, http.MethodDelete, "/api/v1/labels/"+bugID+"?tasks=cascade", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Len(t, projects.labels, 1)
+	assert.Equal(t, []string{bugID}, tasks.tasks[0].LabelIDs)
+
+	tasks.tasks[0].LabelIDs = nil
+
+	w = serveProjects(tasks, projects, http.MethodDelete, "/api/v1/labels/"+bugID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, projects.labels)
+
+	w = serveProjects(tasks, projects, http.MethodDelete, "/api/v1/labels/"+bugID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_DeleteLabelDetachesReferences(t *testing.T) {
+	tasks, projects := &GRPCMockDB{}, &ProjectMockDB{}
+	ids := newTasks(tasks, "design", "build", "ship")
+
+	bugID := createResource(t, tasks, projects, "/api/v1/labels", `{"name": "bug"}`)
+	urgentID := createResource(t, tasks, projects, "/api/v1/labels", `{"name": "urgent"}`)
+
+	for id, body := range map[string]string{
+		ids[0]: `{"label_ids": ["` + bugID + `", "` + urgentID + `"]}`,
+		ids[1]: `{"label_ids": ["` + bugID + `"]}`,
+		ids[2]: `{"label_ids": ["` + urgentID + `"]}`,
+	} {
+		w := serveProjects(tasks, projects, http.MethodPatch, "/api/v1/tasks/"+id, body)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	tasks.audit = nil
+
+	w := serveProjects(tasks, projects, http.MethodDelete, "/api/v1/labels/"+bugID+"?tasks=detach", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	require.Len(t, projects.labels, 1)
+	assert.Equal(t, urgentID, projects.labels[0].ID.Hex())
+	assert.Equal(t, []string{urgentID}, tasks.tasks[0].LabelIDs)
+	assert.Empty(t, tasks.tasks[1].LabelIDs)
+	assert.Equal(t, []string{urgentID}, tasks.tasks[2].LabelIDs)
+
+	// Only the tasks that had the label are changed, and each change is
+	// audited.
+	require.Len(t, tasks.audit, 2)
+	for _, entry := range tasks.audit {
+		assert.Equal(t, database.AuditUpdate, entry.Action)
+	}
+}
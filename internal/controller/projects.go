@@ -0,0 +1,340 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReferencePolicy says what happens to the tasks that refer to a deleted
+// project or label.
+type ReferencePolicy string
+
+const (
+	// RejectReferences refuses to delete a project or label that tasks use.
+	RejectReferences ReferencePolicy = "reject"
+	// DetachReferences removes the project or label from its tasks.
+	DetachReferences ReferencePolicy = "detach"
+)
+
+func (p ReferencePolicy) valid() bool {
+	return p == RejectReferences || p == DetachReferences
+}
+
+var (
+	errInvalidProject = errors.New("project_id must be an existing project")
+	errProjectInUse   = errors.New("project has tasks")
+)
+
+type ProjectController struct{}
+
+type ProjectRequest struct {
+	Name        string `json:"name" binding:"required,max=200"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+var pC *ProjectController
+
+func SetUpProjectRoutes(r *gin.Engine) {
+
+	projectGroup := r.Group("/api/v1/projects")
+	{
+		projectGroup.GET("", pC.getProjects)
+		projectGroup.POST("", middleware.Idempotency(), pC.postProject)
+		projectGroup.GET("/:id", pC.getProject)
+		projectGroup.PUT("/:id", pC.putProject)
+		projectGroup.DELETE("/:id", pC.deleteProject)
+		projectGroup.GET("/:id/tasks", pC.getProjectTasks)
+	}
+}
+
+func NewProjectController() {
+	pC = &ProjectController{}
+}
+
+// getProjects retrieves all projects.
+// @Summary Retrieve all projects
+// @Description Get every project, ordered by name.
+// @ID getProjects
+// @Accept json
+// @Produce json
+// @Success 200 {array} database.Project "OK"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /projects [get]
+// @Tags projects
+func (pc *ProjectController) getProjects(c *gin.Context) {
+
+	projects, err := database.Projects.GetProjects()
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// postProject creates a project.
+// @Summary Create a project
+// @Description Create a project that tasks can be added to with their project_id.
+// @ID postProject
+// @Accept json
+// @Produce json
+// @Param body body ProjectRequest true "Project to create"
+// @Param Idempotency-Key header string false "Replay the stored response when a request is retried with the same key"
+// @Success 201 {object} database.Project "Created"
+// @Header 201 {string} Location "URL of the created project"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /projects [post]
+// @Tags projects
+func (pc *ProjectController) postProject(c *gin.Context) {
+
+	var req ProjectRequest
+
+	err := c.BindJSON(&req)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	project := database.Project{
+		ID:          primitive.NewObjectID(),
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err = database.Projects.InsertProject(project)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Location", "/api/v1/projects/"+project.ID.Hex())
+	c.JSON(http.StatusCreated, project)
+}
+
+// getProject retrieves a project by ID.
+// @Summary Retrieve a project by ID
+// @Description Get the details of a project.
+// @ID getProject
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the project" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {object} database.Project "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Router /projects/{id} [get]
+// @Tags projects
+func (pc *ProjectController) getProject(c *gin.Context) {
+
+	project, ok := pathProject(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// putProject updates a project.
+// @Summary Update a project
+// @Description Replace the name and description of a project.
+// @ID putProject
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the project" Pattern("^[0-9a-fA-F]{24}$")
+// @Param body body ProjectRequest true "New details of the project"
+// @Success 200 {object} database.Project "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /projects/{id} [put]
+// @Tags projects
+func (pc *ProjectController) putProject(c *gin.Context) {
+
+	var req ProjectRequest
+
+	err := c.BindJSON(&req)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	project, ok := pathProject(c)
+	if !ok {
+		return
+	}
+
+	project.Name = req.Name
+	project.Description = req.Description
+	project.UpdatedAt = time.Now().UTC()
+
+	err = database.Projects.UpdateProject(project)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// deleteProject deletes a project.
+// @Summary Delete a project
+// @Description Delete a project. A project that still has tasks is kept (reject), or its tasks are taken out of it first (detach).
+// @ID deleteProject
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the project" Pattern("^[0-9a-fA-F]{24}$")
+// @Param tasks query string false "What to do with the tasks of the project" Enums(reject, detach) default(reject)
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /projects/{id} [delete]
+// @Tags projects
+func (pc *ProjectController) deleteProject(c *gin.Context) {
+
+	policy := ReferencePolicy(c.DefaultQuery("tasks", string(RejectReferences)))
+
+	if !policy.valid() {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, "tasks must be reject or detach")
+		return
+	}
+
+	project, ok := pathProject(c)
+	if !ok {
+		return
+	}
+	projectID := project.ID.Hex()
+
+	tasks, err := findTasks(c.Request.Context(), database.TaskFilter{ProjectID: projectID})
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(tasks) > 0 && policy == RejectReferences {
+		middleware.AbortWithProblem(c, http.StatusConflict, errProjectInUse.Error())
+		return
+	}
+
+	err = tC.detachTasks(originOf(c), tasks, func(task *database.Task) {
+		task.ProjectID = ""
+	})
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	deleteCount, err := database.Projects.DeleteProject(projectID)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if deleteCount == 0 {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	c.JSON(http.StatusOK, "OK")
+}
+
+// getProjectTasks retrieves the tasks of a project.
+// @Summary Retrieve the tasks of a project
+// @Description Get the tasks of a project, ordered by ID, optionally only those with every one of the given labels. The list is streamed from the database as it is read; send Accept: application/x-ndjson to receive one task per line instead of a JSON array.
+// @ID getProjectTasks
+// @Accept json
+// @Produce json,application/x-ndjson
+// @Param id path string true "ID of the project" Pattern("^[0-9a-fA-F]{24}$")
+// @Param labels query []string false "IDs of the labels the tasks must have, repeated or comma separated" collectionFormat(multi)
+// @Success 200 {array} TaskResponse "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /projects/{id}/tasks [get]
+// @Tags projects
+func (pc *ProjectController) getProjectTasks(c *gin.Context) {
+
+	project, ok := pathProject(c)
+	if !ok {
+		return
+	}
+
+	filter := database.TaskFilter{ProjectID: project.ID.Hex(), LabelIDs: queryList(c, "labels")}
+
+	cursor, err := database.MongoDB.FindTasks(c.Request.Context(), filter, 0, 0)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer cursor.Close(c)
+
+	streamTasks(c, cursor, c.NegotiateFormat(gin.MIMEJSON, mimeNDJSON) == mimeNDJSON)
+}
+
+// pathProject returns the project in the path, answering 404 and reporting
+// false when there is no such project.
+func pathProject(c *gin.Context) (database.Project, bool) {
+
+	project, err := database.Projects.GetProject(c.Param("id"))
+
+	if err != nil || project.ID.IsZero() {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return database.Project{}, false
+	}
+
+	return project, true
+}
+
+// checkProject reports whether projectID is an existing project; an empty ID
+// means the task is in no project.
+func checkProject(projectID string) error {
+	if projectID == "" {
+		return nil
+	}
+
+	project, err := database.Projects.GetProject(projectID)
+	if err != nil || project.ID.IsZero() {
+		return errInvalidProject
+	}
+	return nil
+}
+
+// findTasks returns every task filter selects, for the callers that need them
+// all at once.
+func findTasks(ctx context.Context, filter database.TaskFilter) ([]database.Task, error) {
+	cursor, err := database.MongoDB.FindTasks(ctx, filter, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return cursor.All(ctx)
+}
+
+// detachTasks removes a deleted project or label from tasks with detach, and
+// saves and reports every task like any other update.
+func (tc *TaskController) detachTasks(origin changeOrigin, tasks []database.Task, detach func(*database.Task)) error {
+
+	for _, before := range tasks {
+		taskID := before.ID.Hex()
+
+		updated := before
+		detach(&updated)
+
+		err := database.MongoDB.UpdateTaskID(taskID, updated)
+		if err != nil {
+			return err
+		}
+
+		tc.taskChanged(origin, database.AuditUpdate, taskID, &before, &updated)
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+)
+
+// ProjectMockDB keeps projects and labels in memory.
+type ProjectMockDB struct {
+	projects []database.Project
+	labels   []database.Label
+}
+
+func (db *ProjectMockDB) InsertProject(project database.Project) error {
+	db.projects = append(db.projects, project)
+	return nil
+}
+
+func (db *ProjectMockDB) GetProject(projectID string) (database.Project, error) {
+	for _, project := range db.projects {
+		if project.ID.Hex() == projectID {
+			return project, nil
+		}
+	}
+	return database.Project{}, nil
+}
+
+func (db *ProjectMockDB) GetProjects() ([]database.Project, error) {
+	return append([]database.Project{}, db.projects...), nil
+}
+
+func (db *ProjectMockDB) UpdateProject(project database.Project) error {
+	for i, p := range db.projects {
+		if p.ID == project.ID {
+			db.projects[i] = project
+		}
+	}
+	return nil
+}
+
+func (db *ProjectMockDB) DeleteProject(projectID string) (int64, error) {
+	for i, project := range db.projects {
+		if project.ID.Hex() == projectID {
+			db.projects = append(db.projects[:i], db.projects[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (db *ProjectMockDB) InsertLabel(label database.Label) error {
+	db.labels = append(db.labels, label)
+	return nil
+}
+
+func (db *ProjectMockDB) GetLabel(labelID string) (database.Label, error) {
+	for _, label := range db.labels {
+		if label.ID.Hex() == labelID {
+			return label, nil
+		}
+	}
+	return database.Label{}, nil
+}
+
+func (db *ProjectMockDB) GetLabels() ([]database.Label, error) {
+	return append([]database.Label{}, db.labels...), nil
+}
+
+func (db *ProjectMockDB) UpdateLabel(label database.Label) error {
+	for i, l := range db.labels {
+		if l.ID == label.ID {
+			db.labels[i] = label
+		}
+	}
+	return nil
+}
+
+func (db *ProjectMockDB) DeleteLabel(labelID string) (int64, error) {
+	for i, label := range db.labels {
+		if label.ID.Hex() == labelID {
+			db.labels = append(db.labels[:i], db.labels[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func serveProjects(tasks *GRPCMockDB, projects *ProjectMockDB, method, target, body string) *httptest.ResponseRecorder {
+	database.MongoDB = tasks
+	database.Projects = projects
+	database.Labels = projects
+	NewTasksController()
+	NewProjectController()
+	NewLabelController()
+
+	r := gin.New()
+	SetUpTasksRoutes(r)
+	SetUpProjectRoutes(r)
+	SetUpLabelRoutes(r)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// createResource posts body to target and returns the ID of the created
+// project or label.
+func createResource(t *testing.T, tasks *GRPCMockDB, projects *ProjectMockDB, target, body string) string {
+	w := serveProjects(tasks, projects, http.MethodPost, target, body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	return created.ID
+}
+
+func Test_ProjectCRUD(t *testing.T) {
+	tasks, projects := &GRPCMockDB{}, &ProjectMockDB{}
+
+	projectID := createResource(t, tasks, projects, "/api/v1/projects", `{"name": "Website", "description": "Relaunch"}`)
+
+	w := serveProjects(tasks, projects, http.MethodPost, "/api/v1/projects", `{"description": "no name"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveProjects(tasks, projects, http.MethodPut, "/api/v1/projects/"+projectID, `{"name": "Web"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serveProjects(tasks, projects, http.MethodGet, "/api/v1/projects/"+projectID, "")
+
+	var project database.Project
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &project))
+	assert.Equal(t, "Web", project.Name)
+	assert.Empty(t, project.Description)
+
+	w = serveProjects(tasks, projects, http.MethodGet, "/api/v1/projects/65a000000000000000000001", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveProjects(tasks, projects, http.MethodDelete, "/api/v1/projects/"+projectID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, projects.projects)
+}
+
+func Test_TaskProjectAndLabels(t *testing.T) {
+	tasks, projects := &GRPCMockDB{}, &ProjectMockDB{}
+	ids := newTasks(tasks, "design", "build", "ship")
+
+	projectID := createResource(t, tasks, projects, "/api/v1/projects", `{"name": "Website"}`)
+	bugID := createResource(t, tasks, projects, "/api/v1/labels", `{"name": "bug", "color": "#d73a4a"}`)
+	urgentID := createResource(t, tasks, projects, "/api/v1/labels", `{"name": "urgent"}`)
+
+	w := serveProjects(tasks, projects, http.MethodPost, "/api/v1/labels", `{"name": "bad", "color": "red"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveProjects(tasks, projects, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"project_id": "`+projectID+`", "label_ids": ["`+bugID+`", "`+urgentID+`"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = serveProjects(tasks, projects, http.MethodPatch, "/api/v1/tasks/"+ids[1], `{"project_id": "`+projectID+`", "label_ids": ["`+bugID+`"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for name, body := range map[string]string{
+		"missing project":  `{"project_id": "65a000000000000000000001"}`,
+		"missing label":    `{"label_ids": ["65a000000000000000000001"]}`,
+		"duplicated label": `{"label_ids": ["` + bugID + `", "` + bugID + `"]}`,
+	} {
+		w = serveProjects(tasks, projects, http.MethodPatch, "/api/v1/tasks/"+ids[2], body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	w = serveProjects(tasks, projects, http.MethodPost, "/api/v1/tasks/", `{"name": "test", "status": 0, "project_id": "65a000000000000000000001"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var listed []TaskResponse
+
+	w = serveProjects(tasks, projects, http.MethodGet, "/api/v1/projects/"+projectID+"/tasks", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Len(t, listed, 2)
+
+	w = serveProjects(tasks, projects, http.MethodGet, "/api/v1/projects/"+projectID+"/tasks?labels="+urgentID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, ids[0], listed[0].ID)
+
+	w = serveProjects(tasks, projects, http.MethodGet, "/api/v1/tasks/?labels="+bugID+","+urgentID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, ids[0], listed[0].ID)
+	assert.Equal(t, []string{bugID, urgentID}, listed[0].LabelIDs)
+
+	w = serveProjects(tasks, projects, http.MethodGet, "/api/v1/tasks/?labels="+bugID+"&limit=1&offset=1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, ids[1], listed[0].ID)
+}
+
+func Test_DeleteReferencedProject(t *testing.T) {
+	tasks, projects := &GRPCMockDB{}, &ProjectMockDB{}
+	ids := newTasks(tasks, "design")
+
+	projectID := createResource(t, tasks, projects, "/api/v1/projects", `{"name": "Website"}`)
+
+	w := serveProjects(tasks, projects, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"project_id": "`+projectID+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = serveProjects(tasks, projects, http.MethodDelete, "/api/v1/projects/"+projectID, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serveProjects(tasks, projects, http.MethodDelete, "/api/v1/projects/"+projectID+"?tasks=detach", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Empty(t, projects.projects)
+	assert.Empty(t, tasks.tasks[0].ProjectID)
+}
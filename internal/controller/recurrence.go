@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
+)
+
+// schedulerActor is recorded as the actor of the occurrences the scheduler
+// creates.
+const schedulerActor = "scheduler"
+
+// StartScheduler starts the goroutine that creates the next occurrence of
+// recurring tasks as they are completed. It must be called after
+// NewTasksController.
+func StartScheduler(ctx context.Context, clock recurrence.Clock) {
+	tC.scheduler = recurrence.NewScheduler(clock, tC.spawnOccurrence)
+	tC.scheduler.Start(ctx)
+}
+
+// checkRecurrence reports whether rule is a valid recurrence; an empty rule
+// means the task does not recur.
+func checkRecurrence(rule string) error {
+	if rule == "" {
+		return nil
+	}
+	_, err := recurrence.Parse(rule)
+	return err
+}
+
+// recur hands a task that has just been completed to the scheduler if it
+// recurs.
+func (tc *TaskController) recur(task database.Task) {
+	if tc.scheduler == nil || task.Recurrence == "" {
+		return
+	}
+	tc.scheduler.Completed(task)
+}
+
+// spawnOccurrence stores the next occurrence of a recurring task and reports
+// it like any other new task.
+func (tc *TaskController) spawnOccurrence(next database.Task) error {
+
+	err := database.MongoDB.InsertSingleTask(next)
+
+	if err != nil {
+		return err
+	}
+
+	tc.taskChanged(changeOrigin{actor: schedulerActor}, database.AuditCreate, next.ID.Hex(), nil, &next)
+
+	return nil
+}
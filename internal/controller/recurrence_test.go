@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func Test_CompleteRecurringTask(t *testing.T) {
+	due := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	mock := &GRPCMockDB{}
+	task := database.Task{ID: primitive.NewObjectID(), Name: "standup", Recurrence: "FREQ=DAILY", Due: &due}
+	mock.tasks = append(mock.tasks, task)
+
+	database.MongoDB = mock
+	NewTasksController()
+
+	spawned := make(chan database.Task, 1)
+	tC.scheduler = recurrence.NewScheduler(fixedClock(due.Add(-time.Hour)), func(next database.Task) error {
+		spawned <- next
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tC.scheduler.Start(ctx)
+
+	r := gin.New()
+	SetUpTasksRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/tasks/"+task.ID.Hex(), strings.NewReader(`{"name": "standup", "status": 1}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "FREQ=DAILY", mock.tasks[0].Recurrence, "PUT keeps the recurrence")
+
+	var next database.Task
+	select {
+	case next = <-spawned:
+	case <-time.After(time.Second):
+		t.Fatal("no occurrence was scheduled")
+	}
+
+	require.NoError(t, tC.spawnOccurrence(next))
+
+	require.Len(t, mock.tasks, 2)
+	assert.Equal(t, "standup", mock.tasks[1].Name)
+	assert.Equal(t, int(Incomplete), mock.tasks[1].Status)
+	assert.Equal(t, due.AddDate(0, 0, 1), *mock.tasks[1].Due)
+	assert.Equal(t, schedulerActor, mock.audit[len(mock.audit)-1].Actor)
+}
+
+func Test_InvalidRecurrence(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "standup")
+
+	w := serveTasks(mock, http.MethodPost, "/api/v1/tasks/", `{"name": "a", "status": 0, "recurrence": "every day"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"recurrence": "FREQ=SOMETIMES"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"recurrence": "0 9 * * 1-5", "due": "2024-01-01T09:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0 9 * * 1-5", mock.tasks[0].Recurrence)
+	assert.JSONEq(t, `{"ID": "`+ids[0]+`", "Name": "standup", "Status": 0, "Due": "2024-01-01T09:00:00Z", "Recurrence": "0 9 * * 1-5"}`, w.Body.String())
+}
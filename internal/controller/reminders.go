@@ -0,0 +1,14 @@
+package controller
+
+import "github.com/tiffany831101/bs_pretest.git/internal/database"
+
+// reminderActor is recorded as the actor of the changes the reminder worker
+// makes.
+const reminderActor = "reminder"
+
+// TaskMarkedOverdue records in the audit log and publishes on the event bus
+// that the reminder worker marked a task overdue, like any other change of a
+// task. It must be called after NewTasksController.
+func TaskMarkedOverdue(before, after database.Task) {
+	tC.taskChanged(changeOrigin{actor: reminderActor}, database.AuditUpdate, before.ID.Hex(), &before, &after)
+}
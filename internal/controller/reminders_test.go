@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+)
+
+func Test_TaskMarkedOverdue(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "late")
+	database.MongoDB = mock
+	NewTasksController()
+
+	ch, unsubscribe := events.Default.Subscribe(1)
+	defer unsubscribe()
+
+	before := mock.tasks[0]
+	after := before
+	now := time.Now().UTC()
+	after.RemindedAt = &now
+	after.Overdue = true
+
+	TaskMarkedOverdue(before, after)
+
+	require.Len(t, mock.audit, 1)
+	assert.Equal(t, reminderActor, mock.audit[0].Actor)
+	assert.Equal(t, database.AuditUpdate, mock.audit[0].Action)
+	assert.True(t, mock.audit[0].After.Overdue)
+
+	select {
+	case e := <-ch:
+		assert.Equal(t, events.TaskUpdated, e.Type)
+		assert.Equal(t, ids[0], e.TaskID)
+		assert.Equal(t, reminderActor, e.Actor)
+	case <-time.After(time.Second):
+		t.Fatal("no event was published")
+	}
+}
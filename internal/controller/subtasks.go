@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SubtaskPolicy says what happens to the subtasks of a deleted task.
+type SubtaskPolicy string
+
+const (
+	// OrphanSubtasks makes the subtasks top-level tasks.
+	OrphanSubtasks SubtaskPolicy = "orphan"
+	// CascadeSubtasks deletes the subtasks, and theirs, with the task.
+	CascadeSubtasks SubtaskPolicy = "cascade"
+	// RejectSubtasks refuses to delete a task that has subtasks.
+	RejectSubtasks SubtaskPolicy = "reject"
+)
+
+func (p SubtaskPolicy) valid() bool {
+	return p == OrphanSubtasks || p == CascadeSubtasks || p == RejectSubtasks
+}
+
+var (
+	errParentNotFound = errors.New("parent task not found")
+	errParentCycle    = errors.New("a task cannot be moved under itself or one of its subtasks")
+	errHasSubtasks    = errors.New("task has subtasks")
+)
+
+// getSubtasks retrieves the direct subtasks of a task.
+// @Summary Retrieve the subtasks of a task
+// @Description Get the direct subtasks of a task, ordered by ID.
+// @ID getSubtasks
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the parent task" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {array} TaskResponse "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/subtasks [get]
+// @Tags tasks
+func (tc *TaskController) getSubtasks(c *gin.Context) {
+
+	parentID := c.Param("id")
+
+	_, err := primitive.ObjectIDFromHex(parentID)
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	parent, err := database.MongoDB.GetTaskByID(parentID)
+
+	if err != nil || parent.ID.IsZero() {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	subtasks, err := database.MongoDB.GetSubtasks(parentID)
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	results := make([]TaskResponse, 0, len(subtasks))
+	for _, t := range subtasks {
+		results = append(results, taskResponse(t))
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// postSubtask creates a subtask.
+// @Summary Create a subtask
+// @Description Create a new task under an existing one. Once every subtask of a task is completed, the task is completed too.
+// @ID postSubtask
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the parent task" Pattern("^[0-9a-fA-F]{24}$")
+// @Param body body TaskRequest true "Subtask details"
+// @Param Idempotency-Key header string false "Replay the stored response when a request is retried with the same key"
+// @Success 201 {string} string "Created"
+// @Header 201 {string} Location "URL of the created subtask"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 422 {object} middleware.Problem "Unprocessable Entity"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id}/subtasks [post]
+// @Tags tasks
+func (tc *TaskController) postSubtask(c *gin.Context) {
+
+	var taskReq TaskRequest
+
+	err := c.BindJSON(&taskReq)
+	if err != nil {
+		c.Error(err)
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	parentID := c.Param("id")
+
+	_, err = primitive.ObjectIDFromHex(parentID)
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	taskID := primitive.NewObjectID().Hex()
+
+	err = tc.createSubtask(originOf(c), parentID, taskReq, taskID)
+
+	if errors.Is(err, errParentNotFound) {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	if errors.Is(err, recurrence.ErrInvalidRule) || errors.Is(err, errInvalidProject) || errors.Is(err, errInvalidLabels) {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Location", taskLocation(taskID))
+	c.JSON(http.StatusCreated, "Created")
+}
+
+// createSubtask inserts a new task under taskID as a subtask of parentID and
+// reports the change.
+func (tc *TaskController) createSubtask(origin changeOrigin, parentID string, taskReq TaskRequest, taskID string) error {
+
+	parent, err := database.MongoDB.GetTaskByID(parentID)
+
+	if err != nil || parent.ID.IsZero() {
+		return errParentNotFound
+	}
+
+	if err := checkTaskRequest(taskReq); err != nil {
+		return err
+	}
+
+	task := taskFromRequest(taskReq, taskID)
+	task.ParentID = parentID
+
+	err = database.MongoDB.InsertSingleTask(*task)
+
+	if err != nil {
+		return err
+	}
+
+	tc.taskChanged(origin, database.AuditCreate, taskID, nil, task)
+
+	return nil
+}
+
+// checkParent reports whether parentID can become the parent of taskID: it
+// must exist, and taskID must not be among its ancestors.
+func checkParent(taskID, parentID string) error {
+
+	if _, err := primitive.ObjectIDFromHex(parentID); err != nil {
+		return errParentNotFound
+	}
+
+	seen := map[string]bool{}
+	for id := parentID; id != ""; {
+		if id == taskID || seen[id] {
+			return errParentCycle
+		}
+		seen[id] = true
+
+		task, err := database.MongoDB.GetTaskByID(id)
+		if err != nil || task.ID.IsZero() {
+			if id == parentID {
+				return errParentNotFound
+			}
+			return nil
+		}
+		id = task.ParentID
+	}
+
+	return nil
+}
+
+// releaseSubtasks applies policy to the subtasks of the deleted task taskID.
+func (tc *TaskController) releaseSubtasks(origin changeOrigin, taskID string, policy SubtaskPolicy) error {
+
+	subtasks, err := database.MongoDB.GetSubtasks(taskID)
+
+	if err != nil {
+		return err
+	}
+
+	for _, subtask := range subtasks {
+		subtaskID := subtask.ID.Hex()
+
+		if policy == CascadeSubtasks {
+			err := tc.removeTask(origin, subtaskID, CascadeSubtasks)
+			if err != nil && !errors.Is(err, errTaskNotFound) {
+				return err
+			}
+			continue
+		}
+
+		orphan := subtask
+		orphan.ParentID = ""
+
+		if err := database.MongoDB.UpdateTaskID(subtaskID, orphan); err != nil {
+			return err
+		}
+
+		tc.taskChanged(origin, database.AuditUpdate, subtaskID, &subtask, &orphan)
+	}
+
+	return nil
+}
+
+// rollUpCompletion completes the task parentID once all of its subtasks are
+// completed. The parent's own change goes through taskChanged, which carries
+// the completion further up.
+func (tc *TaskController) rollUpCompletion(origin changeOrigin, parentID string) {
+
+	parent, err := database.MongoDB.GetTaskByID(parentID)
+
+	if err != nil || parent.ID.IsZero() || parent.Status == int(Completed) {
+		return
+	}
+
+	subtasks, err := database.MongoDB.GetSubtasks(parentID)
+
+	if err != nil {
+		log.Println("Error Roll Up Completion: ", err)
+		return
+	}
+
+	if len(subtasks) == 0 {
+		return
+	}
+
+	for _, subtask := range subtasks {
+		if subtask.Status != int(Completed) {
+			return
+		}
+	}
+
+	completed := parent
+	completed.Status = int(Completed)
+
+	// A parent that is still blocked stays open until it is completed by hand,
+	// and one the status workflow may not complete stays open.
+	if checkBlockers(parent, completed) != nil || checkStatusChange(parent, completed) != nil {
+		return
+	}
+
+	if err := database.MongoDB.UpdateTaskID(parentID, completed); err != nil {
+		log.Println("Error Roll Up Completion: ", err)
+		return
+	}
+
+	tc.taskChanged(origin, database.AuditUpdate, parentID, &parent, &completed)
+}
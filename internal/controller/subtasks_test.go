@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTaskTree stores a task for every name, each one a subtask of the
+// previous one, and returns their IDs from the root down.
+func newTaskTree(mock *GRPCMockDB, names ...string) []string {
+	var ids []string
+	parentID := ""
+	for _, name := range names {
+		task := database.Task{ID: primitive.NewObjectID(), Name: name, ParentID: parentID}
+		mock.tasks = append(mock.tasks, task)
+		parentID = task.ID.Hex()
+		ids = append(ids, parentID)
+	}
+	return ids
+}
+
+func serveTasks(mock *GRPCMockDB, method, target, body string) *httptest.ResponseRecorder {
+	database.MongoDB = mock
+	NewTasksController()
+
+	r := gin.New()
+	SetUpTasksRoutes(r)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func Test_PostAndGetSubtasks(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTaskTree(mock, "release")
+
+	w := serveTasks(mock, http.MethodPost, "/api/v1/tasks/"+ids[0]+"/subtasks", `{"name": "write notes", "status": 0}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	require.Len(t, mock.tasks, 2)
+	assert.Equal(t, ids[0], mock.tasks[1].ParentID)
+	assert.Equal(t, "/api/v1/tasks/"+mock.tasks[1].ID.Hex(), w.Header().Get("Location"))
+
+	w = serveTasks(mock, http.MethodGet, "/api/v1/tasks/"+ids[0]+"/subtasks", "")
+
+	var subtasks []TaskResponse
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &subtasks))
+	assert.Equal(t, []TaskResponse{{ID: mock.tasks[1].ID.Hex(), Name: "write notes", ParentID: ids[0]}}, subtasks)
+}
+
+func Test_PostSubtask_ParentNotFound(t *testing.T) {
+	mock := &GRPCMockDB{}
+
+	w := serveTasks(mock, http.MethodPost, "/api/v1/tasks/"+primitive.NewObjectID().Hex()+"/subtasks", `{"name": "a", "status": 0}`)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, mock.tasks)
+}
+
+func Test_PatchTaskParentCycle(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTaskTree(mock, "root", "child", "grandchild")
+
+	w := serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"parent_id": "`+ids[2]+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"parent_id": "`+ids[0]+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[2], `{"parent_id": "`+ids[0]+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ids[0], mock.tasks[2].ParentID)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[2], `{"parent_id": ""}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, mock.tasks[2].ParentID)
+}
+
+func Test_DeleteTaskSubtaskPolicies(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTaskTree(mock, "root", "child", "grandchild")
+
+	w := serveTasks(mock, http.MethodDelete, "/api/v1/tasks/"+ids[0]+"?subtasks=reject", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Len(t, mock.tasks, 3)
+
+	w = serveTasks(mock, http.MethodDelete, "/api/v1/tasks/"+ids[0]+"?subtasks=keep", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveTasks(mock, http.MethodDelete, "/api/v1/tasks/"+ids[0], "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, mock.tasks, 2)
+	assert.Empty(t, mock.tasks[0].ParentID)
+	assert.Equal(t, ids[1], mock.tasks[1].ParentID)
+
+	w = serveTasks(mock, http.MethodDelete, "/api/v1/tasks/"+ids[1]+"?subtasks=cascade", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, mock.tasks)
+}
+
+func Test_SubtaskCompletionRollsUp(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTaskTree(mock, "root", "child", "grandchild")
+	sibling := database.Task{ID: primitive.NewObjectID(), Name: "sibling", ParentID: ids[0]}
+	mock.tasks = append(mock.tasks, sibling)
+
+	w := serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[2], `{"status": 1}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int(Completed), mock.tasks[1].Status, "child has no other subtask")
+	assert.Equal(t, int(Incomplete), mock.tasks[0].Status, "sibling is still incomplete")
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+sibling.ID.Hex(), `{"status": 1}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int(Completed), mock.tasks[0].Status)
+	assert.Len(t, mock.audit, 4)
+}
@@ -1,14 +1,26 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/internal/recurrence"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type TaskController struct{}
+type TaskController struct {
+	scheduler *recurrence.Scheduler
+}
 
 type TaskStatus int
 
@@ -17,19 +29,45 @@ const (
 	Completed  TaskStatus = 1
 )
 
+// TaskRequest is the body of a create or full update. Due and recurrence are
+// optional; an update that leaves them out keeps the stored ones.
 type TaskRequest struct {
-	Name   string      `json:"name" binding:"required"`
-	Status *TaskStatus `json:"status" binding:"required"`
+	Name       string      `json:"name" binding:"required"`
+	Status     *TaskStatus `json:"status" binding:"required"`
+	Due        *time.Time  `json:"due"`
+	Recurrence string      `json:"recurrence"`
+	ProjectID  string      `json:"project_id"`
+	LabelIDs   []string    `json:"label_ids"`
 }
 
-type TaskResponse struct {
-	ID     string
-	Name   string
-	Status int
+// TaskPatch is the body of a partial update; fields left out are unchanged.
+// An empty parent_id makes a subtask a top-level task again, blocked_by
+// replaces the whole list of blockers and an empty recurrence stops a task
+// from recurring. An empty project_id takes the task out of its project and
+// label_ids replaces the whole list of labels.
+type TaskPatch struct {
+	Name       *string     `json:"name"`
+	Status     *TaskStatus `json:"status"`
+	ParentID   *string     `json:"parent_id"`
+	BlockedBy  *[]string   `json:"blocked_by"`
+	Due        *time.Time  `json:"due"`
+	Recurrence *string     `json:"recurrence"`
+	ProjectID  *string     `json:"project_id"`
+	LabelIDs   *[]string   `json:"label_ids"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+type TaskResponse struct {
+	ID          string
+	Name        string
+	Status      int
+	ParentID    string     `json:",omitempty"`
+	BlockedBy   []string   `json:",omitempty"`
+	Due         *time.Time `json:",omitempty"`
+	Recurrence  string     `json:",omitempty"`
+	ProjectID   string     `json:",omitempty"`
+	LabelIDs    []string   `json:",omitempty"`
+	AssigneeIDs []string   `json:",omitempty"`
+	WatcherIDs  []string   `json:",omitempty"`
 }
 
 var tC *TaskController
@@ -40,13 +78,28 @@ func SetUpTasksRoutes(r *gin.Engine) {
 	{
 
 		taskGroup.GET("/", tC.getAllTasks)
+		taskGroup.GET("/events", tC.streamTaskEvents)
+		taskGroup.GET("/export", tC.exportTasks)
+		taskGroup.POST("/import", tC.importTasks)
+		taskGroup.GET("/order", tC.getTaskOrder)
 		taskGroup.GET("/:id", tC.getTaskByID)
-		taskGroup.PUT("/:id", tC.putTask)
-
-		taskGroup.POST("/", tC.postTask)
+		taskGroup.PUT("/:id", middleware.Idempotency(), tC.putTask)
+		taskGroup.PATCH("/:id", middleware.Idempotency(), tC.patchTask)
+		taskGroup.GET("/:id/history", tC.getTaskHistory)
+		taskGroup.GET("/:id/subtasks", tC.getSubtasks)
+		taskGroup.POST("/:id/subtasks", middleware.Idempotency(), tC.postSubtask)
+		taskGroup.POST("/:id/revert", tC.revertTask)
+		taskGroup.PUT("/:id/assignees/:userId", tC.putAssignee)
+		taskGroup.DELETE("/:id/assignees/:userId", tC.deleteAssignee)
+		taskGroup.PUT("/:id/watchers/:userId", tC.putWatcher)
+		taskGroup.DELETE("/:id/watchers/:userId", tC.deleteWatcher)
+
+		taskGroup.POST("/", middleware.Idempotency(), tC.postTask)
 		taskGroup.DELETE("/:id", tC.deleteTask)
 
 	}
+
+	r.GET("/api/v1/me/tasks", tC.getMyTasks)
 }
 
 func NewTasksController() {
@@ -60,9 +113,13 @@ func NewTasksController() {
 // @Accept json
 // @Produce json
 // @Param body body TaskRequest true "Task details to create"
+// @Param Idempotency-Key header string false "Replay the stored response when a request is retried with the same key"
 // @Success 201 {string} string "Created"
-// @Failure 400 {object} ErrorResponse "Bad Request"
-// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Header 201 {string} Location "URL of the created task"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 422 {object} middleware.Problem "Unprocessable Entity"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
 // @Router /tasks [post]
 // @Tags tasks
 func (tc *TaskController) postTask(c *gin.Context) {
@@ -72,23 +129,190 @@ func (tc *TaskController) postTask(c *gin.Context) {
 	if err != nil {
 		c.Error(err)
 		c.AbortWithStatusJSON(http.StatusBadRequest, err)
+		return
 	}
 
-	err = tc.insertTask(taskReq, "")
+	taskID := primitive.NewObjectID().Hex()
+
+	err = tc.createTask(originOf(c), taskReq, taskID)
+
+	if errors.Is(err, recurrence.ErrInvalidRule) || errors.Is(err, errInvalidProject) || errors.Is(err, errInvalidLabels) {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
 	}
 
+	c.Header("Location", taskLocation(taskID))
 	c.JSON(http.StatusCreated, "Created")
 
 }
 
+// taskResponse is the API representation of task.
+func taskResponse(task database.Task) TaskResponse {
+	return TaskResponse{
+		ID:          task.ID.Hex(),
+		Name:        task.Name,
+		Status:      task.Status,
+		ParentID:    task.ParentID,
+		BlockedBy:   task.BlockedBy,
+		Due:         task.Due,
+		Recurrence:  task.Recurrence,
+		ProjectID:   task.ProjectID,
+		LabelIDs:    task.LabelIDs,
+		AssigneeIDs: task.AssigneeIDs,
+		WatcherIDs:  task.WatcherIDs,
+	}
+}
+
+// taskLocation is the URL of the task with taskID, sent in the Location header
+// when a task is created.
+func taskLocation(taskID string) string {
+	return "/api/v1/tasks/" + taskID
+}
+
+// changeOrigin identifies who made a change and in which request, for the
+// audit log and the event bus.
+type changeOrigin struct {
+	actor     string
+	requestID string
+}
+
+func originOf(c *gin.Context) changeOrigin {
+	return changeOrigin{actor: middleware.Actor(c), requestID: middleware.RequestID(c)}
+}
+
+// createTask inserts a new task under taskID and reports the change.
+func (tc *TaskController) createTask(origin changeOrigin, taskReq TaskRequest, taskID string) error {
+
+	if err := checkTaskRequest(taskReq); err != nil {
+		return err
+	}
+
+	err := tc.insertTask(taskReq, taskID)
+
+	if err != nil {
+		return err
+	}
+
+	tc.taskChanged(origin, database.AuditCreate, taskID, nil, taskFromRequest(taskReq, taskID))
+
+	return nil
+}
+
+// saveTask updates the task with taskID, creating it when it does not exist,
+// and returns the task as stored.
+func (tc *TaskController) saveTask(origin changeOrigin, taskReq TaskRequest, taskID string) (saved database.Task, created bool, err error) {
+
+	task, _ := database.MongoDB.GetTaskByID(taskID)
+
+	if task.ID.IsZero() {
+		if err := tc.createTask(origin, taskReq, taskID); err != nil {
+			return database.Task{}, true, err
+		}
+		return *taskFromRequest(taskReq, taskID), true, nil
+	}
+
+	// Fields that are not part of the request, like the parent, are kept.
+	updated := task
+	updated.Name = taskReq.Name
+	updated.Status = int(*taskReq.Status)
+	if taskReq.Due != nil {
+		setDue(&updated, taskReq.Due)
+	}
+	if taskReq.Recurrence != "" {
+		if err := checkRecurrence(taskReq.Recurrence); err != nil {
+			return database.Task{}, false, err
+		}
+		setRecurrence(&updated, taskReq.Recurrence)
+	}
+	if taskReq.ProjectID != "" {
+		if err := checkProject(taskReq.ProjectID); err != nil {
+			return database.Task{}, false, err
+		}
+		updated.ProjectID = taskReq.ProjectID
+	}
+	if taskReq.LabelIDs != nil {
+		if err := checkLabels(taskReq.LabelIDs); err != nil {
+			return database.Task{}, false, err
+		}
+		updated.LabelIDs = taskReq.LabelIDs
+	}
+
+	if err := checkStatusChange(task, updated); err != nil {
+		return database.Task{}, false, err
+	}
+	if err := checkBlockers(task, updated); err != nil {
+		return database.Task{}, false, err
+	}
+
+	err = database.MongoDB.UpdateTaskID(taskID, updated)
+
+	if err != nil {
+		return database.Task{}, false, err
+	}
+
+	tc.taskChanged(origin, database.AuditUpdate, taskID, &task, &updated)
+
+	return updated, false, nil
+}
+
 func (tc *TaskController) insertTask(task TaskRequest, taskID string) error {
 
+	err := database.MongoDB.InsertSingleTask(*taskFromRequest(task, taskID))
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkTaskRequest reports whether the recurrence, project and labels of a new
+// task are valid.
+func checkTaskRequest(taskReq TaskRequest) error {
+	if err := checkRecurrence(taskReq.Recurrence); err != nil {
+		return err
+	}
+	if err := checkProject(taskReq.ProjectID); err != nil {
+		return err
+	}
+	return checkLabels(taskReq.LabelIDs)
+}
+
+// setDue changes the due date of task; nil removes it. A new due date gets its
+// own reminders, so the ones sent for the old one are forgotten.
+func setDue(task *database.Task, due *time.Time) {
+	if task.Due == nil && due == nil || task.Due != nil && due != nil && task.Due.Equal(*due) {
+		return
+	}
+	task.Due = due
+	task.RemindedAt = nil
+	task.Overdue = false
+}
+
+// setRecurrence changes the recurrence rule of task. A new rule starts a new
+// series, so the start of the old one is forgotten.
+func setRecurrence(task *database.Task, rule string) {
+	if task.Recurrence == rule {
+		return
+	}
+	task.Recurrence = rule
+	task.SeriesStart = nil
+}
+
+func taskFromRequest(task TaskRequest, taskID string) *database.Task {
+
 	dbTask := database.Task{
-		Name:   task.Name,
-		Status: int(*task.Status),
+		Name:       task.Name,
+		Status:     int(*task.Status),
+		Due:        task.Due,
+		Recurrence: task.Recurrence,
+		ProjectID:  task.ProjectID,
+		LabelIDs:   task.LabelIDs,
 	}
 	if taskID != "" {
 		objectID, _ := primitive.ObjectIDFromHex(taskID)
@@ -96,45 +320,190 @@ func (tc *TaskController) insertTask(task TaskRequest, taskID string) error {
 		dbTask.ID = objectID
 	}
 
-	err := database.MongoDB.InsertSingleTask(dbTask)
+	return &dbTask
+}
 
-	if err != nil {
-		return err
+// taskChanged runs after every successful task write: it records the change in
+// the audit log, publishes it on the event bus, rolls completion up to the
+// parents of the task and schedules the next occurrence of a completed
+// recurring task.
+func (tc *TaskController) taskChanged(origin changeOrigin, action database.AuditAction, taskID string, before, after *database.Task) {
+
+	tc.recordAudit(origin, action, taskID, before, after)
+
+	// A deletion carries the task as it was, so subscribers can filter it
+	// like any other change.
+	eventType, payload := events.TaskUpdated, after
+	switch action {
+	case database.AuditCreate:
+		eventType = events.TaskCreated
+	case database.AuditDelete:
+		eventType, payload = events.TaskDeleted, before
 	}
 
-	return nil
+	events.Default.Publish(events.NewTaskEvent(eventType, taskID, payload, origin.actor))
+
+	if after != nil && after.ParentID != "" {
+		tc.rollUpCompletion(origin, after.ParentID)
+	}
+	if before != nil && before.ParentID != "" && (after == nil || after.ParentID != before.ParentID) {
+		tc.rollUpCompletion(origin, before.ParentID)
+	}
+
+	if before != nil && after != nil && before.Status != int(Completed) && after.Status == int(Completed) {
+		tc.recur(*after)
+	}
 }
 
 // getAllTasks retrieves all tasks.
 // @Summary Retrieve all tasks
-// @Description Get details of all tasks.
+// @Description Get details of all tasks, or one page of them ordered by ID when limit is set. With overdue=true only the incomplete tasks past their due date are returned, the earliest due first, and with labels only the tasks that have every one of the labels. The list is streamed from the database as it is read; send Accept: application/x-ndjson to receive one task per line instead of a JSON array.
 // @ID getAllTasks
 // @Accept json
-// @Produce json
+// @Produce json,application/x-ndjson
+// @Param limit query int false "Maximum number of tasks to return"
+// @Param offset query int false "Number of tasks to skip"
+// @Param overdue query bool false "Only return overdue tasks"
+// @Param labels query []string false "IDs of the labels the tasks must have, repeated or comma separated" collectionFormat(multi)
 // @Success 200 {array} TaskResponse "OK"
-// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
 // @Router /tasks [get]
 // @Tags tasks
 func (tc *TaskController) getAllTasks(c *gin.Context) {
 
-	res, err := database.MongoDB.GetTasks()
+	limit, err := queryInt(c, "limit")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	results := []TaskResponse{}
-	for _, t := range res {
-		task := TaskResponse{
-			ID:     t.ID.Hex(),
-			Name:   t.Name,
-			Status: t.Status,
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	overdue := false
+	if value := c.Query("overdue"); value != "" {
+		overdue, err = strconv.ParseBool(value)
+		if err != nil {
+			middleware.AbortWithProblem(c, http.StatusBadRequest, "overdue must be true or false")
+			return
 		}
+	}
 
-		results = append(results, task)
+	filter := database.TaskFilter{LabelIDs: queryList(c, "labels")}
+	if overdue {
+		now := time.Now()
+		filter.DueBefore = &now
+	}
+
+	cursor, err := database.MongoDB.FindTasks(c.Request.Context(), filter, offset, limit)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
 	}
+	defer cursor.Close(c)
 
-	c.JSON(http.StatusOK, results)
+	streamTasks(c, cursor, c.NegotiateFormat(gin.MIMEJSON, mimeNDJSON) == mimeNDJSON)
+}
+
+const (
+	mimeNDJSON = "application/x-ndjson"
+
+	// streamFlushEvery is how many tasks are written between flushes of a
+	// streamed response.
+	streamFlushEvery = 100
+)
+
+// streamTasks writes the tasks from cursor straight to the response as a JSON
+// array, or one object per line when ndjson is set, so memory use does not
+// grow with the collection. The status is sent before the first task, so an
+// error from the cursor can only cut the response short; a JSON array is then
+// left unterminated for the client to notice.
+func streamTasks(c *gin.Context, cursor *database.TaskCursor, ndjson bool) {
+
+	if ndjson {
+		c.Header("Content-Type", mimeNDJSON)
+	} else {
+		c.Header("Content-Type", gin.MIMEJSON+"; charset=utf-8")
+	}
+	c.Status(http.StatusOK)
+
+	if !ndjson {
+		c.Writer.WriteString("[")
+	}
+
+	count := 0
+	for cursor.Next(c) {
+		t, err := cursor.Task()
+		if err != nil {
+			log.Println("Error Stream Tasks: ", err)
+			return
+		}
+
+		b, err := json.Marshal(taskResponse(t))
+		if err != nil {
+			log.Println("Error Stream Tasks: ", err)
+			return
+		}
+
+		if ndjson {
+			b = append(b, '\n')
+		} else if count > 0 {
+			c.Writer.WriteString(",")
+		}
+		c.Writer.Write(b)
+
+		count++
+		if count%streamFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		log.Println("Error Stream Tasks: ", err)
+		return
+	}
+
+	if !ndjson {
+		c.Writer.WriteString("]")
+	}
+	c.Writer.Flush()
+}
+
+// queryList returns the values of the query parameter key, which may be
+// repeated or comma separated, without blanks or duplicates.
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	seen := map[string]bool{}
+	for _, value := range c.QueryArray(key) {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" || seen[v] {
+				continue
+			}
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// queryInt parses the non-negative integer query parameter key, which
+// defaults to 0.
+func queryInt(c *gin.Context, key string) (int64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, nil
 }
 
 // getTaskByID retrieves a task by ID.
@@ -145,8 +514,8 @@ func (tc *TaskController) getAllTasks(c *gin.Context) {
 // @Produce json
 // @Param id path string true "ID of the task to retrieve" Pattern("^[0-9a-fA-F]{24}$")
 // @Success 200 {object} TaskResponse "OK"
-// @Success 404 {object} ErrorResponse "Resource Not Found"
-// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Success 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
 // @Router /tasks/{id} [get]
 // @Tags tasks
 func (tc *TaskController) getTaskByID(c *gin.Context) {
@@ -156,7 +525,7 @@ func (tc *TaskController) getTaskByID(c *gin.Context) {
 	_, err := primitive.ObjectIDFromHex(taskID)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Resource Not Found"})
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
 		return
 	}
 
@@ -179,10 +548,13 @@ func (tc *TaskController) getTaskByID(c *gin.Context) {
 // @Produce json
 // @Param id path string true "ID of the task to update" Pattern("^[0-9a-fA-F]{24}$")
 // @Param body body TaskRequest true "Task details to update"
+// @Param Idempotency-Key header string false "Replay the stored response when a request is retried with the same key"
 // @Success 200 {string} string "OK"
 // @Success 201 {string} string "Created"
-// @Failure 400 {object} ErrorResponse "Bad Request"
-// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 422 {object} middleware.Problem "Unprocessable Entity"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
 // @Router /tasks/{id} [put]
 // @Tags tasks
 func (tc *TaskController) putTask(c *gin.Context) {
@@ -192,7 +564,7 @@ func (tc *TaskController) putTask(c *gin.Context) {
 	err := c.BindJSON(&taskReq)
 	if err != nil {
 		c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -201,51 +573,191 @@ func (tc *TaskController) putTask(c *gin.Context) {
 	_, err = primitive.ObjectIDFromHex(taskID)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Task ID, should be in hex format"})
+		middleware.AbortWithProblem(c, http.StatusBadRequest, "Invalid Task ID, should be in hex format")
 		return
 	}
 
-	task, _ := database.MongoDB.GetTaskByID(taskID)
+	_, created, err := tc.saveTask(originOf(c), taskReq, taskID)
 
-	if task == (database.Task{}) {
+	if errors.Is(err, recurrence.ErrInvalidRule) || errors.Is(err, errInvalidProject) || errors.Is(err, errInvalidLabels) {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-		err = tc.insertTask(taskReq, taskID)
+	if errors.Is(err, errBlocked) || errors.Is(err, errStatusChange) {
+		middleware.AbortWithProblem(c, http.StatusConflict, err.Error())
+		return
+	}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
 
+	if created {
 		c.JSON(http.StatusCreated, "Created")
-
 	} else {
+		c.JSON(http.StatusOK, "OK")
+	}
 
-		err = database.MongoDB.UpdateTaskID(taskID, database.Task(database.Task{
-			Name:   taskReq.Name,
-			Status: int(*taskReq.Status),
-		}))
+}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+// patchTask partially updates a task.
+// @Summary Partially update a task
+// @Description Change the name, status, parent and/or blockers of an existing task, leaving the fields that are not sent unchanged. A task cannot be moved under itself or one of its subtasks, its blockers must not depend on it, and it cannot be completed while a blocker is incomplete.
+// @ID patchTask
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the task to update" Pattern("^[0-9a-fA-F]{24}$")
+// @Param body body TaskPatch true "Fields to change"
+// @Param Idempotency-Key header string false "Replay the stored response when a request is retried with the same key"
+// @Success 200 {object} TaskResponse "OK"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 422 {object} middleware.Problem "Unprocessable Entity"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /tasks/{id} [patch]
+// @Tags tasks
+func (tc *TaskController) patchTask(c *gin.Context) {
+
+	var patch TaskPatch
+
+	err := c.BindJSON(&patch)
+	if err != nil {
+		c.Error(err)
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	taskID := c.Param("id")
+
+	_, err = primitive.ObjectIDFromHex(taskID)
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	updated, err := tc.applyPatch(originOf(c), taskID, patch)
+
+	if errors.Is(err, errTaskNotFound) {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	if errors.Is(err, errEmptyName) || errors.Is(err, errParentNotFound) || errors.Is(err, errParentCycle) ||
+		errors.Is(err, errInvalidBlocker) || errors.Is(err, errDependencyCycle) || errors.Is(err, recurrence.ErrInvalidRule) ||
+		errors.Is(err, errInvalidProject) || errors.Is(err, errInvalidLabels) {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, errBlocked) || errors.Is(err, errStatusChange) {
+		middleware.AbortWithProblem(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, taskResponse(updated))
+}
+
+var (
+	errTaskNotFound = errors.New("task not found")
+	errEmptyName    = errors.New("name must not be empty")
+)
+
+// applyPatch changes the fields set in patch on the existing task with taskID
+// and reports the change.
+func (tc *TaskController) applyPatch(origin changeOrigin, taskID string, patch TaskPatch) (database.Task, error) {
+
+	before, err := database.MongoDB.GetTaskByID(taskID)
+
+	if err != nil || before.ID.IsZero() {
+		return database.Task{}, errTaskNotFound
+	}
+
+	updated := before
+	if patch.Name != nil {
+		if *patch.Name == "" {
+			return database.Task{}, errEmptyName
 		}
+		updated.Name = *patch.Name
+	}
+	if patch.Status != nil {
+		updated.Status = int(*patch.Status)
+	}
+	if patch.ParentID != nil {
+		if *patch.ParentID != "" {
+			if err := checkParent(taskID, *patch.ParentID); err != nil {
+				return database.Task{}, err
+			}
+		}
+		updated.ParentID = *patch.ParentID
+	}
+	if patch.BlockedBy != nil {
+		if err := checkDependencies(taskID, *patch.BlockedBy); err != nil {
+			return database.Task{}, err
+		}
+		updated.BlockedBy = *patch.BlockedBy
+	}
+	if patch.Due != nil {
+		setDue(&updated, patch.Due)
+	}
+	if patch.Recurrence != nil {
+		if err := checkRecurrence(*patch.Recurrence); err != nil {
+			return database.Task{}, err
+		}
+		setRecurrence(&updated, *patch.Recurrence)
+	}
+	if patch.ProjectID != nil {
+		if err := checkProject(*patch.ProjectID); err != nil {
+			return database.Task{}, err
+		}
+		updated.ProjectID = *patch.ProjectID
+	}
+	if patch.LabelIDs != nil {
+		if err := checkLabels(*patch.LabelIDs); err != nil {
+			return database.Task{}, err
+		}
+		updated.LabelIDs = *patch.LabelIDs
+	}
 
-		c.JSON(http.StatusOK, "OK")
+	if err := checkStatusChange(before, updated); err != nil {
+		return database.Task{}, err
+	}
+	if err := checkBlockers(before, updated); err != nil {
+		return database.Task{}, err
+	}
+
+	err = database.MongoDB.UpdateTaskID(taskID, updated)
 
+	if err != nil {
+		return database.Task{}, err
 	}
 
+	tc.taskChanged(origin, database.AuditUpdate, taskID, &before, &updated)
+
+	return updated, nil
 }
 
 // deleteTask deletes a task by ID.
 // @Summary Delete a task
-// @Description Delete an existing task by ID.
+// @Description Delete an existing task by ID. Its subtasks become top-level tasks (orphan), are deleted with it (cascade), or prevent the delete (reject).
 // @ID deleteTask
 // @Accept json
 // @Produce json
 // @Param id path string true "ID of the task to delete" Pattern("^[0-9a-fA-F]{24}$")
+// @Param subtasks query string false "What to do with the subtasks" Enums(orphan, cascade, reject) default(orphan)
 // @Success 200 {string} string "OK"
-// @Success 404 {object} ErrorResponse "Resource Not Found"
-// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Success 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 409 {object} middleware.Problem "Conflict"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
 // @Router /tasks/{id} [delete]
 // @Tags tasks
 func (tc *TaskController) deleteTask(c *gin.Context) {
@@ -254,30 +766,66 @@ func (tc *TaskController) deleteTask(c *gin.Context) {
 	_, err := primitive.ObjectIDFromHex(taskID)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Resource Not Found"})
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
 		return
 	}
 
-	deleteCount, err := database.MongoDB.DeleteTaskByID(taskID)
+	policy := SubtaskPolicy(c.DefaultQuery("subtasks", string(OrphanSubtasks)))
 
-	if err != nil {
+	if !policy.valid() {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, "subtasks must be orphan, cascade or reject")
+		return
+	}
 
-		if deleteCount == 0 {
+	err = tc.removeTask(originOf(c), taskID, policy)
 
-			c.JSON(http.StatusNotFound, gin.H{"error": "Resource Not Found"})
+	if errors.Is(err, errTaskNotFound) {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	if errors.Is(err, errHasSubtasks) {
+		middleware.AbortWithProblem(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-		} else {
+	c.JSON(http.StatusOK, "OK")
+}
 
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// removeTask deletes the task with taskID, reports the change and applies
+// policy to its subtasks.
+func (tc *TaskController) removeTask(origin changeOrigin, taskID string, policy SubtaskPolicy) error {
 
+	if policy == RejectSubtasks {
+		subtasks, err := database.MongoDB.GetSubtasks(taskID)
+		if err != nil {
+			return err
+		}
+		if len(subtasks) > 0 {
+			return errHasSubtasks
 		}
-		return
+	}
+
+	before, _ := database.MongoDB.GetTaskByID(taskID)
+
+	deleteCount, err := database.MongoDB.DeleteTaskByID(taskID)
+
+	if err != nil {
+		return err
 	}
 
 	if deleteCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Resource Not Found"})
-		return
+		return errTaskNotFound
 	}
 
-	c.JSON(http.StatusOK, "OK")
+	tc.taskChanged(origin, database.AuditDelete, taskID, &before, nil)
+	deleteTaskComments(taskID)
+	deleteTaskAttachments(taskID)
+
+	return tc.releaseSubtasks(origin, taskID, policy)
 }
@@ -1,12 +1,15 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +38,30 @@ func (db *MockDB) GetTasks() ([]database.Task, error) {
 	return []database.Task{}, nil
 }
 
+func (db *MockDB) GetSubtasks(parentID string) ([]database.Task, error) {
+	return []database.Task{}, nil
+}
+
+func (db *MockDB) GetDueTasks(before time.Time) ([]database.Task, error) {
+	return []database.Task{}, nil
+}
+
+func (db *MockDB) ClaimTaskReminder(taskID string, due, at time.Time, overdue bool) (database.Task, bool, error) {
+	return database.Task{}, false, nil
+}
+
+func (db *MockDB) ReleaseTaskReminder(before database.Task, at time.Time) error {
+	return nil
+}
+
+func (db *MockDB) IterateTasks(ctx context.Context) (*database.TaskCursor, error) {
+	return database.NewTaskCursor(nil)
+}
+
+func (db *MockDB) FindTasks(ctx context.Context, filter database.TaskFilter, offset, limit int64) (*database.TaskCursor, error) {
+	return database.NewTaskCursor([]database.Task{})
+}
+
 func (db *MockDB) DeleteTaskByID(taskID string) (int64, error) {
 	database.MongoDB = &MockDB{}
 	return 1, nil
@@ -45,6 +72,62 @@ func (db *MockDB) UpdateTaskID(taskID string, task database.Task) error {
 	return nil
 }
 
+func (db *MockDB) InsertAuditEntry(entry database.AuditEntry) error {
+	return nil
+}
+
+func (db *MockDB) GetAuditEntries(taskID string) ([]database.AuditEntry, error) {
+	return []database.AuditEntry{}, nil
+}
+
+func (db *MockDB) GetTaskHistory(taskID string) ([]database.TaskVersion, error) {
+	return []database.TaskVersion{}, nil
+}
+
+func (db *MockDB) GetTaskVersion(taskID string, version int) (database.TaskVersion, error) {
+	return database.TaskVersion{}, nil
+}
+
+func (db *MockDB) InsertIdempotencyRecord(record database.IdempotencyRecord) error {
+	return nil
+}
+
+func (db *MockDB) GetIdempotencyRecord(key string) (database.IdempotencyRecord, error) {
+	return database.IdempotencyRecord{}, nil
+}
+
+func (db *MockDB) SaveIdempotencyRecord(record database.IdempotencyRecord) error {
+	return nil
+}
+
+func (db *MockDB) DeleteIdempotencyRecord(key string) error {
+	return nil
+}
+
+func (db *MockDB) InsertWebhook(webhook database.Webhook) error {
+	return nil
+}
+
+func (db *MockDB) GetWebhooks() ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+
+func (db *MockDB) DeleteWebhookByID(webhookID string) (int64, error) {
+	return 1, nil
+}
+
+func (db *MockDB) InsertWebhookDelivery(delivery database.WebhookDelivery) error {
+	return nil
+}
+
+func (db *MockDB) GetWebhookDeliveries(webhookID string, status database.DeliveryStatus) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+
+func (db *MockDB) WatchTasks(ctx context.Context, instanceID string, handle func(database.TaskChange)) error {
+	return nil
+}
+
 func Test_NewTaskController(t *testing.T) {
 	tC := &TaskController{}
 	assert.NotNil(t, tC)
@@ -71,6 +154,7 @@ func Test_GetAllTasks(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/tasks/", nil)
 
 	tC.getAllTasks(c)
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -156,3 +240,120 @@ func Test_PutTask(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 }
+
+type PatchMockDB struct {
+	MockDB
+	task    database.Task
+	updated *database.Task
+}
+
+func (db *PatchMockDB) GetTaskByID(taskID string) (database.Task, error) {
+	return db.task, nil
+}
+
+func (db *PatchMockDB) UpdateTaskID(taskID string, task database.Task) error {
+	db.updated = &task
+	return nil
+}
+
+func Test_GetAllTasks_InvalidLimit(t *testing.T) {
+	database.MongoDB = &MockDB{}
+
+	tC := &TaskController{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/tasks/?limit=-1", nil)
+
+	tC.getAllTasks(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_PatchTask(t *testing.T) {
+	taskID := primitive.NewObjectID()
+	db := &PatchMockDB{task: database.Task{ID: taskID, Name: "Test Task", Status: 0}}
+	database.MongoDB = db
+
+	w := httptest.NewRecorder()
+
+	tC := &TaskController{}
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: taskID.Hex()})
+	c.Request = &http.Request{
+		Header: map[string][]string{"Content-Type": {"application/json"}},
+		Body:   io.NopCloser(strings.NewReader(`{"status": 1}`)),
+	}
+
+	tC.patchTask(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Test Task", db.updated.Name)
+	assert.Equal(t, 1, db.updated.Status)
+}
+
+func Test_PatchTask_NotFound(t *testing.T) {
+	database.MongoDB = &PatchMockDB{}
+
+	w := httptest.NewRecorder()
+
+	tC := &TaskController{}
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: primitive.NewObjectID().Hex()})
+	c.Request = &http.Request{
+		Header: map[string][]string{"Content-Type": {"application/json"}},
+		Body:   io.NopCloser(strings.NewReader(`{"name": "Renamed"}`)),
+	}
+
+	tC.patchTask(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_GetAllTasks_Overdue(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "late", "later", "future", "done")
+	for i, due := range []time.Duration{-time.Hour, -2 * time.Hour, time.Hour, -time.Hour} {
+		at := time.Now().Add(due)
+		mock.tasks[i].Due = &at
+	}
+	mock.tasks[3].Status = int(Completed)
+
+	w := serveTasks(mock, http.MethodGet, "/api/v1/tasks/?overdue=true", "")
+
+	var tasks []TaskResponse
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, ids[1], tasks[0].ID)
+	assert.Equal(t, ids[0], tasks[1].ID)
+
+	w = serveTasks(mock, http.MethodGet, "/api/v1/tasks/?overdue=true&limit=1&offset=1", "")
+
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, ids[0], tasks[0].ID)
+
+	w = serveTasks(mock, http.MethodGet, "/api/v1/tasks/?overdue=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_PatchTaskDueResetsReminders(t *testing.T) {
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "report")
+	due := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	mock.tasks[0].Due = &due
+	mock.tasks[0].RemindedAt = &due
+	mock.tasks[0].Overdue = true
+
+	w := serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"due": "2024-01-01T09:00:00Z", "name": "the report"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mock.tasks[0].Overdue, "same due date")
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"due": "2024-01-08T09:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, mock.tasks[0].Overdue)
+	assert.Nil(t, mock.tasks[0].RemindedAt)
+}
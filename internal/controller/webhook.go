@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookController struct{}
+
+type WebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Secret string   `json:"secret"`
+}
+
+// WebhookCreatedResponse is only returned once, on creation, because it
+// includes the signing secret.
+type WebhookCreatedResponse struct {
+	database.Webhook
+	Secret string `json:"secret"`
+}
+
+var wC *WebhookController
+
+func SetUpWebhookRoutes(r *gin.Engine) {
+
+	webhookGroup := r.Group("/api/v1/webhooks")
+	{
+		webhookGroup.GET("", wC.getWebhooks)
+		webhookGroup.POST("", wC.postWebhook)
+		webhookGroup.DELETE("/:id", wC.deleteWebhook)
+		webhookGroup.GET("/:id/deliveries", wC.getWebhookDeliveries)
+		webhookGroup.GET("/dead-letters", wC.getDeadLetters)
+	}
+}
+
+func NewWebhookController() {
+	wC = &WebhookController{}
+}
+
+// postWebhook registers a webhook subscription.
+// @Summary Register a webhook
+// @Description Subscribe a URL to task events. Deliveries are signed with HMAC-SHA256 of the body in the X-Webhook-Signature header; a secret is generated when none is given.
+// @ID postWebhook
+// @Accept json
+// @Produce json
+// @Param body body WebhookRequest true "Webhook to register"
+// @Success 201 {object} WebhookCreatedResponse "Created"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /webhooks [post]
+// @Tags webhooks
+func (wc *WebhookController) postWebhook(c *gin.Context) {
+	var req WebhookRequest
+
+	err := c.BindJSON(&req)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, e := range req.Events {
+		if !slices.Contains(events.Types, events.Type(e)) {
+			middleware.AbortWithProblem(c, http.StatusBadRequest, "Unknown event type: "+e)
+			return
+		}
+	}
+
+	if req.Secret == "" {
+		req.Secret, err = generateSecret()
+		if err != nil {
+			middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	webhook := database.Webhook{
+		ID:        primitive.NewObjectID(),
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    req.Secret,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err = database.MongoDB.InsertWebhook(webhook)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, WebhookCreatedResponse{Webhook: webhook, Secret: webhook.Secret})
+}
+
+// getWebhooks retrieves all webhooks.
+// @Summary Retrieve all webhooks
+// @Description Get every registered webhook subscription. Secrets are not included.
+// @ID getWebhooks
+// @Accept json
+// @Produce json
+// @Success 200 {array} database.Webhook "OK"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /webhooks [get]
+// @Tags webhooks
+func (wc *WebhookController) getWebhooks(c *gin.Context) {
+
+	webhooks, err := database.MongoDB.GetWebhooks()
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// deleteWebhook deletes a webhook by ID.
+// @Summary Delete a webhook
+// @Description Remove a webhook subscription. Its delivery log is kept.
+// @ID deleteWebhook
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the webhook to delete" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {string} string "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /webhooks/{id} [delete]
+// @Tags webhooks
+func (wc *WebhookController) deleteWebhook(c *gin.Context) {
+	webhookID := c.Param("id")
+
+	if _, err := primitive.ObjectIDFromHex(webhookID); err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	deleteCount, err := database.MongoDB.DeleteWebhookByID(webhookID)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if deleteCount == 0 {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	c.JSON(http.StatusOK, "OK")
+}
+
+// getWebhookDeliveries retrieves the delivery log of a webhook.
+// @Summary Retrieve webhook deliveries
+// @Description Get every delivery made to a webhook, newest first. A delivery is "dead" once its attempts are exhausted, or with no attempt when the server fell behind and dropped the event.
+// @ID getWebhookDeliveries
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the webhook" Pattern("^[0-9a-fA-F]{24}$")
+// @Success 200 {array} database.WebhookDelivery "OK"
+// @Failure 404 {object} middleware.Problem "Resource Not Found"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /webhooks/{id}/deliveries [get]
+// @Tags webhooks
+func (wc *WebhookController) getWebhookDeliveries(c *gin.Context) {
+	webhookID := c.Param("id")
+
+	if _, err := primitive.ObjectIDFromHex(webhookID); err != nil {
+		middleware.AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+		return
+	}
+
+	deliveries, err := database.MongoDB.GetWebhookDeliveries(webhookID, "")
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// getDeadLetters retrieves deliveries that exhausted their retries.
+// @Summary Retrieve dead letters
+// @Description Get every webhook delivery that failed after all retry attempts, newest first.
+// @ID getDeadLetters
+// @Accept json
+// @Produce json
+// @Success 200 {array} database.WebhookDelivery "OK"
+// @Failure 500 {object} middleware.Problem "Internal Server Error"
+// @Router /webhooks/dead-letters [get]
+// @Tags webhooks
+func (wc *WebhookController) getDeadLetters(c *gin.Context) {
+
+	deliveries, err := database.MongoDB.GetWebhookDeliveries("", database.DeliveryDead)
+	if err != nil {
+		middleware.AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
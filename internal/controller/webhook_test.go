@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookMockDB struct {
+	MockDB
+	webhooks   []database.Webhook
+	deliveries []database.WebhookDelivery
+}
+
+func (db *WebhookMockDB) InsertWebhook(webhook database.Webhook) error {
+	db.webhooks = append(db.webhooks, webhook)
+	return nil
+}
+
+func (db *WebhookMockDB) GetWebhookDeliveries(webhookID string, status database.DeliveryStatus) ([]database.WebhookDelivery, error) {
+	results := []database.WebhookDelivery{}
+	for _, d := range db.deliveries {
+		if (webhookID == "" || d.WebhookID == webhookID) && (status == "" || d.Status == status) {
+			results = append(results, d)
+		}
+	}
+	return results, nil
+}
+
+func newWebhookEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	NewWebhookController()
+	SetUpWebhookRoutes(r)
+	return r
+}
+
+func Test_PostWebhook(t *testing.T) {
+	mock := &WebhookMockDB{}
+	database.MongoDB = mock
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "http://example.com/hook", "events": ["task.created"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	newWebhookEngine().ServeHTTP(w, req)
+
+	var res WebhookCreatedResponse
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Len(t, mock.webhooks, 1)
+	assert.Len(t, res.Secret, 64)
+	assert.Equal(t, mock.webhooks[0].Secret, res.Secret)
+}
+
+func Test_PostWebhookInvalid(t *testing.T) {
+	database.MongoDB = &WebhookMockDB{}
+
+	for _, body := range []string{
+		`{"url": "not a url", "events": ["task.created"]}`,
+		`{"url": "http://example.com/hook", "events": []}`,
+		`{"url": "http://example.com/hook", "events": ["task.exploded"]}`,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		newWebhookEngine().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func Test_GetDeadLetters(t *testing.T) {
+	webhookID := primitive.NewObjectID().Hex()
+	database.MongoDB = &WebhookMockDB{deliveries: []database.WebhookDelivery{
+		{WebhookID: webhookID, Status: database.DeliverySucceeded},
+		{WebhookID: webhookID, Status: database.DeliveryDead},
+	}}
+
+	w := httptest.NewRecorder()
+	newWebhookEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/dead-letters", nil))
+
+	var deliveries []database.WebhookDelivery
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+	assert.Len(t, deliveries, 1)
+
+	w = httptest.NewRecorder()
+	newWebhookEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/"+webhookID+"/deliveries", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+	assert.Len(t, deliveries, 2)
+}
+
+func Test_DeleteTaskPublishesEvent(t *testing.T) {
+	database.MongoDB = &MockDB{}
+
+	ch, unsubscribe := events.Default.Subscribe(1)
+	defer unsubscribe()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	taskID := primitive.NewObjectID().Hex()
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: taskID})
+
+	tC := &TaskController{}
+	tC.deleteTask(c)
+
+	e := <-ch
+	assert.Equal(t, events.TaskDeleted, e.Type)
+	assert.Equal(t, taskID, e.TaskID)
+	assert.NotNil(t, e.Task)
+}
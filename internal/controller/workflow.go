@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+)
+
+var errStatusChange = errors.New("the status workflow does not allow this status change")
+
+// statusWorkflow returns the active status workflow. Every change is allowed
+// until UseStatusWorkflow is called.
+var statusWorkflow = func() config.StatusWorkflow { return nil }
+
+// UseStatusWorkflow makes task changes follow the status workflow of live,
+// as it is after every reload.
+func UseStatusWorkflow(live *config.Live) {
+	statusWorkflow = func() config.StatusWorkflow { return live.Config().Runtime.StatusWorkflow }
+}
+
+// checkStatusChange reports whether the status workflow lets a task be saved
+// as after.
+func checkStatusChange(before, after database.Task) error {
+	if !statusWorkflow().Allows(before.Status, after.Status) {
+		return fmt.Errorf("%w: from %d to %d", errStatusChange, before.Status, after.Status)
+	}
+	return nil
+}
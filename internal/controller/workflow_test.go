@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+func Test_StatusWorkflow(t *testing.T) {
+	UseStatusWorkflow(config.NewLive(&config.Config{Runtime: config.RuntimeConfig{
+		StatusWorkflow: config.StatusWorkflow{"completed": {}},
+	}}))
+	t.Cleanup(func() { statusWorkflow = func() config.StatusWorkflow { return nil } })
+
+	mock := &GRPCMockDB{}
+	ids := newTasks(mock, "design")
+
+	w := serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"status": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"status": 0}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serveTasks(mock, http.MethodPut, "/api/v1/tasks/"+ids[0], `{"name": "design", "status": 0}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, int(Completed), mock.tasks[0].Status)
+
+	w = serveTasks(mock, http.MethodPatch, "/api/v1/tasks/"+ids[0], `{"name": "design it"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,283 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = wsPongWait * 9 / 10
+	wsMaxMessageSize = 64 * 1024
+
+	// wsSendBuffer bounds the messages queued for a connection. A client that
+	// falls this far behind is disconnected instead of slowing everyone down.
+	wsSendBuffer = 64
+)
+
+// WSRequest is a message sent by the client.
+//
+//	{"type": "subscribe", "id": "s1", "filter": {"status": 0}}
+//	{"type": "unsubscribe", "id": "s1"}
+//	{"type": "create", "ref": "r1", "task": {"name": "...", "status": 0}}
+//	{"type": "update", "ref": "r2", "task_id": "...", "task": {"name": "...", "status": 1}}
+type WSRequest struct {
+	Type   string          `json:"type"`
+	ID     string          `json:"id,omitempty"`
+	Ref    string          `json:"ref,omitempty"`
+	TaskID string          `json:"task_id,omitempty"`
+	Filter events.Filter   `json:"filter,omitempty"`
+	Task   json.RawMessage `json:"task,omitempty"`
+}
+
+// WSResponse is a message sent by the server: "event" for a subscribed task
+// change, "ack" when a command succeeded and "error" when it did not.
+type WSResponse struct {
+	Type         string        `json:"type"`
+	Subscription string        `json:"subscription,omitempty"`
+	Ref          string        `json:"ref,omitempty"`
+	TaskID       string        `json:"task_id,omitempty"`
+	Event        *events.Event `json:"event,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// SetUpWSRoutes mounts the WebSocket at /api/v1/ws. Browsers may only open it
+// from the server's own origin or one allowed by runtime.cors.origins.
+func SetUpWSRoutes(r *gin.Engine, live *config.Live) {
+	wsUpgrader.CheckOrigin = wsCheckOrigin(live)
+
+	r.GET("/api/v1/ws", tC.serveWS)
+}
+
+// wsCheckOrigin accepts requests without an Origin header, which do not come
+// from a browser, from the same host, or from an origin allowed for CORS. The
+// allow-list is read on every request, so config reloads apply immediately.
+func wsCheckOrigin(live *config.Live) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		return middleware.OriginAllowed(live, origin)
+	}
+}
+
+type wsClient struct {
+	conn *websocket.Conn
+	send chan WSResponse
+	done chan struct{}
+
+	mu            sync.Mutex
+	subscriptions map[string]events.Filter
+	closeOnce     sync.Once
+}
+
+// serveWS upgrades the connection to a WebSocket for live task subscriptions
+// and task commands.
+// @Summary Open a task WebSocket
+// @Description Upgrade to a WebSocket. Clients send "subscribe"/"unsubscribe" messages with an event filter to receive task changes, and "create"/"update" messages to change tasks.
+// @ID serveWS
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} middleware.Problem "Bad Request"
+// @Failure 403 {object} middleware.Problem "Forbidden"
+// @Router /ws [get]
+// @Tags tasks
+func (tc *TaskController) serveWS(c *gin.Context) {
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written the error response.
+		return
+	}
+
+	client := &wsClient{
+		conn:          conn,
+		send:          make(chan WSResponse, wsSendBuffer),
+		done:          make(chan struct{}),
+		subscriptions: map[string]events.Filter{},
+	}
+
+	feed, unsubscribe := events.Default.Subscribe(wsSendBuffer)
+	defer unsubscribe()
+	defer client.close(websocket.CloseNormalClosure, "")
+
+	go client.writePump()
+	go client.eventPump(feed)
+
+	client.readPump(func(req WSRequest) {
+		tc.handleWSRequest(c, client, req)
+	})
+}
+
+func (tc *TaskController) handleWSRequest(c *gin.Context, client *wsClient, req WSRequest) {
+
+	switch req.Type {
+	case "subscribe":
+		if req.ID == "" {
+			client.enqueue(WSResponse{Type: "error", Ref: req.Ref, Error: "Subscription id is required"})
+			return
+		}
+		client.mu.Lock()
+		client.subscriptions[req.ID] = req.Filter
+		client.mu.Unlock()
+		client.enqueue(WSResponse{Type: "ack", Ref: req.Ref, Subscription: req.ID})
+
+	case "unsubscribe":
+		client.mu.Lock()
+		delete(client.subscriptions, req.ID)
+		client.mu.Unlock()
+		client.enqueue(WSResponse{Type: "ack", Ref: req.Ref, Subscription: req.ID})
+
+	case "create", "update":
+		var taskReq TaskRequest
+		if err := json.Unmarshal(req.Task, &taskReq); err != nil {
+			client.enqueue(WSResponse{Type: "error", Ref: req.Ref, Error: err.Error()})
+			return
+		}
+		if err := binding.Validator.ValidateStruct(&taskReq); err != nil {
+			client.enqueue(WSResponse{Type: "error", Ref: req.Ref, Error: err.Error()})
+			return
+		}
+
+		taskID := req.TaskID
+		var err error
+		if req.Type == "create" {
+			taskID = primitive.NewObjectID().Hex()
+			err = tc.createTask(originOf(c), taskReq, taskID)
+		} else {
+			if _, hexErr := primitive.ObjectIDFromHex(taskID); hexErr != nil {
+				client.enqueue(WSResponse{Type: "error", Ref: req.Ref, Error: "Invalid Task ID, should be in hex format"})
+				return
+			}
+			_, _, err = tc.saveTask(originOf(c), taskReq, taskID)
+		}
+
+		if err != nil {
+			client.enqueue(WSResponse{Type: "error", Ref: req.Ref, Error: err.Error()})
+			return
+		}
+		client.enqueue(WSResponse{Type: "ack", Ref: req.Ref, TaskID: taskID})
+
+	default:
+		client.enqueue(WSResponse{Type: "error", Ref: req.Ref, Error: "Unknown message type: " + req.Type})
+	}
+}
+
+// readPump handles client messages until the connection fails or the client
+// misses a heartbeat.
+func (client *wsClient) readPump(handle func(WSRequest)) {
+	client.conn.SetReadLimit(wsMaxMessageSize)
+	client.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var req WSRequest
+		if err := client.conn.ReadJSON(&req); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				client.enqueue(WSResponse{Type: "error", Error: err.Error()})
+				continue
+			}
+			return
+		}
+		handle(req)
+	}
+}
+
+// writePump is the only writer of data frames and sends a ping every
+// wsPingPeriod so the client's pongs keep the read deadline alive.
+func (client *wsClient) writePump() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-client.done:
+			return
+		case msg := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := client.conn.WriteJSON(msg); err != nil {
+				client.close(websocket.CloseInternalServerErr, "")
+				return
+			}
+		case <-ticker.C:
+			if err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				client.close(websocket.CloseGoingAway, "")
+				return
+			}
+		}
+	}
+}
+
+func (client *wsClient) eventPump(feed <-chan events.Event) {
+	for {
+		select {
+		case <-client.done:
+			return
+		case e, ok := <-feed:
+			if !ok {
+				return
+			}
+
+			client.mu.Lock()
+			var matched []string
+			for id, filter := range client.subscriptions {
+				if filter.Matches(e) {
+					matched = append(matched, id)
+				}
+			}
+			client.mu.Unlock()
+
+			for _, id := range matched {
+				e := e
+				client.enqueue(WSResponse{Type: "event", Subscription: id, Event: &e})
+			}
+		}
+	}
+}
+
+// enqueue queues msg for writing, disconnecting the client if its queue is full.
+func (client *wsClient) enqueue(msg WSResponse) {
+	select {
+	case <-client.done:
+	case client.send <- msg:
+	default:
+		log.Println("WebSocket client is too slow, closing connection")
+		client.close(websocket.ClosePolicyViolation, "slow consumer")
+	}
+}
+
+func (client *wsClient) close(code int, reason string) {
+	client.closeOnce.Do(func() {
+		close(client.done)
+		client.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteWait))
+		client.conn.Close()
+	})
+}
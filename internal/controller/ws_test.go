@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+)
+
+// serveWSFor serves the WebSocket, allowing browser pages from origins.
+func serveWSFor(origins ...string) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+
+	live := config.NewLive(&config.Config{Runtime: config.RuntimeConfig{
+		CORS: config.CORSConfig{Origins: origins},
+	}})
+
+	r := gin.New()
+	NewTasksController()
+	SetUpWSRoutes(r, live)
+	return httptest.NewServer(r)
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws"
+}
+
+func dialWS(t *testing.T) (*websocket.Conn, func()) {
+	server := serveWSFor()
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(server), nil)
+	assert.NoError(t, err)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	return conn, func() {
+		conn.Close()
+		server.Close()
+	}
+}
+
+func readWS(t *testing.T, conn *websocket.Conn) WSResponse {
+	var res WSResponse
+	assert.NoError(t, conn.ReadJSON(&res))
+	return res
+}
+
+func Test_WSCheckOrigin(t *testing.T) {
+	server := serveWSFor("https://app.example.com")
+	defer server.Close()
+
+	for origin, allowed := range map[string]bool{
+		"":                         true,
+		server.URL:                 true,
+		"https://app.example.com":  true,
+		"https://evil.example.com": false,
+	} {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL(server), header)
+		if allowed {
+			assert.NoError(t, err, origin)
+			conn.Close()
+		} else {
+			assert.ErrorIs(t, err, websocket.ErrBadHandshake, origin)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, origin)
+		}
+	}
+}
+
+func Test_WSSubscribe(t *testing.T) {
+	database.MongoDB = &MockDB{}
+	events.Default = events.NewBus(10)
+
+	conn, closeConn := dialWS(t)
+	defer closeConn()
+
+	completed := 1
+	conn.WriteJSON(WSRequest{Type: "subscribe", ID: "done", Filter: events.Filter{Status: &completed}})
+	assert.Equal(t, WSResponse{Type: "ack", Subscription: "done"}, readWS(t, conn))
+
+	events.Default.Publish(events.NewTaskEvent(events.TaskUpdated, "a", &database.Task{Status: 0}, ""))
+	events.Default.Publish(events.NewTaskEvent(events.TaskUpdated, "b", &database.Task{Status: 1}, ""))
+
+	res := readWS(t, conn)
+	assert.Equal(t, "event", res.Type)
+	assert.Equal(t, "done", res.Subscription)
+	assert.Equal(t, "b", res.Event.TaskID)
+
+	conn.WriteJSON(WSRequest{Type: "unsubscribe", ID: "done"})
+	assert.Equal(t, WSResponse{Type: "ack", Subscription: "done"}, readWS(t, conn))
+}
+
+func Test_WSCommands(t *testing.T) {
+	database.MongoDB = &MockDB{}
+	events.Default = events.NewBus(10)
+
+	conn, closeConn := dialWS(t)
+	defer closeConn()
+
+	conn.WriteJSON(WSRequest{Type: "create", Ref: "r1", Task: []byte(`{"name": "Test Task", "status": 0}`)})
+	res := readWS(t, conn)
+	assert.Equal(t, "ack", res.Type)
+	assert.Equal(t, "r1", res.Ref)
+	assert.Len(t, res.TaskID, 24)
+
+	conn.WriteJSON(WSRequest{Type: "create", Ref: "r2", Task: []byte(`{"status": 0}`)})
+	res = readWS(t, conn)
+	assert.Equal(t, "error", res.Type)
+	assert.Equal(t, "r2", res.Ref)
+
+	conn.WriteJSON(WSRequest{Type: "update", Ref: "r3", TaskID: "nope", Task: []byte(`{"name": "Test Task", "status": 1}`)})
+	res = readWS(t, conn)
+	assert.Equal(t, "error", res.Type)
+
+	conn.WriteJSON(WSRequest{Type: "launch", Ref: "r4"})
+	res = readWS(t, conn)
+	assert.Equal(t, "Unknown message type: launch", res.Error)
+
+	conn.WriteMessage(websocket.TextMessage, []byte("{not json"))
+	res = readWS(t, conn)
+	assert.Equal(t, "error", res.Type)
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Attachment describes a file attached to a task. Its contents are kept in a
+// blob store under the attachment ID.
+type Attachment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TaskID      string             `bson:"task_id" json:"task_id"`
+	Filename    string             `bson:"filename" json:"filename"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `bson:"size" json:"size"`
+	Uploader    string             `bson:"uploader" json:"uploader"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+const attachmentCollection = "attachments"
+
+// Database returns the MongoDB database, for stores that keep their own
+// collections in it such as GridFS.
+func (db *DB) Database() *mongo.Database {
+	return db.db
+}
+
+func (db *DB) InsertAttachment(attachment Attachment) error {
+	collection := db.db.Collection(attachmentCollection)
+
+	_, err := collection.InsertOne(context.TODO(), attachment)
+
+	if err != nil {
+		log.Println("Error Insert Attachment: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) GetAttachment(attachmentID string) (Attachment, error) {
+	collection := db.db.Collection(attachmentCollection)
+
+	id, err := primitive.ObjectIDFromHex(attachmentID)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	var attachment Attachment
+	err = collection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&attachment)
+
+	return attachment, err
+}
+
+// GetAttachments returns the attachments of a task, oldest first.
+func (db *DB) GetAttachments(taskID string) ([]Attachment, error) {
+	collection := db.db.Collection(attachmentCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(context.TODO(), bson.M{"task_id": taskID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Attachment{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
+
+func (db *DB) DeleteAttachment(attachmentID string) (int64, error) {
+	collection := db.db.Collection(attachmentCollection)
+
+	id, err := primitive.ObjectIDFromHex(attachmentID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
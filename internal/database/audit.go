@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+type AuditEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TaskID    string             `bson:"task_id" json:"task_id"`
+	Action    AuditAction        `bson:"action" json:"action"`
+	Actor     string             `bson:"actor" json:"actor"`
+	RequestID string             `bson:"request_id" json:"request_id"`
+	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+	Before    *Task              `bson:"before,omitempty" json:"before,omitempty"`
+	After     *Task              `bson:"after,omitempty" json:"after,omitempty"`
+	PrevHash  string             `bson:"prev_hash" json:"prev_hash"`
+	Hash      string             `bson:"hash" json:"hash"`
+	// Seq is the position of the entry in the chain, starting at 1. A unique
+	// index on it lets only one writer append after a given entry.
+	Seq int64 `bson:"seq" json:"seq"`
+}
+
+const auditCollection = "audit_log"
+
+// auditInsertAttempts bounds how often InsertAuditEntry retries when another
+// writer appended to the chain first.
+const auditInsertAttempts = 10
+
+// ComputeAuditHash returns the chain hash of an entry: the SHA-256 of the
+// previous entry's hash followed by the entry's content. Any edit to a stored
+// entry, or removal of one, breaks the chain for every entry after it.
+func ComputeAuditHash(entry AuditEntry) string {
+	content, _ := json.Marshal(struct {
+		TaskID    string      `json:"task_id"`
+		Action    AuditAction `json:"action"`
+		Actor     string      `json:"actor"`
+		RequestID string      `json:"request_id"`
+		Timestamp int64       `json:"timestamp"`
+		Before    *Task       `json:"before"`
+		After     *Task       `json:"after"`
+	}{
+		TaskID:    entry.TaskID,
+		Action:    entry.Action,
+		Actor:     entry.Actor,
+		RequestID: entry.RequestID,
+		Timestamp: entry.Timestamp.UnixMilli(),
+		Before:    entry.Before,
+		After:     entry.After,
+	})
+
+	sum := sha256.Sum256(append([]byte(entry.PrevHash), content...))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain checks that entries, in insertion order, link to each other
+// and that none of them was modified after being written.
+func VerifyAuditChain(entries []AuditEntry) bool {
+	for i, entry := range entries {
+		if i > 0 && entry.PrevHash != entries[i-1].Hash {
+			return false
+		}
+		if ComputeAuditHash(entry) != entry.Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// InsertAuditEntry appends entry to the chain. Other processes may append to
+// the same chain, so the entry claims the sequence number after the current
+// head, and the unique index on seq turns a race for it into a duplicate key
+// error, after which the entry is linked to the new head and tried again.
+func (db *DB) InsertAuditEntry(entry AuditEntry) error {
+	collection := db.db.Collection(auditCollection)
+
+	// Writers in this process take turns so that they do not race each other.
+	db.auditMu.Lock()
+	defer db.auditMu.Unlock()
+
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Millisecond)
+
+	var err error
+	for attempt := 0; attempt < auditInsertAttempts; attempt++ {
+		var last AuditEntry
+		opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})
+		err = collection.FindOne(context.TODO(), bson.M{}, opts).Decode(&last)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+
+		entry.ID = primitive.NewObjectID()
+		entry.Seq = last.Seq + 1
+		entry.PrevHash = last.Hash
+		entry.Hash = ComputeAuditHash(entry)
+
+		_, err = collection.InsertOne(context.TODO(), entry)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		log.Println("Error Insert Audit Entry: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) GetAuditEntries(taskID string) ([]AuditEntry, error) {
+	collection := db.db.Collection(auditCollection)
+
+	filter := bson.M{}
+	if taskID != "" {
+		filter["task_id"] = taskID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []AuditEntry{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
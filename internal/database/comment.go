@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Comment is a Markdown comment on a task. Edits keeps the earlier bodies of
+// an edited comment, oldest first.
+type Comment struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TaskID    string             `bson:"task_id" json:"task_id"`
+	Author    string             `bson:"author" json:"author"`
+	Body      string             `bson:"body" json:"body"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	Edits     []CommentEdit      `bson:"edits,omitempty" json:"edits,omitempty"`
+}
+
+// CommentEdit is the body a comment had until it was edited at EditedAt.
+type CommentEdit struct {
+	Body     string    `bson:"body" json:"body"`
+	EditedAt time.Time `bson:"edited_at" json:"edited_at"`
+}
+
+const commentCollection = "comments"
+
+func (db *DB) InsertComment(comment Comment) error {
+	collection := db.db.Collection(commentCollection)
+
+	_, err := collection.InsertOne(context.TODO(), comment)
+
+	if err != nil {
+		log.Println("Error Insert Comment: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) GetComment(commentID string) (Comment, error) {
+	collection := db.db.Collection(commentCollection)
+
+	id, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	var comment Comment
+	err = collection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&comment)
+
+	return comment, err
+}
+
+// GetComments returns the comments on a task, oldest first.
+func (db *DB) GetComments(taskID string) ([]Comment, error) {
+	collection := db.db.Collection(commentCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(context.TODO(), bson.M{"task_id": taskID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Comment{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
+
+func (db *DB) UpdateComment(comment Comment) error {
+	collection := db.db.Collection(commentCollection)
+
+	_, err := collection.ReplaceOne(context.TODO(), bson.M{"_id": comment.ID}, comment)
+
+	return err
+}
+
+func (db *DB) DeleteComment(commentID string) (int64, error) {
+	collection := db.db.Collection(commentCollection)
+
+	id, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func (db *DB) DeleteTaskComments(taskID string) (int64, error) {
+	collection := db.db.Collection(commentCollection)
+
+	result, err := collection.DeleteMany(context.TODO(), bson.M{"task_id": taskID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
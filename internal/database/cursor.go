@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TaskCursor walks over tasks one at a time, fetching them from MongoDB in
+// batches, so that callers can handle collections that do not fit in memory.
+// It must be closed.
+//
+//	cursor, err := database.MongoDB.IterateTasks(ctx)
+//	defer cursor.Close(ctx)
+//	for cursor.Next(ctx) {
+//		task, err := cursor.Task()
+//	}
+//	err = cursor.Err()
+type TaskCursor struct {
+	cursor *mongo.Cursor
+}
+
+// NewTaskCursor returns a cursor over tasks already in memory. It lets
+// DBInterface implementations that are not backed by MongoDB, such as test
+// doubles, implement IterateTasks and FindTasks.
+func NewTaskCursor(tasks []Task) (*TaskCursor, error) {
+	documents := make([]interface{}, len(tasks))
+	for i, t := range tasks {
+		documents[i] = t
+	}
+
+	cursor, err := mongo.NewCursorFromDocuments(documents, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskCursor{cursor: cursor}, nil
+}
+
+// Next advances to the next task. It returns false once the tasks are
+// exhausted or an error occurs; Err tells them apart.
+func (c *TaskCursor) Next(ctx context.Context) bool {
+	return c.cursor.Next(ctx)
+}
+
+// Task decodes the task Next advanced to.
+func (c *TaskCursor) Task() (Task, error) {
+	var task Task
+	err := c.cursor.Decode(&task)
+	return task, err
+}
+
+// All decodes the remaining tasks and closes the cursor. It is for results
+// known to be small, such as a page.
+func (c *TaskCursor) All(ctx context.Context) ([]Task, error) {
+	tasks := []Task{}
+	err := c.cursor.All(ctx, &tasks)
+	return tasks, err
+}
+
+func (c *TaskCursor) Err() error {
+	return c.cursor.Err()
+}
+
+func (c *TaskCursor) Close(ctx context.Context) error {
+	return c.cursor.Close(ctx)
+}
+
+// IterateTasks returns a cursor over every task, ordered by ID.
+func (db *DB) IterateTasks(ctx context.Context) (*TaskCursor, error) {
+	collection := db.db.Collection(taskCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskCursor{cursor: cursor}, nil
+}
+
+// FindTasks returns a cursor over the tasks filter selects, skipping offset of
+// them and returning up to limit, or all of them when limit is 0.
+func (db *DB) FindTasks(ctx context.Context, filter TaskFilter, offset, limit int64) (*TaskCursor, error) {
+	collection := db.db.Collection(taskCollection)
+
+	opts := options.Find().SetSort(filter.sort()).SetSkip(offset)
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := collection.Find(ctx, filter.query(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskCursor{cursor: cursor}, nil
+}
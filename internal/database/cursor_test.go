@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewTaskCursor(t *testing.T) {
+	ctx := context.Background()
+	tasks := []Task{
+		{ID: primitive.NewObjectID(), Name: "a", Status: 0},
+		{ID: primitive.NewObjectID(), Name: "b", Status: 1},
+	}
+
+	cursor, err := NewTaskCursor(tasks)
+	assert.NoError(t, err)
+	defer cursor.Close(ctx)
+
+	var got []Task
+	for cursor.Next(ctx) {
+		task, err := cursor.Task()
+		assert.NoError(t, err)
+		got = append(got, task)
+	}
+
+	assert.NoError(t, cursor.Err())
+	assert.Equal(t, tasks, got)
+}
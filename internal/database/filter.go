@@ -0,0 +1,92 @@
+package database
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TaskFilter selects tasks by their fields for FindTasks. Fields left at their
+// zero value select every task.
+type TaskFilter struct {
+	// ProjectID selects the tasks of the project.
+	ProjectID string
+	// LabelIDs selects the tasks that have every one of the labels.
+	LabelIDs []string
+	// AssigneeID selects the tasks assigned to the user.
+	AssigneeID string
+	// DueBefore selects the tasks that are not completed and are due at or
+	// before it, and orders them the earliest due first.
+	DueBefore *time.Time
+	// Status selects the tasks with the status.
+	Status *int
+	// NameContains selects the tasks whose name contains it, ignoring case.
+	NameContains string
+}
+
+// query returns the MongoDB filter that selects the tasks of f.
+func (f TaskFilter) query() bson.M {
+	query := bson.M{}
+	if f.ProjectID != "" {
+		query["project_id"] = f.ProjectID
+	}
+	if len(f.LabelIDs) > 0 {
+		query["label_ids"] = bson.M{"$all": f.LabelIDs}
+	}
+	if f.AssigneeID != "" {
+		query["assignee_ids"] = f.AssigneeID
+	}
+	status := bson.M{}
+	if f.Status != nil {
+		status["$eq"] = *f.Status
+	}
+	if f.DueBefore != nil {
+		status["$ne"] = 1
+		query["due"] = bson.M{"$lte": *f.DueBefore}
+	}
+	if len(status) > 0 {
+		query["status"] = status
+	}
+	if f.NameContains != "" {
+		query["name"] = bson.M{"$regex": regexp.QuoteMeta(f.NameContains), "$options": "i"}
+	}
+	return query
+}
+
+// sort returns the order of the tasks of f, which always ends with the ID so
+// that consecutive pages neither repeat nor miss tasks.
+func (f TaskFilter) sort() bson.D {
+	if f.DueBefore != nil {
+		return bson.D{{Key: "due", Value: 1}, {Key: "_id", Value: 1}}
+	}
+	return bson.D{{Key: "_id", Value: 1}}
+}
+
+// Matches reports whether f selects task. It lets DBInterface implementations
+// that are not backed by MongoDB, such as test doubles, implement FindTasks.
+func (f TaskFilter) Matches(task Task) bool {
+	if f.ProjectID != "" && task.ProjectID != f.ProjectID {
+		return false
+	}
+	for _, id := range f.LabelIDs {
+		if !slices.Contains(task.LabelIDs, id) {
+			return false
+		}
+	}
+	if f.AssigneeID != "" && !slices.Contains(task.AssigneeIDs, f.AssigneeID) {
+		return false
+	}
+	if f.DueBefore != nil && (task.Status == 1 || task.Due == nil || task.Due.After(*f.DueBefore)) {
+		return false
+	}
+	if f.Status != nil && task.Status != *f.Status {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(task.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	return true
+}
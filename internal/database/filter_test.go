@@ -0,0 +1,61 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTaskFilter(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	late, early := now.Add(-time.Hour), now.Add(time.Hour)
+
+	filter := TaskFilter{LabelIDs: []string{"bug", "urgent"}, DueBefore: &now}
+
+	assert.Equal(t, bson.M{
+		"label_ids": bson.M{"$all": []string{"bug", "urgent"}},
+		"status":    bson.M{"$ne": 1},
+		"due":       bson.M{"$lte": now},
+	}, filter.query())
+	assert.Equal(t, bson.D{{Key: "due", Value: 1}, {Key: "_id", Value: 1}}, filter.sort())
+
+	assert.True(t, filter.Matches(Task{LabelIDs: []string{"urgent", "bug", "docs"}, Due: &late}))
+	assert.False(t, filter.Matches(Task{LabelIDs: []string{"bug"}, Due: &late}))
+	assert.False(t, filter.Matches(Task{LabelIDs: []string{"bug", "urgent"}, Due: &early}))
+	assert.False(t, filter.Matches(Task{LabelIDs: []string{"bug", "urgent"}}))
+	assert.False(t, filter.Matches(Task{LabelIDs: []string{"bug", "urgent"}, Due: &late, Status: 1}))
+
+	incomplete := 0
+	byName := TaskFilter{Status: &incomplete, NameContains: "buy (2)"}
+
+	assert.Equal(t, bson.M{
+		"status": bson.M{"$eq": 0},
+		"name":   bson.M{"$regex": `buy \(2\)`, "$options": "i"},
+	}, byName.query())
+
+	assert.True(t, byName.Matches(Task{Name: "Buy (2) eggs"}))
+	assert.False(t, byName.Matches(Task{Name: "Buy (2) eggs", Status: 1}))
+	assert.False(t, byName.Matches(Task{Name: "Buy 2 eggs"}))
+
+	byProject := TaskFilter{ProjectID: "website", LabelIDs: []string{"bug"}}
+
+	assert.Equal(t, bson.M{
+		"project_id": "website",
+		"label_ids":  bson.M{"$all": []string{"bug"}},
+	}, byProject.query())
+
+	assert.True(t, byProject.Matches(Task{ProjectID: "website", LabelIDs: []string{"bug"}}))
+	assert.False(t, byProject.Matches(Task{ProjectID: "blog", LabelIDs: []string{"bug"}}))
+	assert.False(t, byProject.Matches(Task{LabelIDs: []string{"bug"}}))
+
+	byAssignee := TaskFilter{AssigneeID: "alice"}
+
+	assert.Equal(t, bson.M{"assignee_ids": "alice"}, byAssignee.query())
+	assert.True(t, byAssignee.Matches(Task{AssigneeIDs: []string{"bob", "alice"}}))
+	assert.False(t, byAssignee.Matches(Task{AssigneeIDs: []string{"bob"}}))
+
+	assert.Equal(t, bson.M{}, TaskFilter{}.query())
+	assert.True(t, TaskFilter{}.Matches(Task{}))
+}
@@ -2,8 +2,12 @@ package database
 
 import (
 	"context"
+	"errors"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/tiffany831101/bs_pretest.git/config"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -11,8 +15,11 @@ import (
 )
 
 type DB struct {
-	client *mongo.Client
-	db     *mongo.Database
+	client    *mongo.Client
+	db        *mongo.Database
+	auditMu   sync.Mutex
+	historyMu sync.Mutex
+	deletes   localDeletes
 }
 
 type DBInterface interface {
@@ -20,25 +27,129 @@ type DBInterface interface {
 	InsertSingleTask(task Task) error
 	GetTaskByID(taskID string) (Task, error)
 	GetTasks() ([]Task, error)
+	GetSubtasks(parentID string) ([]Task, error)
+	GetDueTasks(before time.Time) ([]Task, error)
+	ClaimTaskReminder(taskID string, due, at time.Time, overdue bool) (Task, bool, error)
+	ReleaseTaskReminder(before Task, at time.Time) error
+	IterateTasks(ctx context.Context) (*TaskCursor, error)
+	FindTasks(ctx context.Context, filter TaskFilter, offset, limit int64) (*TaskCursor, error)
 	DeleteTaskByID(taskID string) (int64, error)
 	UpdateTaskID(taskID string, task Task) error
+	InsertAuditEntry(entry AuditEntry) error
+	GetAuditEntries(taskID string) ([]AuditEntry, error)
+	GetTaskHistory(taskID string) ([]TaskVersion, error)
+	GetTaskVersion(taskID string, version int) (TaskVersion, error)
+	InsertIdempotencyRecord(record IdempotencyRecord) error
+	GetIdempotencyRecord(key string) (IdempotencyRecord, error)
+	SaveIdempotencyRecord(record IdempotencyRecord) error
+	DeleteIdempotencyRecord(key string) error
+	InsertWebhook(webhook Webhook) error
+	GetWebhooks() ([]Webhook, error)
+	DeleteWebhookByID(webhookID string) (int64, error)
+	InsertWebhookDelivery(delivery WebhookDelivery) error
+	GetWebhookDeliveries(webhookID string, status DeliveryStatus) ([]WebhookDelivery, error)
+	WatchTasks(ctx context.Context, instanceID string, handle func(TaskChange)) error
+}
+
+// CommentRepository stores the comments on tasks, in their own collection.
+// DB implements it; the task controller deletes the comments of a task with
+// the task.
+type CommentRepository interface {
+	InsertComment(comment Comment) error
+	GetComment(commentID string) (Comment, error)
+	GetComments(taskID string) ([]Comment, error)
+	UpdateComment(comment Comment) error
+	DeleteComment(commentID string) (int64, error)
+	DeleteTaskComments(taskID string) (int64, error)
+}
+
+// AttachmentRepository stores the metadata of task attachments, in their own
+// collection. DB implements it; the contents are in a storage.BlobStore.
+type AttachmentRepository interface {
+	InsertAttachment(attachment Attachment) error
+	GetAttachment(attachmentID string) (Attachment, error)
+	GetAttachments(taskID string) ([]Attachment, error)
+	DeleteAttachment(attachmentID string) (int64, error)
+}
+
+// ProjectRepository stores projects and LabelRepository labels, each in their
+// own collection. DB implements both; the tasks refer to them by ID.
+type ProjectRepository interface {
+	InsertProject(project Project) error
+	GetProject(projectID string) (Project, error)
+	GetProjects() ([]Project, error)
+	UpdateProject(project Project) error
+	DeleteProject(projectID string) (int64, error)
+}
+
+type LabelRepository interface {
+	InsertLabel(label Label) error
+	GetLabel(labelID string) (Label, error)
+	GetLabels() ([]Label, error)
+	UpdateLabel(label Label) error
+	DeleteLabel(labelID string) (int64, error)
 }
 
 var MongoDB DBInterface
 
+var Comments CommentRepository
+
+var Attachments AttachmentRepository
+
+var Projects ProjectRepository
+
+var Labels LabelRepository
+
 type Task struct {
 	ID     primitive.ObjectID `bson:"_id,omitempty"`
 	Name   string             `bson:"name,omitempty"`
 	Status int                `bson:"status"`
+	// ParentID is the ID of the task this one is a subtask of. It is left out
+	// of the JSON when empty so that audit hashes of top-level tasks are the
+	// same as before subtasks existed.
+	ParentID string `bson:"parent_id,omitempty" json:",omitempty"`
+	// BlockedBy lists the IDs of the tasks that must be completed before this
+	// one can be.
+	BlockedBy []string `bson:"blocked_by,omitempty" json:",omitempty"`
+	// Due is when the task should be done by.
+	Due *time.Time `bson:"due,omitempty" json:",omitempty"`
+	// Recurrence is an RRULE or cron expression; completing the task creates
+	// its next occurrence.
+	Recurrence string `bson:"recurrence,omitempty" json:",omitempty"`
+	// SeriesStart is when the recurring series the task is an occurrence of
+	// started. It anchors rules such as COUNT that are counted from the first
+	// occurrence, and is carried from each occurrence to the next.
+	SeriesStart *time.Time `bson:"series_start,omitempty" json:",omitempty"`
+	// ProjectID is the ID of the project the task belongs to, and LabelIDs
+	// the IDs of its labels.
+	ProjectID string   `bson:"project_id,omitempty" json:",omitempty"`
+	LabelIDs  []string `bson:"label_ids,omitempty" json:",omitempty"`
+	// AssigneeIDs are the users working on the task and WatcherIDs the users
+	// notified when it changes.
+	AssigneeIDs []string `bson:"assignee_ids,omitempty" json:",omitempty"`
+	WatcherIDs  []string `bson:"watcher_ids,omitempty" json:",omitempty"`
+	// RemindedAt is when the last due-date reminder was sent, and Overdue is
+	// set once the overdue reminder has been. Both are cleared when the due
+	// date changes.
+	RemindedAt *time.Time `bson:"reminded_at,omitempty" json:",omitempty"`
+	Overdue    bool       `bson:"overdue,omitempty" json:",omitempty"`
 }
 
 const taskCollection = "tasks"
 
-func NewDB(dbURL, dbName string) {
-	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(dbURL))
+func NewDB(cfg config.DBConfig) *DB {
+	clientOptions := options.Client().ApplyURI(cfg.URI)
+	if cfg.Username != "" {
+		clientOptions.SetAuth(options.Credential{
+			Username: cfg.Username,
+			Password: cfg.Password,
+		})
+	}
+
+	client, err := mongo.Connect(context.TODO(), clientOptions)
 	if err != nil {
 		log.Fatal("Error initializeing MongoDB: ", err)
-		return
+		return nil
 	}
 
 	// Try connecting to mongodb
@@ -48,12 +159,18 @@ func NewDB(dbURL, dbName string) {
 		log.Println("Successfully Connected To MongDB.")
 	}
 
-	db := client.Database(dbName)
-
-	MongoDB = &DB{
+	db := &DB{
 		client: client,
-		db:     db,
+		db:     client.Database(cfg.Name),
 	}
+
+	MongoDB = db
+	Comments = db
+	Attachments = db
+	Projects = db
+	Labels = db
+
+	return db
 }
 
 func (db *DB) CloseConnection() {
@@ -67,13 +184,17 @@ func (db *DB) CloseConnection() {
 func (db *DB) InsertSingleTask(task Task) error {
 	collection := db.db.Collection(taskCollection)
 
-	_, err := collection.InsertOne(context.TODO(), task)
+	result, err := collection.InsertOne(context.TODO(), taskDocument{Task: task, Origin: originStamp()})
 
 	if err != nil {
 		log.Println("Error Insert Single Task: ", err)
 		return err
 	}
 
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		db.recordVersion(id.Hex(), task)
+	}
+
 	return nil
 }
 
@@ -108,6 +229,103 @@ func (db *DB) GetTasks() ([]Task, error) {
 	return results, err
 }
 
+// GetSubtasks returns the tasks whose parent is parentID, ordered by ID.
+func (db *DB) GetSubtasks(parentID string) ([]Task, error) {
+	collection := db.db.Collection(taskCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(context.TODO(), bson.M{"parent_id": parentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Task{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
+
+// GetDueTasks returns the tasks that are not completed and are due at or
+// before before, the earliest first.
+func (db *DB) GetDueTasks(before time.Time) ([]Task, error) {
+	collection := db.db.Collection(taskCollection)
+
+	filter := bson.M{"status": bson.M{"$ne": 1}, "due": bson.M{"$lte": before}}
+	opts := options.Find().SetSort(bson.D{{Key: "due", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Task{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
+
+// ClaimTaskReminder records that a reminder for the task, due at due, is sent
+// at at, and that the task is overdue when overdue is set. Every replica runs
+// the reminder worker, so the record is made only if no reminder of the kind
+// was claimed for the due date yet, and exactly one worker gets true back and
+// sends the reminder. The task is returned as it was before the claim. This is
+// bookkeeping of the reminder worker, so no task version is recorded for it.
+func (db *DB) ClaimTaskReminder(taskID string, due, at time.Time, overdue bool) (Task, bool, error) {
+	collection := db.db.Collection(taskCollection)
+	id, err := primitive.ObjectIDFromHex(taskID)
+
+	if err != nil {
+		return Task{}, false, err
+	}
+
+	filter := bson.M{"_id": id, "due": due}
+	if overdue {
+		filter["overdue"] = bson.M{"$ne": true}
+	} else {
+		filter["reminded_at"] = bson.M{"$exists": false}
+	}
+
+	set := bson.M{"reminded_at": at.UTC().Truncate(time.Millisecond), originField: originStamp()}
+	if overdue {
+		set["overdue"] = true
+	}
+
+	var before Task
+	err = collection.FindOneAndUpdate(context.TODO(), filter, bson.M{"$set": set}).Decode(&before)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Task{}, false, nil
+	}
+	if err != nil {
+		return Task{}, false, err
+	}
+
+	return before, true, nil
+}
+
+// ReleaseTaskReminder undoes the claim made at at on a reminder that could not
+// be sent, putting back the reminder state of the task from before, so that
+// the reminder is tried again. A claim made since by another worker is kept.
+func (db *DB) ReleaseTaskReminder(before Task, at time.Time) error {
+	collection := db.db.Collection(taskCollection)
+
+	filter := bson.M{"_id": before.ID, "reminded_at": at.UTC().Truncate(time.Millisecond)}
+
+	set := bson.M{originField: originStamp()}
+	unset := bson.M{}
+	setOrUnset(set, unset, "reminded_at", before.RemindedAt, before.RemindedAt == nil)
+	setOrUnset(set, unset, "overdue", before.Overdue, !before.Overdue)
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err := collection.UpdateOne(context.TODO(), filter, update)
+
+	return err
+}
+
 func (db *DB) DeleteTaskByID(taskID string) (int64, error) {
 	collection := db.db.Collection(taskCollection)
 	idPrimitive, err := primitive.ObjectIDFromHex(taskID)
@@ -116,10 +334,11 @@ func (db *DB) DeleteTaskByID(taskID string) (int64, error) {
 		return -1, err
 	}
 
+	db.deletes.add(taskID)
+
 	deletedResult, err := collection.DeleteOne(context.TODO(), bson.M{"_id": idPrimitive})
 
 	if err != nil {
-		log.Fatal(err)
 		return 0, err
 	}
 
@@ -134,12 +353,41 @@ func (db *DB) UpdateTaskID(taskID string, task Task) error {
 
 	filter := bson.M{"_id": id}
 
-	update := bson.M{"$set": bson.M{"name": task.Name, "status": task.Status}}
+	set := bson.M{"name": task.Name, "status": task.Status, originField: originStamp()}
+	unset := bson.M{}
+	setOrUnset(set, unset, "parent_id", task.ParentID, task.ParentID == "")
+	setOrUnset(set, unset, "blocked_by", task.BlockedBy, len(task.BlockedBy) == 0)
+	setOrUnset(set, unset, "due", task.Due, task.Due == nil)
+	setOrUnset(set, unset, "recurrence", task.Recurrence, task.Recurrence == "")
+	setOrUnset(set, unset, "series_start", task.SeriesStart, task.SeriesStart == nil)
+	setOrUnset(set, unset, "project_id", task.ProjectID, task.ProjectID == "")
+	setOrUnset(set, unset, "label_ids", task.LabelIDs, len(task.LabelIDs) == 0)
+	setOrUnset(set, unset, "assignee_ids", task.AssigneeIDs, len(task.AssigneeIDs) == 0)
+	setOrUnset(set, unset, "watcher_ids", task.WatcherIDs, len(task.WatcherIDs) == 0)
+	setOrUnset(set, unset, "reminded_at", task.RemindedAt, task.RemindedAt == nil)
+	setOrUnset(set, unset, "overdue", task.Overdue, !task.Overdue)
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
 	_, err := collection.UpdateOne(context.TODO(), filter, update)
 
 	if err != nil {
 		return err
 	}
 
+	db.recordVersion(taskID, task)
+
 	return nil
 }
+
+// setOrUnset adds an optional field to the $set or, when it is empty, the
+// $unset of an update, matching how omitempty leaves it out on insert.
+func setOrUnset(set, unset bson.M, field string, value interface{}, empty bool) {
+	if empty {
+		unset[field] = ""
+	} else {
+		set[field] = value
+	}
+}
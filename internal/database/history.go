@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TaskVersion struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	TaskID    string             `bson:"task_id" json:"task_id"`
+	Version   int                `bson:"version" json:"version"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	// Task is the whole task as it was in this version.
+	Task Task `bson:"task" json:"task"`
+}
+
+const historyCollection = "task_history"
+
+// historyInsertAttempts bounds how often recordVersion retries when another
+// writer recorded a version of the same task first.
+const historyInsertAttempts = 10
+
+// recordVersion stores a snapshot of task as its next version. It is called
+// after every successful insert or update so the history always ends with the
+// task's current state. The write it follows has already been made, so a
+// failure is only logged, like a failed audit entry. The version claims the
+// number after the task's latest one, and the unique index on task_id and
+// version turns a race with another writer into a duplicate key error, after
+// which the next number is tried.
+func (db *DB) recordVersion(taskID string, task Task) {
+	collection := db.db.Collection(historyCollection)
+
+	db.historyMu.Lock()
+	defer db.historyMu.Unlock()
+
+	snapshot := task
+	snapshot.ID, _ = primitive.ObjectIDFromHex(taskID)
+
+	var err error
+	for attempt := 0; attempt < historyInsertAttempts; attempt++ {
+		var last TaskVersion
+		opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+		err = collection.FindOne(context.TODO(), bson.M{"task_id": taskID}, opts).Decode(&last)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+
+		_, err = collection.InsertOne(context.TODO(), TaskVersion{
+			TaskID:    taskID,
+			Version:   last.Version + 1,
+			CreatedAt: time.Now().UTC(),
+			Task:      snapshot,
+		})
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		log.Println("Error Record Task Version: ", err)
+	}
+}
+
+func (db *DB) GetTaskHistory(taskID string) ([]TaskVersion, error) {
+	collection := db.db.Collection(historyCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cursor, err := collection.Find(context.TODO(), bson.M{"task_id": taskID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []TaskVersion{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
+
+func (db *DB) GetTaskVersion(taskID string, version int) (TaskVersion, error) {
+	collection := db.db.Collection(historyCollection)
+
+	filter := bson.M{"task_id": taskID, "version": version}
+
+	var result TaskVersion
+	err := collection.FindOne(context.TODO(), filter).Decode(&result)
+
+	return result, err
+}
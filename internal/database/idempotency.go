@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type IdempotencyRecord struct {
+	Key         string    `bson:"_id"`
+	Fingerprint string    `bson:"fingerprint"`
+	Completed   bool      `bson:"completed"`
+	StatusCode  int       `bson:"status_code"`
+	ContentType string    `bson:"content_type"`
+	Location    string    `bson:"location,omitempty"`
+	Body        []byte    `bson:"body"`
+	CreatedAt   time.Time `bson:"created_at"`
+}
+
+const (
+	idempotencyCollection = "idempotency_keys"
+
+	// IdempotencyTTL is how long a stored response is replayed before Mongo's
+	// TTL monitor removes it and the key can be used again.
+	IdempotencyTTL = 24 * time.Hour
+)
+
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
+// InsertIdempotencyRecord reserves a key. It returns ErrIdempotencyKeyExists
+// when another request already holds it.
+func (db *DB) InsertIdempotencyRecord(record IdempotencyRecord) error {
+	collection := db.db.Collection(idempotencyCollection)
+
+	_, err := collection.InsertOne(context.TODO(), record)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrIdempotencyKeyExists
+	}
+
+	if err != nil {
+		log.Println("Error Insert Idempotency Record: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) GetIdempotencyRecord(key string) (IdempotencyRecord, error) {
+	collection := db.db.Collection(idempotencyCollection)
+
+	var record IdempotencyRecord
+	err := collection.FindOne(context.TODO(), bson.M{"_id": key}).Decode(&record)
+
+	return record, err
+}
+
+func (db *DB) SaveIdempotencyRecord(record IdempotencyRecord) error {
+	collection := db.db.Collection(idempotencyCollection)
+
+	_, err := collection.ReplaceOne(context.TODO(), bson.M{"_id": record.Key}, record)
+
+	return err
+}
+
+func (db *DB) DeleteIdempotencyRecord(key string) error {
+	collection := db.db.Collection(idempotencyCollection)
+
+	_, err := collection.DeleteOne(context.TODO(), bson.M{"_id": key})
+
+	return err
+}
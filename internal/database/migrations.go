@@ -0,0 +1,288 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(db *mongo.Database) error
+	Down    func(db *mongo.Database) error
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type appliedMigration struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+const migrationCollection = "schema_migrations"
+
+// Migrations lists every schema change in the order they are applied. Append
+// new ones at the end; never renumber or edit one that has shipped.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "idempotency keys TTL index",
+		Up: func(db *mongo.Database) error {
+			return createIndex(db, idempotencyCollection, "created_at_ttl", bson.D{{Key: "created_at", Value: 1}},
+				options.Index().SetExpireAfterSeconds(int32(IdempotencyTTL.Seconds())))
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db, idempotencyCollection, "created_at_ttl")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "unique task history version index",
+		Up: func(db *mongo.Database) error {
+			return createIndex(db, historyCollection, "task_id_version", bson.D{{Key: "task_id", Value: 1}, {Key: "version", Value: 1}},
+				options.Index().SetUnique(true))
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db, historyCollection, "task_id_version")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "audit log task and unique sequence indexes",
+		Up: func(db *mongo.Database) error {
+			if err := createIndex(db, auditCollection, "task_id", bson.D{{Key: "task_id", Value: 1}}, options.Index()); err != nil {
+				return err
+			}
+			return createIndex(db, auditCollection, "seq", bson.D{{Key: "seq", Value: 1}}, options.Index().SetUnique(true))
+		},
+		Down: func(db *mongo.Database) error {
+			if err := dropIndex(db, auditCollection, "seq"); err != nil {
+				return err
+			}
+			return dropIndex(db, auditCollection, "task_id")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "webhook deliveries lookup index",
+		Up: func(db *mongo.Database) error {
+			return createIndex(db, webhookDeliveryCollection, "webhook_id_status", bson.D{{Key: "webhook_id", Value: 1}, {Key: "status", Value: 1}}, options.Index())
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db, webhookDeliveryCollection, "webhook_id_status")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "task parent index",
+		Up: func(db *mongo.Database) error {
+			return createIndex(db, taskCollection, "parent_id", bson.D{{Key: "parent_id", Value: 1}}, options.Index().SetSparse(true))
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db, taskCollection, "parent_id")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "task due date index",
+		Up: func(db *mongo.Database) error {
+			return createIndex(db, taskCollection, "due", bson.D{{Key: "due", Value: 1}}, options.Index().SetSparse(true))
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db, taskCollection, "due")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "comments task index",
+		Up: func(db *mongo.Database) error {
+			return createIndex(db, commentCollection, "task_id_created_at", bson.D{{Key: "task_id", Value: 1}, {Key: "created_at", Value: 1}}, options.Index())
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db, commentCollection, "task_id_created_at")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "attachments task index",
+		Up: func(db *mongo.Database) error {
+			return createIndex(db, attachmentCollection, "task_id", bson.D{{Key: "task_id", Value: 1}}, options.Index())
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db, attachmentCollection, "task_id")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "task project and label indexes",
+		Up: func(db *mongo.Database) error {
+			err := createIndex(db, taskCollection, "project_id", bson.D{{Key: "project_id", Value: 1}}, options.Index().SetSparse(true))
+			if err != nil {
+				return err
+			}
+			return createIndex(db, taskCollection, "label_ids", bson.D{{Key: "label_ids", Value: 1}}, options.Index().SetSparse(true))
+		},
+		Down: func(db *mongo.Database) error {
+			if err := dropIndex(db, taskCollection, "label_ids"); err != nil {
+				return err
+			}
+			return dropIndex(db, taskCollection, "project_id")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "task assignees index",
+		Up: func(db *mongo.Database) error {
+			return createIndex(db, taskCollection, "assignee_ids", bson.D{{Key: "assignee_ids", Value: 1}}, options.Index().SetSparse(true))
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db, taskCollection, "assignee_ids")
+		},
+	},
+}
+
+// indexOptionsConflict is returned when an index on the same keys already
+// exists under another name, e.g. one created before migrations existed.
+const indexOptionsConflict = 85
+
+func createIndex(db *mongo.Database, collection, name string, keys bson.D, opts *options.IndexOptions) error {
+	_, err := db.Collection(collection).Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    keys,
+		Options: opts.SetName(name),
+	})
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == indexOptionsConflict {
+		return nil
+	}
+	return err
+}
+
+func dropIndex(db *mongo.Database, collection, name string) error {
+	_, err := db.Collection(collection).Indexes().DropOne(context.TODO(), name)
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
+
+func (db *DB) appliedMigrations() (map[int]appliedMigration, error) {
+	collection := db.db.Collection(migrationCollection)
+
+	cursor, err := collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []appliedMigration
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		return nil, err
+	}
+
+	applied := map[int]appliedMigration{}
+	for _, m := range results {
+		applied[m.Version] = m
+	}
+
+	return applied, nil
+}
+
+// MigrateUp applies every pending migration in order and returns the ones it
+// applied.
+func (db *DB) MigrateUp() ([]Migration, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range Migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		if err := m.Up(db.db); err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		_, err := db.db.Collection(migrationCollection).InsertOne(context.TODO(), appliedMigration{
+			Version:   m.Version,
+			Name:      m.Name,
+			AppliedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return done, err
+		}
+
+		log.Printf("Applied migration %d: %s", m.Version, m.Name)
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and
+// returns the ones it reverted.
+func (db *DB) MigrateDown(steps int) ([]Migration, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := Migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		if err := m.Down(db.db); err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		_, err := db.db.Collection(migrationCollection).DeleteOne(context.TODO(), bson.M{"_id": m.Version})
+		if err != nil {
+			return done, err
+		}
+
+		log.Printf("Reverted migration %d: %s", m.Version, m.Name)
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+func (db *DB) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []MigrationStatus{}
+	for _, m := range Migrations {
+		a, ok := applied[m.Version]
+		results = append(results, MigrationStatus{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   ok,
+			AppliedAt: a.AppliedAt,
+		})
+	}
+
+	return results, nil
+}
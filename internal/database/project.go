@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Project groups tasks. A task belongs to at most one project.
+type Project struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Label tags tasks across projects. A task can have any number of labels.
+type Label struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Color     string             `bson:"color,omitempty" json:"color,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+const (
+	projectCollection = "projects"
+	labelCollection   = "labels"
+)
+
+func (db *DB) InsertProject(project Project) error {
+	collection := db.db.Collection(projectCollection)
+
+	_, err := collection.InsertOne(context.TODO(), project)
+
+	if err != nil {
+		log.Println("Error Insert Project: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) GetProject(projectID string) (Project, error) {
+	collection := db.db.Collection(projectCollection)
+
+	id, err := primitive.ObjectIDFromHex(projectID)
+	if err != nil {
+		return Project{}, err
+	}
+
+	var project Project
+	err = collection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&project)
+
+	return project, err
+}
+
+// GetProjects returns every project, ordered by name.
+func (db *DB) GetProjects() ([]Project, error) {
+	collection := db.db.Collection(projectCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(context.TODO(), bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Project{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
+
+func (db *DB) UpdateProject(project Project) error {
+	collection := db.db.Collection(projectCollection)
+
+	update := bson.M{"$set": bson.M{"name": project.Name, "description": project.Description, "updated_at": project.UpdatedAt}}
+
+	_, err := collection.UpdateByID(context.TODO(), project.ID, update)
+
+	if err != nil {
+		log.Println("Error Update Project: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) DeleteProject(projectID string) (int64, error) {
+	collection := db.db.Collection(projectCollection)
+
+	id, err := primitive.ObjectIDFromHex(projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func (db *DB) InsertLabel(label Label) error {
+	collection := db.db.Collection(labelCollection)
+
+	_, err := collection.InsertOne(context.TODO(), label)
+
+	if err != nil {
+		log.Println("Error Insert Label: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) GetLabel(labelID string) (Label, error) {
+	collection := db.db.Collection(labelCollection)
+
+	id, err := primitive.ObjectIDFromHex(labelID)
+	if err != nil {
+		return Label{}, err
+	}
+
+	var label Label
+	err = collection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&label)
+
+	return label, err
+}
+
+// GetLabels returns every label, ordered by name.
+func (db *DB) GetLabels() ([]Label, error) {
+	collection := db.db.Collection(labelCollection)
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := collection.Find(context.TODO(), bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Label{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
+
+func (db *DB) UpdateLabel(label Label) error {
+	collection := db.db.Collection(labelCollection)
+
+	update := bson.M{"$set": bson.M{"name": label.Name, "color": label.Color, "updated_at": label.UpdatedAt}}
+
+	_, err := collection.UpdateByID(context.TODO(), label.ID, update)
+
+	if err != nil {
+		log.Println("Error Update Label: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) DeleteLabel(labelID string) (int64, error) {
+	collection := db.db.Collection(labelCollection)
+
+	id, err := primitive.ObjectIDFromHex(labelID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
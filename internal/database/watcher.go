@@ -0,0 +1,262 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TaskChangeOperation string
+
+const (
+	TaskInserted TaskChangeOperation = "insert"
+	TaskReplaced TaskChangeOperation = "replace"
+	TaskModified TaskChangeOperation = "update"
+	TaskRemoved  TaskChangeOperation = "delete"
+)
+
+// TaskChange is a write to the tasks collection made by another instance.
+// Task is the current document and is nil for deletions.
+type TaskChange struct {
+	Operation TaskChangeOperation
+	TaskID    string
+	Task      *Task
+}
+
+// processID identifies this process in the documents it writes, so its own
+// changes can be told apart from those of every other process in the change
+// stream, including a command run in the same container.
+var processID = primitive.NewObjectID().Hex()
+
+const (
+	originField           = "_origin"
+	resumeTokenCollection = "change_stream_tokens"
+	watchRetryDelay       = 5 * time.Second
+	localDeleteTTL        = time.Minute
+
+	// Server error codes for a resume token that can no longer be used.
+	changeStreamHistoryLost = 286
+	changeStreamFatalError  = 280
+)
+
+// taskDocument is the stored form of a task, stamped by the write that last
+// changed it; see originStamp.
+type taskDocument struct {
+	Task   `bson:",inline"`
+	Origin string `bson:"_origin,omitempty"`
+}
+
+type changeEvent struct {
+	OperationType TaskChangeOperation `bson:"operationType"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument      *taskDocument `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+type resumeToken struct {
+	ID        string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// localDeletes remembers tasks this instance is deleting. A delete event only
+// carries the document key, so there is no origin stamp to check.
+type localDeletes struct {
+	mu  sync.Mutex
+	ids map[string]time.Time
+}
+
+func (l *localDeletes) add(taskID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.ids == nil {
+		l.ids = map[string]time.Time{}
+	}
+
+	now := time.Now()
+	for id, at := range l.ids {
+		if now.Sub(at) > localDeleteTTL {
+			delete(l.ids, id)
+		}
+	}
+	l.ids[taskID] = now
+}
+
+func (l *localDeletes) consume(taskID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, ok := l.ids[taskID]
+	delete(l.ids, taskID)
+	return ok
+}
+
+// originStamp is the _origin value of a task write by this process: the
+// process ID and a fresh write ID. Because it differs on every write, an
+// update always changes the field, so it is always among the updatedFields of
+// the change event. Only changed fields are listed there, so a plain process
+// ID that was already stored would be missing.
+func originStamp() string {
+	return processID + "/" + primitive.NewObjectID().Hex()
+}
+
+// stampedBy returns the process that made the write stamped with stamp.
+func stampedBy(stamp string) string {
+	if i := strings.LastIndex(stamp, "/"); i >= 0 {
+		return stamp[:i]
+	}
+	return stamp
+}
+
+// WatchTasks follows the change stream of the tasks collection and calls
+// handle for every change made by another process, until ctx is cancelled.
+// The resume token is persisted under instanceID after each event so a
+// restarted replica picks up where it left off; instanceID must therefore stay
+// the same across restarts and differ between replicas. Change streams need
+// MongoDB to run as a replica set.
+func (db *DB) WatchTasks(ctx context.Context, instanceID string, handle func(TaskChange)) error {
+	for {
+		err := db.watchTasksOnce(ctx, instanceID, handle)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var serverErr mongo.ServerError
+		if errors.As(err, &serverErr) &&
+			(serverErr.HasErrorCode(changeStreamHistoryLost) || serverErr.HasErrorCode(changeStreamFatalError)) {
+			log.Println("Change stream resume token expired, restarting from now: ", err)
+			if err := db.deleteResumeToken(instanceID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		log.Println("Error Watching Tasks, retrying: ", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+func (db *DB) watchTasksOnce(ctx context.Context, instanceID string, handle func(TaskChange)) error {
+	collection := db.db.Collection(taskCollection)
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	token, err := db.loadResumeToken(instanceID)
+	if err != nil {
+		return err
+	}
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+
+	stream, err := collection.Watch(ctx, mongo.Pipeline{}, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.TODO())
+
+	for stream.Next(ctx) {
+		var event changeEvent
+		if err := stream.Decode(&event); err != nil {
+			log.Println("Error Decode Change Event: ", err)
+		} else if change, ok := db.remoteChange(event); ok {
+			handle(change)
+		}
+
+		if err := db.saveResumeToken(instanceID, stream.ResumeToken()); err != nil {
+			log.Println("Error Save Resume Token: ", err)
+		}
+	}
+
+	return stream.Err()
+}
+
+// remoteChange converts a change event, reporting false for changes made by
+// this process and for operations that do not affect a single task. The
+// origin of an update is read from its updatedFields rather than from the
+// looked up full document, which may already hold a later write.
+func (db *DB) remoteChange(event changeEvent) (TaskChange, bool) {
+	change := TaskChange{
+		Operation: event.OperationType,
+		TaskID:    event.DocumentKey.ID.Hex(),
+	}
+
+	var stamp string
+
+	switch event.OperationType {
+	case TaskInserted, TaskReplaced:
+		if event.FullDocument != nil {
+			stamp = event.FullDocument.Origin
+		}
+	case TaskModified:
+		stamp, _ = event.UpdateDescription.UpdatedFields[originField].(string)
+	case TaskRemoved:
+		if db.deletes.consume(change.TaskID) {
+			return change, false
+		}
+	default:
+		return change, false
+	}
+
+	if stamp != "" && stampedBy(stamp) == processID {
+		return change, false
+	}
+
+	if event.FullDocument != nil && event.OperationType != TaskRemoved {
+		task := event.FullDocument.Task
+		change.Task = &task
+	}
+
+	return change, true
+}
+
+func (db *DB) loadResumeToken(instanceID string) (bson.Raw, error) {
+	collection := db.db.Collection(resumeTokenCollection)
+
+	var token resumeToken
+	err := collection.FindOne(context.TODO(), bson.M{"_id": instanceID}).Decode(&token)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	return token.Token, err
+}
+
+func (db *DB) saveResumeToken(instanceID string, token bson.Raw) error {
+	collection := db.db.Collection(resumeTokenCollection)
+
+	_, err := collection.ReplaceOne(context.TODO(),
+		bson.M{"_id": instanceID},
+		resumeToken{ID: instanceID, Token: token, UpdatedAt: time.Now().UTC()},
+		options.Replace().SetUpsert(true))
+
+	return err
+}
+
+func (db *DB) deleteResumeToken(instanceID string) error {
+	collection := db.db.Collection(resumeTokenCollection)
+
+	_, err := collection.DeleteOne(context.TODO(), bson.M{"_id": instanceID})
+
+	return err
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newChangeEvent builds the event a change stream sends for a write stamped
+// with stamp. Like the server, it lists only the fields the write changed in
+// the updatedFields of an update.
+func newChangeEvent(op TaskChangeOperation, stamp string, updated bson.M) changeEvent {
+	event := changeEvent{OperationType: op}
+	event.DocumentKey.ID = primitive.NewObjectID()
+
+	if op != TaskRemoved {
+		event.FullDocument = &taskDocument{Task: Task{ID: event.DocumentKey.ID, Name: "Test Task"}, Origin: stamp}
+	}
+	if op == TaskModified {
+		event.UpdateDescription.UpdatedFields = updated
+	}
+
+	return event
+}
+
+func TestRemoteChange(t *testing.T) {
+	db := &DB{}
+	other := "other-replica/" + primitive.NewObjectID().Hex()
+
+	for _, op := range []TaskChangeOperation{TaskInserted, TaskModified, TaskReplaced} {
+		local := originStamp()
+		_, ok := db.remoteChange(newChangeEvent(op, local, bson.M{"name": "Test Task", originField: local}))
+		assert.False(t, ok, op)
+
+		change, ok := db.remoteChange(newChangeEvent(op, other, bson.M{"name": "Test Task", originField: other}))
+		assert.True(t, ok, op)
+		assert.Equal(t, op, change.Operation)
+		assert.Equal(t, "Test Task", change.Task.Name)
+	}
+
+	_, ok := db.remoteChange(newChangeEvent("drop", "", nil))
+	assert.False(t, ok)
+}
+
+func TestRemoteChange_Update(t *testing.T) {
+	db := &DB{}
+
+	// Writing values that are already stored changes only the stamp, which
+	// is fresh on every write.
+	first, second := originStamp(), originStamp()
+	assert.NotEqual(t, first, second)
+
+	_, ok := db.remoteChange(newChangeEvent(TaskModified, first, bson.M{originField: second}))
+	assert.False(t, ok, "own write of unchanged values")
+
+	// The looked up document may already hold a later write by another
+	// replica; the origin comes from the fields this update changed.
+	later := "other-replica/" + primitive.NewObjectID().Hex()
+	_, ok = db.remoteChange(newChangeEvent(TaskModified, later, bson.M{"reminded_at": "2024-01-01", originField: second}))
+	assert.False(t, ok, "own write looked up after a remote one")
+
+	_, ok = db.remoteChange(newChangeEvent(TaskModified, second, bson.M{"reminded_at": "2024-01-01", originField: later}))
+	assert.True(t, ok, "remote write looked up after an own one")
+
+	_, ok = db.remoteChange(newChangeEvent(TaskModified, "", bson.M{"name": "Edited"}))
+	assert.True(t, ok, "unstamped write, e.g. from the mongo shell")
+}
+
+func TestRemoteChange_Delete(t *testing.T) {
+	db := &DB{}
+
+	local := newChangeEvent(TaskRemoved, "", nil)
+	db.deletes.add(local.DocumentKey.ID.Hex())
+
+	_, ok := db.remoteChange(local)
+	assert.False(t, ok)
+
+	// The local delete is consumed, so a later delete of the same ID, e.g. by
+	// another replica after the task was recreated, is passed through.
+	change, ok := db.remoteChange(local)
+	assert.True(t, ok)
+	assert.Nil(t, change.Task)
+}
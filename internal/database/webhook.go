@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Webhook struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL       string             `bson:"url" json:"url"`
+	Events    []string           `bson:"events" json:"events"`
+	Secret    string             `bson:"secret" json:"-"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+type WebhookDelivery struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WebhookID    string             `bson:"webhook_id" json:"webhook_id"`
+	EventID      string             `bson:"event_id" json:"event_id"`
+	EventType    string             `bson:"event_type" json:"event_type"`
+	Payload      string             `bson:"payload" json:"payload"`
+	Status       DeliveryStatus     `bson:"status" json:"status"`
+	Attempts     int                `bson:"attempts" json:"attempts"`
+	ResponseCode int                `bson:"response_code,omitempty" json:"response_code,omitempty"`
+	Error        string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	CompletedAt  time.Time          `bson:"completed_at" json:"completed_at"`
+}
+
+const (
+	webhookCollection         = "webhooks"
+	webhookDeliveryCollection = "webhook_deliveries"
+)
+
+func (db *DB) InsertWebhook(webhook Webhook) error {
+	collection := db.db.Collection(webhookCollection)
+
+	_, err := collection.InsertOne(context.TODO(), webhook)
+
+	if err != nil {
+		log.Println("Error Insert Webhook: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) GetWebhooks() ([]Webhook, error) {
+	collection := db.db.Collection(webhookCollection)
+
+	cursor, err := collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Webhook{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
+
+func (db *DB) DeleteWebhookByID(webhookID string) (int64, error) {
+	collection := db.db.Collection(webhookCollection)
+
+	id, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return 0, err
+	}
+
+	deletedResult, err := collection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		return 0, err
+	}
+
+	return deletedResult.DeletedCount, nil
+}
+
+func (db *DB) InsertWebhookDelivery(delivery WebhookDelivery) error {
+	collection := db.db.Collection(webhookDeliveryCollection)
+
+	_, err := collection.InsertOne(context.TODO(), delivery)
+
+	if err != nil {
+		log.Println("Error Insert Webhook Delivery: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries lists deliveries newest first. Empty arguments match
+// every webhook or every status.
+func (db *DB) GetWebhookDeliveries(webhookID string, status DeliveryStatus) ([]WebhookDelivery, error) {
+	collection := db.db.Collection(webhookDeliveryCollection)
+
+	filter := bson.M{}
+	if webhookID != "" {
+		filter["webhook_id"] = webhookID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []WebhookDelivery{}
+	err = cursor.All(context.TODO(), &results)
+
+	return results, err
+}
@@ -0,0 +1,218 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Type string
+
+const (
+	TaskCreated Type = "task.created"
+	TaskUpdated Type = "task.updated"
+	TaskDeleted Type = "task.deleted"
+)
+
+var Types = []Type{TaskCreated, TaskUpdated, TaskDeleted}
+
+type TaskPayload struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Status      int        `json:"status"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	BlockedBy   []string   `json:"blocked_by,omitempty"`
+	Due         *time.Time `json:"due,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	ProjectID   string     `json:"project_id,omitempty"`
+	LabelIDs    []string   `json:"label_ids,omitempty"`
+	AssigneeIDs []string   `json:"assignee_ids,omitempty"`
+	WatcherIDs  []string   `json:"watcher_ids,omitempty"`
+}
+
+type Event struct {
+	ID        string       `json:"id"`
+	Seq       uint64       `json:"seq"`
+	Type      Type         `json:"type"`
+	TaskID    string       `json:"task_id"`
+	Task      *TaskPayload `json:"task,omitempty"`
+	Actor     string       `json:"actor,omitempty"`
+	Timestamp time.Time    `json:"timestamp"`
+
+	// Remote is set on events that another replica published, received
+	// through the MongoDB change stream.
+	Remote bool `json:"remote,omitempty"`
+}
+
+// NewTaskEvent builds an event for a task mutation. task is the state after
+// the change, or the state before it for a deletion.
+func NewTaskEvent(eventType Type, taskID string, task *database.Task, actor string) Event {
+	e := Event{
+		ID:        primitive.NewObjectID().Hex(),
+		Type:      eventType,
+		TaskID:    taskID,
+		Actor:     actor,
+		Timestamp: time.Now().UTC(),
+	}
+
+	if task != nil {
+		e.Task = &TaskPayload{
+			ID:          taskID,
+			Name:        task.Name,
+			Status:      task.Status,
+			ParentID:    task.ParentID,
+			BlockedBy:   task.BlockedBy,
+			Due:         task.Due,
+			Recurrence:  task.Recurrence,
+			ProjectID:   task.ProjectID,
+			LabelIDs:    task.LabelIDs,
+			AssigneeIDs: task.AssigneeIDs,
+			WatcherIDs:  task.WatcherIDs,
+		}
+	}
+
+	return e
+}
+
+// Bus fans events out to in-process subscribers. Every published event is
+// numbered and the most recent ones are kept so that a subscriber can resume
+// from the last sequence number it saw. Publishing never blocks: a subscriber
+// whose buffer is full misses the event, which is logged and counted.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[int]subscriber
+	nextID      int
+
+	seq         uint64
+	history     []Event
+	historySize int
+	dropped     uint64
+}
+
+type subscriber struct {
+	ch      chan Event
+	dropped func(Event)
+}
+
+const DefaultHistorySize = 1000
+
+// Default is the bus the task controller publishes to.
+var Default = NewBus(DefaultHistorySize)
+
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		subscribers: map[int]subscriber{},
+		historySize: historySize,
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.Seq = b.seq
+
+	if b.historySize > 0 {
+		if len(b.history) == b.historySize {
+			b.history = b.history[1:]
+		}
+		b.history = append(b.history, e)
+	}
+
+	for id, s := range b.subscribers {
+		select {
+		case s.ch <- e:
+		default:
+			b.dropped++
+			log.Printf("Event bus subscriber %d is full, dropping event %s (%d dropped so far)", id, e.ID, b.dropped)
+			if s.dropped != nil {
+				s.dropped(e)
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscriber with the given buffer size. The returned
+// function unsubscribes and closes the channel.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	return b.SubscribeDropped(buffer, nil)
+}
+
+// SubscribeDropped is Subscribe, also calling dropped with every event the
+// subscriber misses because its buffer is full. dropped runs with the bus
+// locked, so it must return quickly and must not publish.
+func (b *Bus) SubscribeDropped(buffer int, dropped func(Event)) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.subscribe(buffer, dropped)
+}
+
+// SubscribeSince registers a subscriber and returns the buffered events
+// published after seq. complete is false when the replay has a gap, either
+// because events were evicted from the history or because seq comes from a
+// previous run of the process.
+func (b *Bus) SubscribeSince(seq uint64, buffer int) (missed []Event, complete bool, events <-chan Event, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if seq > b.seq {
+		seq = 0
+		complete = false
+	}
+
+	if len(b.history) > 0 && b.history[0].Seq > seq+1 {
+		complete = false
+	} else if len(b.history) == 0 && b.seq > seq {
+		complete = false
+	}
+
+	for _, e := range b.history {
+		if e.Seq > seq {
+			missed = append(missed, e)
+		}
+	}
+
+	events, unsubscribe = b.subscribe(buffer, nil)
+	return missed, complete, events, unsubscribe
+}
+
+func (b *Bus) subscribe(buffer int, dropped func(Event)) (<-chan Event, func()) {
+	id := b.nextID
+	b.nextID++
+
+	ch := make(chan Event, buffer)
+	b.subscribers[id] = subscriber{ch: ch, dropped: dropped}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Seq returns the sequence number of the last published event.
+func (b *Bus) Seq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.seq
+}
+
+// Dropped returns how many events subscribers have missed because their
+// buffer was full.
+func (b *Bus) Dropped() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dropped
+}
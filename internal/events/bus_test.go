@@ -0,0 +1,101 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus(0)
+
+	first, unsubscribeFirst := bus.Subscribe(1)
+	second, unsubscribeSecond := bus.Subscribe(1)
+	defer unsubscribeSecond()
+
+	e := NewTaskEvent(TaskCreated, "abc", &database.Task{Name: "Test Task"}, "alice")
+	bus.Publish(e)
+	e.Seq = 1
+
+	assert.Equal(t, e, <-first)
+	assert.Equal(t, e, <-second)
+	assert.Equal(t, "Test Task", e.Task.Name)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+
+	_, open := <-first
+	assert.False(t, open)
+}
+
+func TestBus_PublishDoesNotBlock(t *testing.T) {
+	bus := NewBus(0)
+
+	ch, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	bus.Publish(NewTaskEvent(TaskUpdated, "a", nil, ""))
+	bus.Publish(NewTaskEvent(TaskDeleted, "b", nil, ""))
+
+	e := <-ch
+	assert.Equal(t, "a", e.TaskID)
+	assert.Nil(t, e.Task)
+	assert.Len(t, ch, 0)
+	assert.Equal(t, uint64(1), bus.Dropped())
+}
+
+func TestBus_SubscribeDropped(t *testing.T) {
+	bus := NewBus(0)
+
+	var dropped []string
+	ch, unsubscribe := bus.SubscribeDropped(1, func(e Event) {
+		dropped = append(dropped, e.TaskID)
+	})
+	defer unsubscribe()
+
+	bus.Publish(NewTaskEvent(TaskCreated, "a", nil, ""))
+	bus.Publish(NewTaskEvent(TaskCreated, "b", nil, ""))
+	bus.Publish(NewTaskEvent(TaskCreated, "c", nil, ""))
+
+	assert.Equal(t, "a", (<-ch).TaskID)
+	assert.Equal(t, []string{"b", "c"}, dropped)
+	assert.Equal(t, uint64(2), bus.Dropped())
+}
+
+func TestBus_SubscribeSince(t *testing.T) {
+	bus := NewBus(3)
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(NewTaskEvent(TaskCreated, "abc", nil, ""))
+	}
+	assert.Equal(t, uint64(5), bus.Seq())
+
+	missed, complete, ch, unsubscribe := bus.SubscribeSince(3, 1)
+	defer unsubscribe()
+
+	assert.True(t, complete)
+	assert.Len(t, missed, 2)
+	assert.Equal(t, uint64(4), missed[0].Seq)
+
+	bus.Publish(NewTaskEvent(TaskDeleted, "abc", nil, ""))
+	assert.Equal(t, uint64(6), (<-ch).Seq)
+}
+
+func TestBus_SubscribeSinceGap(t *testing.T) {
+	bus := NewBus(3)
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(NewTaskEvent(TaskCreated, "abc", nil, ""))
+	}
+
+	missed, complete, _, unsubscribe := bus.SubscribeSince(1, 1)
+	unsubscribe()
+	assert.False(t, complete)
+	assert.Len(t, missed, 3)
+
+	missed, complete, _, unsubscribe = bus.SubscribeSince(42, 1)
+	unsubscribe()
+	assert.False(t, complete)
+	assert.Len(t, missed, 3)
+}
@@ -0,0 +1,46 @@
+package events
+
+import "slices"
+
+// Filter selects the events a subscriber is interested in. Empty fields match
+// everything. A deletion is filtered by the task as it was before; one made on
+// another replica carries no task, so it only matches filters without a
+// status, labels or assignees.
+type Filter struct {
+	Status  *int     `json:"status,omitempty"`
+	TaskIDs []string `json:"task_ids,omitempty"`
+	Types   []Type   `json:"types,omitempty"`
+	// LabelIDs and AssigneeIDs select the tasks that have every one of the
+	// labels and assignees.
+	LabelIDs    []string `json:"label_ids,omitempty"`
+	AssigneeIDs []string `json:"assignee_ids,omitempty"`
+}
+
+func (f Filter) Matches(e Event) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, e.Type) {
+		return false
+	}
+
+	if len(f.TaskIDs) > 0 && !slices.Contains(f.TaskIDs, e.TaskID) {
+		return false
+	}
+
+	if e.Task == nil {
+		return f.Status == nil && len(f.LabelIDs) == 0 && len(f.AssigneeIDs) == 0
+	}
+
+	if f.Status != nil && e.Task.Status != *f.Status {
+		return false
+	}
+
+	return containsAll(e.Task.LabelIDs, f.LabelIDs) && containsAll(e.Task.AssigneeIDs, f.AssigneeIDs)
+}
+
+func containsAll(values, wanted []string) bool {
+	for _, v := range wanted {
+		if !slices.Contains(values, v) {
+			return false
+		}
+	}
+	return true
+}
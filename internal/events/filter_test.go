@@ -0,0 +1,51 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	completed := 1
+
+	created := NewTaskEvent(TaskCreated, "a", &database.Task{Status: 0}, "")
+	updated := NewTaskEvent(TaskUpdated, "b", &database.Task{Status: 1}, "")
+	deleted := NewTaskEvent(TaskDeleted, "a", &database.Task{Status: 1, LabelIDs: []string{"bug"}}, "")
+	remoteDeleted := NewRemoteTaskEvent(database.TaskChange{Operation: database.TaskRemoved, TaskID: "a"})
+
+	assert.True(t, Filter{}.Matches(created))
+
+	byStatus := Filter{Status: &completed}
+	assert.False(t, byStatus.Matches(created))
+	assert.True(t, byStatus.Matches(updated))
+	assert.True(t, byStatus.Matches(deleted))
+	assert.False(t, Filter{Status: new(int)}.Matches(deleted))
+	assert.False(t, byStatus.Matches(remoteDeleted))
+
+	byID := Filter{TaskIDs: []string{"a"}}
+	assert.True(t, byID.Matches(created))
+	assert.False(t, byID.Matches(updated))
+	assert.True(t, byID.Matches(deleted))
+	assert.True(t, byID.Matches(remoteDeleted))
+
+	byType := Filter{Types: []Type{TaskDeleted}}
+	assert.False(t, byType.Matches(created))
+	assert.True(t, byType.Matches(deleted))
+
+	labeled := NewTaskEvent(TaskUpdated, "c", &database.Task{LabelIDs: []string{"bug", "urgent"}, AssigneeIDs: []string{"alice"}}, "")
+
+	byLabel := Filter{LabelIDs: []string{"urgent", "bug"}}
+	assert.True(t, byLabel.Matches(labeled))
+	assert.False(t, byLabel.Matches(created))
+	assert.False(t, byLabel.Matches(deleted))
+	assert.True(t, Filter{LabelIDs: []string{"bug"}}.Matches(deleted))
+	assert.False(t, Filter{LabelIDs: []string{"bug"}}.Matches(remoteDeleted))
+	assert.False(t, Filter{LabelIDs: []string{"bug", "docs"}}.Matches(labeled))
+
+	byAssignee := Filter{AssigneeIDs: []string{"alice"}}
+	assert.True(t, byAssignee.Matches(labeled))
+	assert.False(t, byAssignee.Matches(updated))
+	assert.False(t, byAssignee.Matches(deleted))
+}
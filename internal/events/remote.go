@@ -0,0 +1,19 @@
+package events
+
+import "github.com/tiffany831101/bs_pretest.git/internal/database"
+
+// NewRemoteTaskEvent builds an event for a change another replica made.
+func NewRemoteTaskEvent(change database.TaskChange) Event {
+	eventType := TaskUpdated
+	switch change.Operation {
+	case database.TaskInserted:
+		eventType = TaskCreated
+	case database.TaskRemoved:
+		eventType = TaskDeleted
+	}
+
+	e := NewTaskEvent(eventType, change.TaskID, change.Task, "")
+	e.Remote = true
+
+	return e
+}
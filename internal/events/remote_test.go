@@ -0,0 +1,23 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+)
+
+func TestNewRemoteTaskEvent(t *testing.T) {
+	for op, eventType := range map[database.TaskChangeOperation]Type{
+		database.TaskInserted: TaskCreated,
+		database.TaskModified: TaskUpdated,
+		database.TaskReplaced: TaskUpdated,
+		database.TaskRemoved:  TaskDeleted,
+	} {
+		e := NewRemoteTaskEvent(database.TaskChange{Operation: op, TaskID: "abc"})
+
+		assert.Equal(t, eventType, e.Type)
+		assert.Equal(t, "abc", e.TaskID)
+		assert.True(t, e.Remote)
+	}
+}
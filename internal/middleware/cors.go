@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+const (
+	corsAllowMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders = "Content-Type, Authorization, " + RequestIDHeader + ", " + ActorHeader + ", " + IdempotencyKeyHeader
+)
+
+// CORS allows browser requests from the origins in runtime.cors.origins and
+// answers preflight requests. The allowed origins are read on every request,
+// so config reloads apply immediately.
+func CORS(live *config.Live) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if !OriginAllowed(live, origin) {
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Expose-Headers", RequestIDHeader+", "+IdempotencyReplayedHeader)
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", corsAllowMethods)
+			c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// OriginAllowed reports whether runtime.cors.origins lets browser pages from
+// origin call the API.
+func OriginAllowed(live *config.Live, origin string) bool {
+	origins := live.Config().Runtime.CORS.Origins
+	return slices.Contains(origins, "*") || slices.Contains(origins, origin)
+}
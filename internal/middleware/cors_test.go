@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+func newCORSEngine(origins ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	live := config.NewLive(&config.Config{Runtime: config.RuntimeConfig{
+		CORS: config.CORSConfig{Origins: origins},
+	}})
+
+	r := gin.New()
+	r.Use(CORS(live))
+	r.GET("/tasks", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return r
+}
+
+func TestCORS_AllowedOrigin(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	newCORSEngine("https://app.example.com").ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_DisallowedOrigin(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	newCORSEngine("https://app.example.com").ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("OPTIONS", "/tasks", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	newCORSEngine("*").ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://any.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), IdempotencyKeyHeader)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+// Feature answers 404 for the paths under prefix while the named flag of
+// runtime.features is off. The flag is read on every request, so config
+// reloads apply immediately.
+func Feature(live *config.Live, name, prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, prefix) && !live.Config().FeatureEnabled(name) {
+			AbortWithProblem(c, http.StatusNotFound, "Resource Not Found")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+func TestFeature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Runtime: config.RuntimeConfig{Features: map[string]bool{"graphql": false}}}
+
+	r := gin.New()
+	r.Use(Feature(config.NewLive(cfg), "graphql", "/graphql"))
+	r.GET("/graphql", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, get("/graphql"))
+	assert.Equal(t, http.StatusOK, get("/ping"))
+
+	cfg.Runtime.Features["graphql"] = true
+	assert.Equal(t, http.StatusOK, get("/graphql"))
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	// IdempotencyInFlightError is the error of the 409 answered while the
+	// first request with the same key is still running. It is the only 409
+	// worth retrying.
+	IdempotencyInFlightError = "Request with this Idempotency-Key is being processed, retry later"
+)
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key. Keys are scoped to the actor, and reusing a key for a
+// different method, path or body is rejected with 422. Requests without the
+// header pass through untouched.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			AbortWithProblem(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := database.IdempotencyRecord{
+			Key:         Actor(c) + ":" + key,
+			Fingerprint: fingerprint(c.Request.Method, c.Request.URL.Path, body),
+			CreatedAt:   time.Now().UTC(),
+		}
+
+		err = database.MongoDB.InsertIdempotencyRecord(record)
+
+		if errors.Is(err, database.ErrIdempotencyKeyExists) {
+			replay(c, record)
+			return
+		}
+
+		if err != nil {
+			AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// The record is settled even when the handler panics, which would
+		// otherwise leave the key in flight until it expires. The panic is
+		// then passed on to the recovery middleware.
+		defer func() {
+			p := recover()
+
+			// Server errors and panics are not cached so the client can
+			// retry them.
+			if p != nil || c.Writer.Status() >= http.StatusInternalServerError {
+				if err := database.MongoDB.DeleteIdempotencyRecord(record.Key); err != nil {
+					log.Println("Error Release Idempotency Key: ", err)
+				}
+			} else {
+				record.Completed = true
+				record.StatusCode = recorder.Status()
+				record.ContentType = recorder.Header().Get("Content-Type")
+				record.Location = recorder.Header().Get("Location")
+				record.Body = recorder.body.Bytes()
+
+				if err := database.MongoDB.SaveIdempotencyRecord(record); err != nil {
+					log.Println("Error Save Idempotency Record: ", err)
+				}
+			}
+
+			if p != nil {
+				panic(p)
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+func replay(c *gin.Context, record database.IdempotencyRecord) {
+	stored, err := database.MongoDB.GetIdempotencyRecord(record.Key)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// The key expired or was released between the insert and this lookup.
+		AbortWithProblem(c, http.StatusConflict, IdempotencyInFlightError)
+		return
+	}
+
+	if err != nil {
+		AbortWithProblem(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if stored.Fingerprint != record.Fingerprint {
+		AbortWithProblem(c, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+		return
+	}
+
+	if !stored.Completed {
+		AbortWithProblem(c, http.StatusConflict, IdempotencyInFlightError)
+		return
+	}
+
+	c.Header(IdempotencyReplayedHeader, "true")
+	if stored.Location != "" {
+		c.Header("Location", stored.Location)
+	}
+	c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+	c.Abort()
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
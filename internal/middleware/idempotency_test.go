@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type idempotencyMockDB struct {
+	database.DBInterface
+	records map[string]database.IdempotencyRecord
+}
+
+func (db *idempotencyMockDB) InsertIdempotencyRecord(record database.IdempotencyRecord) error {
+	if _, ok := db.records[record.Key]; ok {
+		return database.ErrIdempotencyKeyExists
+	}
+	db.records[record.Key] = record
+	return nil
+}
+
+func (db *idempotencyMockDB) GetIdempotencyRecord(key string) (database.IdempotencyRecord, error) {
+	record, ok := db.records[key]
+	if !ok {
+		return record, mongo.ErrNoDocuments
+	}
+	return record, nil
+}
+
+func (db *idempotencyMockDB) SaveIdempotencyRecord(record database.IdempotencyRecord) error {
+	db.records[record.Key] = record
+	return nil
+}
+
+func (db *idempotencyMockDB) DeleteIdempotencyRecord(key string) error {
+	delete(db.records, key)
+	return nil
+}
+
+func newIdempotencyEngine(status int) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+
+	r := gin.New()
+	r.Use(RequestContext())
+	r.POST("/tasks", Idempotency(), func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/tasks/1")
+		c.JSON(status, gin.H{"call": calls})
+	})
+	return r, &calls
+}
+
+func postWithKey(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	database.MongoDB = &idempotencyMockDB{records: map[string]database.IdempotencyRecord{}}
+	r, calls := newIdempotencyEngine(http.StatusCreated)
+
+	first := postWithKey(r, "k1", `{"name":"a"}`)
+	second := postWithKey(r, "k1", `{"name":"a"}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, "/tasks/1", second.Header().Get("Location"))
+}
+
+func TestIdempotency_DifferentPayload(t *testing.T) {
+	database.MongoDB = &idempotencyMockDB{records: map[string]database.IdempotencyRecord{}}
+	r, calls := newIdempotencyEngine(http.StatusCreated)
+
+	postWithKey(r, "k1", `{"name":"a"}`)
+	w := postWithKey(r, "k1", `{"name":"b"}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	mock := &idempotencyMockDB{records: map[string]database.IdempotencyRecord{}}
+	database.MongoDB = mock
+	r, calls := newIdempotencyEngine(http.StatusCreated)
+
+	mock.records["anonymous:k1"] = database.IdempotencyRecord{
+		Key:         "anonymous:k1",
+		Fingerprint: fingerprint("POST", "/tasks", []byte(`{}`)),
+	}
+	w := postWithKey(r, "k1", `{}`)
+
+	assert.Equal(t, 0, *calls)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestIdempotency_ServerErrorNotStored(t *testing.T) {
+	database.MongoDB = &idempotencyMockDB{records: map[string]database.IdempotencyRecord{}}
+	r, calls := newIdempotencyEngine(http.StatusInternalServerError)
+
+	postWithKey(r, "k1", `{}`)
+	postWithKey(r, "k1", `{}`)
+
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	database.MongoDB = &idempotencyMockDB{records: map[string]database.IdempotencyRecord{}}
+	gin.SetMode(gin.TestMode)
+	calls := 0
+
+	r := gin.New()
+	r.Use(gin.RecoveryWithWriter(io.Discard))
+	r.POST("/tasks", Idempotency(), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	first := postWithKey(r, "k1", `{}`)
+	second := postWithKey(r, "k1", `{}`)
+
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	database.MongoDB = &idempotencyMockDB{records: map[string]database.IdempotencyRecord{}}
+	r, calls := newIdempotencyEngine(http.StatusCreated)
+
+	postWithKey(r, "", `{}`)
+	postWithKey(r, "", `{}`)
+
+	assert.Equal(t, 2, *calls)
+}
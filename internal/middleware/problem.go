@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of error responses, see RFC 9457.
+const ProblemContentType = "application/problem+json"
+
+// Problem is the body of every error response. Type is always "about:blank",
+// so Title is the status text and Detail says what went wrong.
+type Problem struct {
+	Type   string `json:"type" example:"about:blank"`
+	Title  string `json:"title" example:"Bad Request"`
+	Status int    `json:"status" example:"400"`
+	Detail string `json:"detail" example:"Invalid Task ID, should be in hex format"`
+}
+
+// AbortWithProblem stops the handler chain and answers status with a problem
+// details body.
+func AbortWithProblem(c *gin.Context, status int, detail string) {
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbortWithProblem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		AbortWithProblem(c, http.StatusConflict, "task is blocked")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+	var p Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, Problem{Type: "about:blank", Title: "Conflict", Status: http.StatusConflict, Detail: "task is blocked"}, p)
+}
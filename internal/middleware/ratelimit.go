@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+const rateLimitIdleTTL = 10 * time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit applies a token bucket per client IP using the limits in
+// runtime.rateLimit. The limits are read on every request, so config reloads
+// apply immediately; a rate of 0 disables limiting.
+func RateLimit(live *config.Live) gin.HandlerFunc {
+	var mu sync.Mutex
+	buckets := map[string]*tokenBucket{}
+	lastPrune := time.Now()
+
+	return func(c *gin.Context) {
+		limit := live.Config().Runtime.RateLimit
+		if limit.RequestsPerSecond <= 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		burst := float64(limit.Burst)
+
+		mu.Lock()
+
+		if now.Sub(lastPrune) > rateLimitIdleTTL {
+			for ip, b := range buckets {
+				if now.Sub(b.last) > rateLimitIdleTTL {
+					delete(buckets, ip)
+				}
+			}
+			lastPrune = now
+		}
+
+		b, ok := buckets[c.ClientIP()]
+		if !ok {
+			b = &tokenBucket{tokens: burst, last: now}
+			buckets[c.ClientIP()] = b
+		}
+
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.RequestsPerSecond)
+		b.last = now
+
+		allowed := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+		wait := (1 - b.tokens) / limit.RequestsPerSecond
+
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait))))
+			AbortWithProblem(c, http.StatusTooManyRequests, "Too Many Requests")
+			return
+		}
+
+		c.Next()
+	}
+}
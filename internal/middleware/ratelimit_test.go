@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/config"
+)
+
+func newRateLimitEngine(limit config.RateLimitConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	live := config.NewLive(&config.Config{Runtime: config.RuntimeConfig{RateLimit: limit}})
+
+	r := gin.New()
+	r.Use(RateLimit(live))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return r
+}
+
+func getCodes(r *gin.Engine, n int) []int {
+	codes := []int{}
+	for i := 0; i < n; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		r.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+func TestRateLimit(t *testing.T) {
+	r := newRateLimitEngine(config.RateLimitConfig{RequestsPerSecond: 0.001, Burst: 2})
+
+	assert.Equal(t, []int{200, 200, 429}, getCodes(r, 3))
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	r := newRateLimitEngine(config.RateLimitConfig{})
+
+	assert.Equal(t, []int{200, 200, 200}, getCodes(r, 3))
+}
+
+func TestRateLimit_RetryAfter(t *testing.T) {
+	r := newRateLimitEngine(config.RateLimitConfig{RequestsPerSecond: 0.5, Burst: 1})
+	getCodes(r, 1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+}
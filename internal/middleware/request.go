@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	ActorHeader     = "X-User-ID"
+
+	requestIDKey = "requestID"
+	actorKey     = "actor"
+)
+
+// AnonymousActor is recorded as the caller of requests without X-User-ID.
+const AnonymousActor = "anonymous"
+
+// RequestContext tags every request with a request ID and the calling actor.
+// A client supplied X-Request-ID is kept, otherwise a new one is generated and
+// echoed back in the response headers.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = primitive.NewObjectID().Hex()
+		}
+
+		actor := c.GetHeader(ActorHeader)
+		if actor == "" {
+			actor = AnonymousActor
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Set(actorKey, actor)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// RequestID returns the request ID assigned by RequestContext.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Actor returns the caller assigned by RequestContext, or "anonymous" when the
+// middleware did not run.
+func Actor(c *gin.Context) string {
+	if actor := c.GetString(actorKey); actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
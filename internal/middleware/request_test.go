@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequestContextEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestContext())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, RequestID(c)+" "+Actor(c))
+	})
+	return r
+}
+
+func TestRequestContext_Defaults(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	newRequestContextEngine().ServeHTTP(w, req)
+
+	requestID := w.Header().Get(RequestIDHeader)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, requestID+" anonymous", w.Body.String())
+}
+
+func TestRequestContext_FromHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set(ActorHeader, "alice")
+	newRequestContextEngine().ServeHTTP(w, req)
+
+	assert.Equal(t, "req-1", w.Header().Get(RequestIDHeader))
+	assert.Equal(t, "req-1 alice", w.Body.String())
+}
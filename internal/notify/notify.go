@@ -0,0 +1,155 @@
+// Package notify sends notifications about tasks, such as due-date reminders,
+// through the channel selected in the notifications config.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/webhook"
+)
+
+const (
+	TaskDueSoon = "task.due_soon"
+	TaskOverdue = "task.overdue"
+
+	defaultTimeout = 10 * time.Second
+)
+
+type Notification struct {
+	Type      string    `json:"type"`
+	TaskID    string    `json:"task_id"`
+	Subject   string    `json:"subject"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	// Recipients are the IDs of the users the notification is for, such as
+	// the watchers of a task; none means whoever the channel reaches. The
+	// channels know no addresses for user IDs, so they pass them on: in the
+	// webhook payload, and above the message of a mail.
+	Recipients []string `json:"recipients,omitempty"`
+}
+
+// Notifier delivers a notification. An error means it was not delivered and
+// may be retried.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// New returns the Notifier for the configured channel.
+func New(cfg config.NotificationsConfig) (Notifier, error) {
+	switch cfg.Channel {
+	case "", "log":
+		return LogNotifier{}, nil
+	case "webhook":
+		return NewWebhookNotifier(cfg.Webhook.URL, cfg.Webhook.Secret), nil
+	case "smtp":
+		return NewSMTPNotifier(cfg.SMTP), nil
+	}
+	return nil, fmt.Errorf("unknown notification channel %q", cfg.Channel)
+}
+
+// LogNotifier writes notifications to the standard logger.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	if len(n.Recipients) > 0 {
+		log.Printf("Notification %s for task %s to %s: %s", n.Type, n.TaskID, strings.Join(n.Recipients, ", "), n.Subject)
+		return nil
+	}
+	log.Printf("Notification %s for task %s: %s", n.Type, n.TaskID, n.Subject)
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to a URL, signed like webhook
+// deliveries when a secret is set.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.EventHeader, n.Type)
+	if w.secret != "" {
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(w.secret, body))
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook responded %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// SMTPNotifier mails notifications. It authenticates only when a username is
+// set, which net/smtp allows over an unencrypted connection to localhost only.
+type SMTPNotifier struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+func NewSMTPNotifier(cfg config.SMTPConfig) *SMTPNotifier {
+	s := &SMTPNotifier{
+		addr: cfg.Host + ":" + strconv.Itoa(cfg.Port),
+		from: cfg.From,
+		to:   cfg.To,
+	}
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return s
+}
+
+// headerValue keeps a task name from breaking out of the Subject header.
+var headerValue = strings.NewReplacer("\r", " ", "\n", " ")
+
+func (s *SMTPNotifier) Notify(ctx context.Context, n Notification) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", headerValue.Replace(n.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.Timestamp.Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	if len(n.Recipients) > 0 {
+		fmt.Fprintf(&msg, "For: %s\r\n\r\n", strings.Join(n.Recipients, ", "))
+	}
+	msg.WriteString(n.Message)
+	msg.WriteString("\r\n")
+
+	return smtp.SendMail(s.addr, s.auth, s.from, s.to, []byte(msg.String()))
+}
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"github.com/tiffany831101/bs_pretest.git/internal/webhook"
+)
+
+var reminder = Notification{
+	Type:      TaskOverdue,
+	TaskID:    "65a000000000000000000001",
+	Subject:   `"write report" is overdue`,
+	Message:   "The task \"write report\" was due at 2024-01-01T09:00:00Z.",
+	Timestamp: time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+}
+
+func Test_New(t *testing.T) {
+	n, err := New(config.NotificationsConfig{Channel: "log"})
+	require.NoError(t, err)
+	assert.IsType(t, LogNotifier{}, n)
+
+	n, err = New(config.NotificationsConfig{Channel: "webhook", Webhook: config.WebhookNotifierConfig{URL: "http://localhost"}})
+	require.NoError(t, err)
+	assert.IsType(t, &WebhookNotifier{}, n)
+
+	_, err = New(config.NotificationsConfig{Channel: "pager"})
+	assert.Error(t, err)
+}
+
+func Test_WebhookNotifier(t *testing.T) {
+	var body []byte
+	var signature, event string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(webhook.SignatureHeader)
+		event = r.Header.Get(webhook.EventHeader)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL, "s3cret").Notify(context.Background(), reminder)
+
+	require.NoError(t, err)
+	assert.Equal(t, TaskOverdue, event)
+	assert.Equal(t, webhook.Sign("s3cret", body), signature)
+
+	var got Notification
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, reminder, got)
+}
+
+func Test_WebhookNotifierFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL, "").Notify(context.Background(), reminder)
+
+	assert.ErrorContains(t, err, "502")
+}
+
+// serveSMTP accepts one connection on a local port, speaks just enough SMTP to
+// take a message and sends the received data to the returned channel.
+func serveSMTP(t *testing.T) (int, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line + " ")[0]); verb {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 Go ahead")
+				data, _ := io.ReadAll(text.DotReader())
+				received <- string(data)
+				text.PrintfLine("250 Queued")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Unknown command")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, received
+}
+
+func Test_SMTPNotifier(t *testing.T) {
+	port, received := serveSMTP(t)
+
+	n := NewSMTPNotifier(config.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "tasks@example.com",
+		To:   []string{"alice@example.com"},
+	})
+
+	require.NoError(t, n.Notify(context.Background(), reminder))
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(<-received))).ReadMIMEHeader()
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", msg.Get("To"))
+	assert.Equal(t, reminder.Subject, msg.Get("Subject"))
+}
+
+func Test_SMTPNotifierRecipients(t *testing.T) {
+	port, received := serveSMTP(t)
+
+	n := NewSMTPNotifier(config.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "tasks@example.com",
+		To:   []string{"team@example.com"},
+	})
+
+	watched := reminder
+	watched.Recipients = []string{"bob", "carol"}
+	require.NoError(t, n.Notify(context.Background(), watched))
+
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(<-received)))
+	_, err := r.ReadMIMEHeader()
+	require.NoError(t, err)
+
+	line, err := r.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "For: bob, carol", line)
+}
@@ -0,0 +1,81 @@
+// Package recurrence parses the recurrence rules of tasks and creates the next
+// occurrence of a recurring task once it is completed.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/teambition/rrule-go"
+)
+
+// ErrInvalidRule is returned for a rule that is neither an RRULE nor a cron
+// expression.
+var ErrInvalidRule = errors.New("recurrence must be an RFC 5545 RRULE or a cron expression")
+
+// Rule is a parsed recurrence rule.
+type Rule interface {
+	// Next returns the first occurrence after t for a series that started at
+	// start, or the zero time when the series has ended.
+	Next(start, t time.Time) time.Time
+}
+
+// Parse reads an RFC 5545 RRULE, with or without the "RRULE:" prefix (e.g.
+// "FREQ=WEEKLY;BYDAY=MO"), or a standard five-field cron expression such as
+// "0 9 * * 1" or "@daily".
+func Parse(rule string) (Rule, error) {
+
+	rule = strings.TrimSpace(rule)
+
+	prefixed := strings.HasPrefix(strings.ToUpper(rule), "RRULE:")
+	if prefixed {
+		rule = rule[len("RRULE:"):]
+	}
+
+	if prefixed || strings.Contains(strings.ToUpper(rule), "FREQ=") {
+		option, err := rrule.StrToROption(rule)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		if _, err := rrule.NewRRule(*option); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		return rruleRule{option: *option}, nil
+	}
+
+	schedule, err := cron.ParseStandard(rule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return cronRule{schedule: schedule}, nil
+}
+
+type rruleRule struct {
+	option rrule.ROption
+}
+
+// Next counts the series from the rule's DTSTART, or from start when the rule
+// has none.
+func (r rruleRule) Next(start, t time.Time) time.Time {
+	option := r.option
+	if option.Dtstart.IsZero() {
+		option.Dtstart = start
+	}
+
+	rule, err := rrule.NewRRule(option)
+	if err != nil {
+		return time.Time{}
+	}
+	return rule.After(t, false)
+}
+
+type cronRule struct {
+	schedule cron.Schedule
+}
+
+func (r cronRule) Next(start, t time.Time) time.Time {
+	return r.schedule.Next(t)
+}
@@ -0,0 +1,140 @@
+package recurrence
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const queueSize = 256
+
+// Clock tells the scheduler the time. SystemClock is the real one; tests
+// pass a fixed clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Spawn stores the next occurrence of a recurring task.
+type Spawn func(next database.Task) error
+
+// Scheduler creates the next occurrence of every recurring task it is told
+// has been completed, in its own goroutine so that the request completing the
+// task does not wait for it.
+type Scheduler struct {
+	clock     Clock
+	spawn     Spawn
+	completed chan database.Task
+}
+
+func NewScheduler(clock Clock, spawn Spawn) *Scheduler {
+	return &Scheduler{
+		clock:     clock,
+		spawn:     spawn,
+		completed: make(chan database.Task, queueSize),
+	}
+}
+
+// Start creates occurrences until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case task := <-s.completed:
+				s.schedule(task)
+			}
+		}
+	}()
+}
+
+// Drain creates the occurrences of the tasks queued so far and returns. It
+// lets short-lived processes, such as the command line, use a scheduler
+// without starting it.
+func (s *Scheduler) Drain() {
+	for {
+		select {
+		case task := <-s.completed:
+			s.schedule(task)
+		default:
+			return
+		}
+	}
+}
+
+// schedule spawns the occurrence that follows task, if there is one.
+func (s *Scheduler) schedule(task database.Task) {
+	next, ok := s.Next(task)
+	if !ok {
+		return
+	}
+	if err := s.spawn(next); err != nil {
+		log.Println("Error Spawning Recurring Task: ", err)
+	}
+}
+
+// Completed queues the completed recurring task for its next occurrence. It
+// never blocks; when the queue is full the occurrence is dropped and logged.
+func (s *Scheduler) Completed(task database.Task) {
+	select {
+	case s.completed <- task:
+	default:
+		log.Println("Error Scheduling Recurring Task: queue full, dropped ", task.ID.Hex())
+	}
+}
+
+// Next returns the occurrence that follows task: a new incomplete copy due at
+// the first time the rule gives after the task's due date, or after now when
+// that has already passed, so a task completed late does not leave a backlog
+// of occurrences. The series is counted from the task's SeriesStart, or from
+// the task itself when it is the first occurrence. It reports false when the
+// rule is invalid or has ended.
+func (s *Scheduler) Next(task database.Task) (database.Task, bool) {
+
+	rule, err := Parse(task.Recurrence)
+	if err != nil {
+		log.Println("Error Scheduling Recurring Task: ", err)
+		return database.Task{}, false
+	}
+
+	now := s.clock.Now()
+	after := now
+	if task.Due != nil && task.Due.After(now) {
+		after = *task.Due
+	}
+	start := after
+	if task.Due != nil {
+		start = *task.Due
+	}
+	if task.SeriesStart != nil {
+		start = *task.SeriesStart
+	}
+
+	due := rule.Next(start, after)
+	if due.IsZero() {
+		return database.Task{}, false
+	}
+	due = due.UTC()
+
+	return database.Task{
+		ID:          primitive.NewObjectID(),
+		Name:        task.Name,
+		ParentID:    task.ParentID,
+		Recurrence:  task.Recurrence,
+		Due:         &due,
+		SeriesStart: &start,
+		ProjectID:   task.ProjectID,
+		LabelIDs:    task.LabelIDs,
+		AssigneeIDs: task.AssigneeIDs,
+		WatcherIDs:  task.WatcherIDs,
+	}, true
+}
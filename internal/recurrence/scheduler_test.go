@@ -0,0 +1,115 @@
+package recurrence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func date(day, hour int) time.Time {
+	return time.Date(2024, time.January, day, hour, 0, 0, 0, time.UTC)
+}
+
+func Test_Parse(t *testing.T) {
+	for _, rule := range []string{"FREQ=DAILY", "RRULE:FREQ=WEEKLY;BYDAY=MO,WE", "0 9 * * 1-5", "@hourly"} {
+		_, err := Parse(rule)
+		assert.NoError(t, err, rule)
+	}
+
+	for _, rule := range []string{"FREQ=SOMETIMES", "RRULE:", "every day", "61 * * * *"} {
+		_, err := Parse(rule)
+		assert.True(t, errors.Is(err, ErrInvalidRule), rule)
+	}
+}
+
+func Test_SchedulerNext(t *testing.T) {
+	due := date(1, 9) // a Monday
+	tests := []struct {
+		name  string
+		rule  string
+		due   *time.Time
+		now   time.Time
+		want  time.Time
+		start time.Time
+	}{
+		{"rrule from due date", "FREQ=WEEKLY;BYDAY=MO,WE", &due, date(1, 8), date(3, 9), due},
+		{"cron from due date", "0 9 * * *", &due, date(1, 8), date(2, 9), due},
+		{"completed late", "FREQ=DAILY", &due, date(5, 12), date(6, 9), due},
+		{"no due date", "0 9 * * *", nil, date(1, 12), date(2, 9), date(1, 12)},
+		{"rrule with dtstart", "DTSTART=20240101T070000Z;FREQ=DAILY", nil, date(1, 12), date(2, 7), date(1, 12)},
+	}
+
+	for _, test := range tests {
+		s := NewScheduler(fixedClock(test.now), nil)
+		task := database.Task{ID: primitive.NewObjectID(), Name: "standup", Status: 1, ParentID: "p", Recurrence: test.rule, Due: test.due}
+
+		next, ok := s.Next(task)
+
+		require.True(t, ok, test.name)
+		assert.Equal(t, test.want, *next.Due, test.name)
+		assert.NotEqual(t, task.ID, next.ID, test.name)
+		assert.Equal(t, test.start, *next.SeriesStart, test.name)
+		assert.Equal(t, database.Task{ID: next.ID, Name: "standup", ParentID: "p", Recurrence: test.rule, Due: next.Due, SeriesStart: next.SeriesStart}, next, test.name)
+	}
+}
+
+func Test_SchedulerNextKeepsSeries(t *testing.T) {
+	due := date(1, 9)
+	s := NewScheduler(fixedClock(date(1, 8)), nil)
+	task := database.Task{ID: primitive.NewObjectID(), Name: "standup", Recurrence: "FREQ=DAILY;COUNT=3", Due: &due}
+
+	var dues []time.Time
+	for {
+		dues = append(dues, *task.Due)
+		next, ok := s.Next(task)
+		if !ok {
+			break
+		}
+		require.Less(t, len(dues), 3, "the series has a fourth occurrence")
+		task = next
+	}
+
+	assert.Equal(t, []time.Time{date(1, 9), date(2, 9), date(3, 9)}, dues)
+}
+
+func Test_SchedulerNextEnded(t *testing.T) {
+	s := NewScheduler(fixedClock(date(10, 0)), nil)
+
+	_, ok := s.Next(database.Task{Recurrence: "DTSTART=20240101T090000Z;FREQ=DAILY;COUNT=3"})
+
+	assert.False(t, ok)
+}
+
+func Test_SchedulerSpawnsCompletedTasks(t *testing.T) {
+	spawned := make(chan database.Task, 1)
+	s := NewScheduler(fixedClock(date(1, 12)), func(next database.Task) error {
+		spawned <- next
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	s.Completed(database.Task{ID: primitive.NewObjectID(), Name: "report", Recurrence: "@daily"})
+
+	select {
+	case next := <-spawned:
+		assert.Equal(t, "report", next.Name)
+		assert.Equal(t, date(2, 0), *next.Due)
+	case <-time.After(time.Second):
+		t.Fatal("no occurrence was spawned")
+	}
+}
@@ -0,0 +1,142 @@
+// Package reminder runs the background job that reminds of tasks nearing
+// their due date and marks the ones past it as overdue.
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/notify"
+)
+
+// Store is the part of database.DBInterface the worker needs.
+type Store interface {
+	GetDueTasks(before time.Time) ([]database.Task, error)
+	ClaimTaskReminder(taskID string, due, at time.Time, overdue bool) (database.Task, bool, error)
+	ReleaseTaskReminder(before database.Task, at time.Time) error
+}
+
+// Clock tells the worker the time, so tests can fix it.
+type Clock interface {
+	Now() time.Time
+}
+
+// Worker checks the due dates of tasks every Interval. A task due within Lead
+// gets one "due soon" reminder, and one "overdue" reminder once its due date
+// has passed, which also marks it overdue. Every reminder is claimed in the
+// store before it is sent, so that when several replicas run the worker only
+// one of them sends it. A reminder that cannot be delivered is released and
+// tried again on the next check.
+type Worker struct {
+	store    Store
+	notifier notify.Notifier
+	clock    Clock
+	Interval time.Duration
+	Lead     time.Duration
+	// MarkedOverdue, when set, is called with a task before and after the
+	// worker marked it overdue, so that the change can be recorded and
+	// published like any other.
+	MarkedOverdue func(before, after database.Task)
+}
+
+func NewWorker(store Store, notifier notify.Notifier, clock Clock, interval, lead time.Duration) *Worker {
+	return &Worker{
+		store:    store,
+		notifier: notifier,
+		clock:    clock,
+		Interval: interval,
+		Lead:     lead,
+	}
+}
+
+// Start checks every Interval until ctx is cancelled. It does nothing when
+// Interval is not positive.
+func (w *Worker) Start(ctx context.Context) {
+	if w.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := w.Check(ctx); err != nil {
+				log.Println("Error Checking Due Tasks: ", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Check sends the reminders that are due now.
+func (w *Worker) Check(ctx context.Context) error {
+
+	now := w.clock.Now()
+
+	tasks, err := w.store.GetDueTasks(now.Add(w.Lead))
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if task.Due == nil {
+			continue
+		}
+
+		overdue := !task.Due.After(now)
+		if overdue && task.Overdue || !overdue && task.RemindedAt != nil {
+			continue
+		}
+
+		before, claimed, err := w.store.ClaimTaskReminder(task.ID.Hex(), *task.Due, now, overdue)
+		if err != nil {
+			log.Println("Error Claiming Reminder: ", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := w.notifier.Notify(ctx, reminderFor(before, overdue, now)); err != nil {
+			log.Println("Error Sending Reminder: ", err)
+			if err := w.store.ReleaseTaskReminder(before, now); err != nil {
+				log.Println("Error Releasing Reminder: ", err)
+			}
+			continue
+		}
+
+		if overdue && w.MarkedOverdue != nil {
+			after := before
+			after.RemindedAt = &now
+			after.Overdue = true
+			w.MarkedOverdue(before, after)
+		}
+	}
+
+	return nil
+}
+
+func reminderFor(task database.Task, overdue bool, now time.Time) notify.Notification {
+	n := notify.Notification{
+		Type:      notify.TaskDueSoon,
+		TaskID:    task.ID.Hex(),
+		Subject:   fmt.Sprintf("%q is due soon", task.Name),
+		Message:   fmt.Sprintf("The task %q is due at %s.", task.Name, task.Due.UTC().Format(time.RFC3339)),
+		Timestamp: now,
+	}
+	if overdue {
+		n.Type = notify.TaskOverdue
+		n.Subject = fmt.Sprintf("%q is overdue", task.Name)
+		n.Message = fmt.Sprintf("The task %q was due at %s.", task.Name, task.Due.UTC().Format(time.RFC3339))
+	}
+	return n
+}
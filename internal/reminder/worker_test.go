@@ -0,0 +1,161 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/notify"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+type mockStore struct {
+	tasks []database.Task
+}
+
+func (s *mockStore) GetDueTasks(before time.Time) ([]database.Task, error) {
+	var due []database.Task
+	for _, task := range s.tasks {
+		if task.Status != 1 && task.Due != nil && !task.Due.After(before) {
+			due = append(due, task)
+		}
+	}
+	return due, nil
+}
+
+func (s *mockStore) ClaimTaskReminder(taskID string, due, at time.Time, overdue bool) (database.Task, bool, error) {
+	for i, task := range s.tasks {
+		if task.ID.Hex() != taskID || !task.Due.Equal(due) {
+			continue
+		}
+		if overdue && task.Overdue || !overdue && task.RemindedAt != nil {
+			return database.Task{}, false, nil
+		}
+		s.tasks[i].RemindedAt = &at
+		s.tasks[i].Overdue = task.Overdue || overdue
+		return task, true, nil
+	}
+	return database.Task{}, false, nil
+}
+
+func (s *mockStore) ReleaseTaskReminder(before database.Task, at time.Time) error {
+	for i, task := range s.tasks {
+		if task.ID == before.ID && task.RemindedAt != nil && task.RemindedAt.Equal(at) {
+			s.tasks[i].RemindedAt = before.RemindedAt
+			s.tasks[i].Overdue = before.Overdue
+		}
+	}
+	return nil
+}
+
+// staleStore returns the due tasks as they were before any of them was
+// claimed, like a replica that read them just before another one claimed them.
+type staleStore struct {
+	*mockStore
+	due []database.Task
+}
+
+func (s *staleStore) GetDueTasks(before time.Time) ([]database.Task, error) {
+	return s.due, nil
+}
+
+type recordingNotifier struct {
+	sent []notify.Notification
+	err  error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification notify.Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func dueAt(name string, due time.Time) database.Task {
+	return database.Task{ID: primitive.NewObjectID(), Name: name, Due: &due}
+}
+
+func Test_WorkerCheck(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	store := &mockStore{tasks: []database.Task{
+		dueAt("soon", now.Add(30*time.Minute)),
+		dueAt("late", now.Add(-time.Minute)),
+		dueAt("later", now.Add(2*time.Hour)),
+	}}
+	done := dueAt("done", now.Add(-time.Hour))
+	done.Status = 1
+	store.tasks = append(store.tasks, done)
+
+	notifier := &recordingNotifier{}
+	w := NewWorker(store, notifier, fixedClock(now), time.Minute, time.Hour)
+
+	require.NoError(t, w.Check(context.Background()))
+
+	require.Len(t, notifier.sent, 2)
+	assert.Equal(t, notify.TaskDueSoon, notifier.sent[0].Type)
+	assert.Equal(t, store.tasks[0].ID.Hex(), notifier.sent[0].TaskID)
+	assert.Equal(t, notify.TaskOverdue, notifier.sent[1].Type)
+	assert.Equal(t, `"late" is overdue`, notifier.sent[1].Subject)
+	assert.False(t, store.tasks[0].Overdue)
+	assert.True(t, store.tasks[1].Overdue)
+
+	// Nothing is sent twice, but a task that became overdue since is.
+	w.clock = fixedClock(now.Add(45 * time.Minute))
+	require.NoError(t, w.Check(context.Background()))
+
+	require.Len(t, notifier.sent, 3)
+	assert.Equal(t, notify.TaskOverdue, notifier.sent[2].Type)
+	assert.Equal(t, store.tasks[0].ID.Hex(), notifier.sent[2].TaskID)
+}
+
+func Test_WorkerCheckRetriesFailedReminders(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	store := &mockStore{tasks: []database.Task{dueAt("late", now.Add(-time.Minute))}}
+	notifier := &recordingNotifier{err: errors.New("smtp down")}
+	w := NewWorker(store, notifier, fixedClock(now), time.Minute, time.Hour)
+
+	require.NoError(t, w.Check(context.Background()))
+	assert.False(t, store.tasks[0].Overdue)
+
+	notifier.err = nil
+	require.NoError(t, w.Check(context.Background()))
+	assert.Len(t, notifier.sent, 1)
+	assert.True(t, store.tasks[0].Overdue)
+}
+
+func Test_WorkerCheckClaimsReminders(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	store := &mockStore{tasks: []database.Task{dueAt("late", now.Add(-time.Minute))}}
+	notifier := &recordingNotifier{}
+
+	// Both replicas read the task before either of them claimed it.
+	due, err := store.GetDueTasks(now.Add(time.Hour))
+	require.NoError(t, err)
+	stale := &staleStore{mockStore: store, due: due}
+
+	var marked []database.Task
+	for i := 0; i < 2; i++ {
+		w := NewWorker(stale, notifier, fixedClock(now), time.Minute, time.Hour)
+		w.MarkedOverdue = func(before, after database.Task) {
+			assert.False(t, before.Overdue)
+			marked = append(marked, after)
+		}
+		require.NoError(t, w.Check(context.Background()))
+	}
+
+	assert.Len(t, notifier.sent, 1)
+	require.Len(t, marked, 1)
+	assert.True(t, marked[0].Overdue)
+	assert.Equal(t, now, *marked[0].RemindedAt)
+}
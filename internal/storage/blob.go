@@ -0,0 +1,61 @@
+// Package storage keeps the contents of task attachments in a BlobStore: a
+// directory on the local filesystem or a MongoDB GridFS bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tiffany831101/bs_pretest.git/config"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("blob keys may only contain letters, digits, '-' and '_'")
+)
+
+// Blob is a stored blob opened for reading. It can seek, so that ranges of it
+// can be served.
+type Blob interface {
+	io.ReadSeekCloser
+	Size() int64
+}
+
+// BlobStore stores blobs under keys chosen by the caller.
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any blob with the same
+	// key, and returns the number of bytes stored.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns the blob stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (Blob, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is not
+	// an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the BlobStore selected by cfg. db is only used by the gridfs
+// store.
+func New(cfg config.AttachmentsConfig, db *mongo.Database) (BlobStore, error) {
+	switch cfg.Store {
+	case "", "local":
+		return NewLocalStore(cfg.Dir)
+	case "gridfs":
+		return NewGridFSStore(db, cfg.Bucket)
+	}
+	return nil, fmt.Errorf("unknown attachment store %q", cfg.Store)
+}
+
+func checkKey(key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultBucket = "attachments"
+
+// GridFSStore keeps blobs in a GridFS bucket, using the key as the file ID.
+type GridFSStore struct {
+	bucket *gridfs.Bucket
+}
+
+// NewGridFSStore stores blobs in the named bucket of db, "attachments" when
+// name is empty.
+func NewGridFSStore(db *mongo.Database, name string) (*GridFSStore, error) {
+	if name == "" {
+		name = defaultBucket
+	}
+
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GridFSStore{bucket: bucket}, nil
+}
+
+func (s *GridFSStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+
+	// GridFS does not replace files, so drop an existing one first.
+	if err := s.Delete(ctx, key); err != nil {
+		return 0, err
+	}
+
+	counter := &countingReader{r: r}
+	err := s.bucket.UploadFromStreamWithID(key, key, counter)
+
+	return counter.n, err
+}
+
+func (s *GridFSStore) Open(ctx context.Context, key string) (Blob, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	stream, err := s.bucket.OpenDownloadStream(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &gridfsBlob{bucket: s.bucket, key: key, size: stream.GetFile().Length, stream: stream}, nil
+}
+
+func (s *GridFSStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	err := s.bucket.DeleteContext(ctx, key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil
+	}
+	return err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// gridfsBlob makes a GridFS download seekable. Seeking only moves the
+// position; the next read reopens the download and skips to it when the open
+// stream is elsewhere.
+type gridfsBlob struct {
+	bucket *gridfs.Bucket
+	key    string
+	size   int64
+
+	stream    *gridfs.DownloadStream
+	streamPos int64
+	pos       int64
+}
+
+func (b *gridfsBlob) Size() int64 {
+	return b.size
+}
+
+func (b *gridfsBlob) Read(p []byte) (int, error) {
+	if b.pos >= b.size {
+		return 0, io.EOF
+	}
+
+	if b.stream == nil || b.streamPos != b.pos {
+		if b.stream != nil {
+			b.stream.Close()
+			b.stream = nil
+		}
+
+		stream, err := b.bucket.OpenDownloadStream(b.key)
+		if err != nil {
+			return 0, err
+		}
+		b.stream = stream
+
+		if _, err := stream.Skip(b.pos); err != nil {
+			return 0, err
+		}
+		b.streamPos = b.pos
+	}
+
+	n, err := b.stream.Read(p)
+	b.pos += int64(n)
+	b.streamPos += int64(n)
+	return n, err
+}
+
+func (b *gridfsBlob) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.pos
+	case io.SeekEnd:
+		offset += b.size
+	default:
+		return 0, errors.New("gridfs blob: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("gridfs blob: negative position")
+	}
+
+	b.pos = offset
+	return offset, nil
+}
+
+func (b *gridfsBlob) Close() error {
+	if b.stream == nil {
+		return nil
+	}
+	return b.stream.Close()
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore keeps every blob in a file named after its key in one directory.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates dir if needed and stores blobs in it.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Put writes to a temporary file first, so a failed upload never leaves a
+// partial blob under key.
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, err
+	}
+
+	return n, os.Rename(tmp.Name(), filepath.Join(s.dir, key))
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (Blob, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return localBlob{File: f, size: info.Size()}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(s.dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+type localBlob struct {
+	*os.File
+	size int64
+}
+
+func (b localBlob) Size() int64 {
+	return b.size
+}
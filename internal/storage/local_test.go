@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewLocalStore(filepath.Join(dir, "blobs"))
+	require.NoError(t, err)
+
+	n, err := store.Put(ctx, "report", strings.NewReader("hello, world"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), n)
+
+	blob, err := store.Open(ctx, "report")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), blob.Size())
+
+	_, err = blob.Seek(7, io.SeekStart)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(blob)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(rest))
+	require.NoError(t, blob.Close())
+
+	_, err = store.Put(ctx, "report", strings.NewReader("replaced"))
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(filepath.Join(dir, "blobs"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+
+	require.NoError(t, store.Delete(ctx, "report"))
+	require.NoError(t, store.Delete(ctx, "report"), "deleting a missing blob")
+
+	_, err = store.Open(ctx, "report")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStore_InvalidKey(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "../escape", "a/b", "."} {
+		_, err := store.Put(ctx, key, strings.NewReader("x"))
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+
+		_, err = store.Open(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+
+		assert.ErrorIs(t, store.Delete(ctx, key), ErrInvalidKey, key)
+	}
+}
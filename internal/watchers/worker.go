@@ -0,0 +1,89 @@
+// Package watchers notifies the watchers of a task when someone else changes
+// it.
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/notify"
+)
+
+// eventBuffer is how many events the worker can fall behind the bus by before
+// it misses some.
+const eventBuffer = 256
+
+// Worker turns task events into notifications for the watchers of the task.
+// Deleted tasks carry no watchers in their events, so their watchers are not
+// notified.
+type Worker struct {
+	notifier notify.Notifier
+}
+
+func NewWorker(notifier notify.Notifier) *Worker {
+	return &Worker{notifier: notifier}
+}
+
+// Start handles the events published on bus until ctx is cancelled.
+func (w *Worker) Start(ctx context.Context, bus *events.Bus) {
+	ch, unsubscribe := bus.Subscribe(eventBuffer)
+
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-ch:
+				w.Handle(ctx, e)
+			}
+		}
+	}()
+}
+
+// Handle notifies the watchers of the task e is about, except the one who
+// made the change. Events from other replicas are skipped: the replica that
+// made the change notifies.
+func (w *Worker) Handle(ctx context.Context, e events.Event) {
+	n, ok := notificationFor(e)
+	if !ok {
+		return
+	}
+
+	if err := w.notifier.Notify(ctx, n); err != nil {
+		log.Println("Error Notifying Watchers: ", err)
+	}
+}
+
+func notificationFor(e events.Event) (notify.Notification, bool) {
+	if e.Remote || e.Task == nil {
+		return notify.Notification{}, false
+	}
+
+	recipients := slices.DeleteFunc(slices.Clone(e.Task.WatcherIDs), func(id string) bool {
+		return id == e.Actor
+	})
+	if len(recipients) == 0 {
+		return notify.Notification{}, false
+	}
+
+	verb := "updated"
+	switch e.Type {
+	case events.TaskCreated:
+		verb = "created"
+	case events.TaskDeleted:
+		verb = "deleted"
+	}
+
+	return notify.Notification{
+		Type:       string(e.Type),
+		TaskID:     e.TaskID,
+		Subject:    fmt.Sprintf("%q was %s", e.Task.Name, verb),
+		Message:    fmt.Sprintf("%s %s the task %q you are watching.", e.Actor, verb, e.Task.Name),
+		Timestamp:  e.Timestamp,
+		Recipients: recipients,
+	}, true
+}
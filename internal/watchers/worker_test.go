@@ -0,0 +1,91 @@
+package watchers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"github.com/tiffany831101/bs_pretest.git/internal/notify"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type recordingNotifier struct {
+	sent chan notify.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification notify.Notification) error {
+	select {
+	case n.sent <- notification:
+	default:
+	}
+	return nil
+}
+
+func watchedTask(watchers ...string) *database.Task {
+	return &database.Task{ID: primitive.NewObjectID(), Name: "write report", WatcherIDs: watchers}
+}
+
+func Test_Handle(t *testing.T) {
+	notifier := &recordingNotifier{sent: make(chan notify.Notification, 1)}
+	w := NewWorker(notifier)
+
+	task := watchedTask("alice", "bob")
+	w.Handle(context.Background(), events.NewTaskEvent(events.TaskUpdated, task.ID.Hex(), task, "alice"))
+
+	require.Len(t, notifier.sent, 1)
+	n := <-notifier.sent
+	assert.Equal(t, string(events.TaskUpdated), n.Type)
+	assert.Equal(t, task.ID.Hex(), n.TaskID)
+	assert.Equal(t, []string{"bob"}, n.Recipients, "the actor is not notified of their own change")
+	assert.Equal(t, `"write report" was updated`, n.Subject)
+}
+
+func Test_HandleDeleted(t *testing.T) {
+	notifier := &recordingNotifier{sent: make(chan notify.Notification, 1)}
+	w := NewWorker(notifier)
+
+	task := watchedTask("bob")
+	w.Handle(context.Background(), events.NewTaskEvent(events.TaskDeleted, task.ID.Hex(), task, "alice"))
+
+	require.Len(t, notifier.sent, 1)
+	n := <-notifier.sent
+	assert.Equal(t, string(events.TaskDeleted), n.Type)
+	assert.Equal(t, `"write report" was deleted`, n.Subject)
+}
+
+func Test_HandleSkips(t *testing.T) {
+	notifier := &recordingNotifier{sent: make(chan notify.Notification, 1)}
+	w := NewWorker(notifier)
+
+	task := watchedTask("alice")
+	remote := events.NewTaskEvent(events.TaskUpdated, task.ID.Hex(), task, "bob")
+	remote.Remote = true
+
+	for name, e := range map[string]events.Event{
+		"only the actor watches": events.NewTaskEvent(events.TaskUpdated, task.ID.Hex(), task, "alice"),
+		"no watchers":            events.NewTaskEvent(events.TaskUpdated, task.ID.Hex(), watchedTask(), "bob"),
+		"other replica":          remote,
+	} {
+		w.Handle(context.Background(), e)
+		assert.Empty(t, notifier.sent, name)
+	}
+}
+
+func Test_Start(t *testing.T) {
+	notifier := &recordingNotifier{sent: make(chan notify.Notification, 1)}
+	bus := events.NewBus(16)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	NewWorker(notifier).Start(ctx, bus)
+
+	task := watchedTask("bob")
+	require.Eventually(t, func() bool {
+		bus.Publish(events.NewTaskEvent(events.TaskUpdated, task.ID.Hex(), task, "alice"))
+		return len(notifier.sent) > 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,274 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	defaultMaxAttempts = 5
+	defaultBaseDelay   = time.Second
+	defaultTimeout     = 10 * time.Second
+	defaultWebhooksTTL = 5 * time.Second
+	eventBuffer        = 256
+
+	// droppedError is recorded on the deliveries of the events the
+	// dispatcher missed because it fell behind.
+	droppedError = "event dropped: the webhook dispatcher fell behind"
+)
+
+// Store is the part of database.DBInterface the dispatcher needs.
+type Store interface {
+	GetWebhooks() ([]database.Webhook, error)
+	InsertWebhookDelivery(delivery database.WebhookDelivery) error
+}
+
+// Dispatcher delivers task events to every subscribed webhook. Each delivery
+// runs in its own goroutine and is retried with exponential backoff; once
+// MaxAttempts is exhausted it is stored as a dead letter. Events the
+// dispatcher misses because it fell behind the bus are stored as dead letters
+// too, without being attempted.
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// WebhooksTTL is how long the list of webhooks is reused before it is
+	// read again, so that a burst of events costs one query.
+	WebhooksTTL time.Duration
+
+	wg sync.WaitGroup
+
+	mu         sync.Mutex
+	webhooks   []database.Webhook
+	webhooksAt time.Time
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		store:       store,
+		client:      &http.Client{Timeout: defaultTimeout},
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		WebhooksTTL: defaultWebhooksTTL,
+	}
+}
+
+// Sign returns the signature sent in the X-Webhook-Signature header: the
+// hex-encoded HMAC-SHA256 of the body keyed with the webhook secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Start consumes events from bus until ctx is cancelled.
+func (d *Dispatcher) Start(ctx context.Context, bus *events.Bus) {
+	ch, unsubscribe := bus.SubscribeDropped(eventBuffer, func(e events.Event) {
+		// The bus is locked while this runs.
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.Dropped(e)
+		}()
+	})
+
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-ch:
+				d.Dispatch(ctx, e)
+			}
+		}
+	}()
+}
+
+// Dispatch schedules delivery of e to every webhook subscribed to its type.
+// Events from other replicas are skipped: the replica that made the change
+// delivers them.
+func (d *Dispatcher) Dispatch(ctx context.Context, e events.Event) {
+	hooks, payload, ok := d.subscribed(e)
+	if !ok {
+		return
+	}
+
+	for _, hook := range hooks {
+		d.wg.Add(1)
+		go func(hook database.Webhook) {
+			defer d.wg.Done()
+			d.deliver(ctx, hook, e, payload)
+		}(hook)
+	}
+}
+
+// Dropped records a dead delivery of e to every webhook subscribed to its
+// type, for an event the dispatcher missed.
+func (d *Dispatcher) Dropped(e events.Event) {
+	hooks, payload, ok := d.subscribed(e)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, hook := range hooks {
+		delivery := database.WebhookDelivery{
+			WebhookID:   hook.ID.Hex(),
+			EventID:     e.ID,
+			EventType:   string(e.Type),
+			Payload:     string(payload),
+			Status:      database.DeliveryDead,
+			Error:       droppedError,
+			CreatedAt:   now,
+			CompletedAt: now,
+		}
+		if err := d.store.InsertWebhookDelivery(delivery); err != nil {
+			log.Println("Error Record Webhook Delivery: ", err)
+		}
+	}
+}
+
+// subscribed returns the webhooks e is delivered to, and its payload. It is
+// false when there is nothing to deliver: e comes from another replica, which
+// delivers it, or the webhooks cannot be read.
+func (d *Dispatcher) subscribed(e events.Event) ([]database.Webhook, []byte, bool) {
+	if e.Remote {
+		return nil, nil, false
+	}
+
+	webhooks, err := d.getWebhooks()
+	if err != nil {
+		log.Println("Error Get Webhooks: ", err)
+		return nil, nil, false
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Println("Error Encode Webhook Payload: ", err)
+		return nil, nil, false
+	}
+
+	var hooks []database.Webhook
+	for _, hook := range webhooks {
+		if slices.Contains(hook.Events, string(e.Type)) {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, payload, true
+}
+
+// getWebhooks returns the webhooks, read at most once every WebhooksTTL.
+func (d *Dispatcher) getWebhooks() ([]database.Webhook, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.webhooks != nil && time.Since(d.webhooksAt) < d.WebhooksTTL {
+		return d.webhooks, nil
+	}
+
+	webhooks, err := d.store.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []database.Webhook{}
+	}
+
+	d.webhooks, d.webhooksAt = webhooks, time.Now()
+	return webhooks, nil
+}
+
+// Wait blocks until every scheduled delivery has finished.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, hook database.Webhook, e events.Event, payload []byte) {
+	delivery := database.WebhookDelivery{
+		WebhookID: hook.ID.Hex(),
+		EventID:   e.ID,
+		EventType: string(e.Type),
+		Payload:   string(payload),
+		Status:    database.DeliveryDead,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	delay := d.BaseDelay
+	for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+		delivery.Attempts = attempt
+
+		code, err := d.send(ctx, hook, e, payload)
+		delivery.ResponseCode = code
+
+		if err == nil {
+			delivery.Status = database.DeliverySucceeded
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+
+		if attempt == d.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			attempt = d.MaxAttempts
+		case <-time.After(delay):
+			delay *= 2
+		}
+	}
+
+	delivery.CompletedAt = time.Now().UTC()
+
+	if delivery.Status == database.DeliveryDead {
+		log.Printf("Webhook %s gave up on event %s after %d attempts: %s", delivery.WebhookID, e.ID, delivery.Attempts, delivery.Error)
+	}
+
+	if err := d.store.InsertWebhookDelivery(delivery); err != nil {
+		log.Println("Error Record Webhook Delivery: ", err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, hook database.Webhook, e events.Event, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, payload))
+	req.Header.Set(EventHeader, string(e.Type))
+	req.Header.Set(DeliveryHeader, e.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/events"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type mockStore struct {
+	mu         sync.Mutex
+	webhooks   []database.Webhook
+	deliveries []database.WebhookDelivery
+	reads      int
+}
+
+func (s *mockStore) GetWebhooks() ([]database.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reads++
+	return s.webhooks, nil
+}
+
+func (s *mockStore) InsertWebhookDelivery(delivery database.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func newTestDispatcher(store *mockStore) *Dispatcher {
+	d := NewDispatcher(store)
+	d.MaxAttempts = 3
+	d.BaseDelay = time.Millisecond
+	return d
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t,
+		"sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		Sign("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	var body []byte
+	var header http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer receiver.Close()
+
+	store := &mockStore{webhooks: []database.Webhook{
+		{ID: primitive.NewObjectID(), URL: receiver.URL, Events: []string{"task.created"}, Secret: "s3cret"},
+		{ID: primitive.NewObjectID(), URL: receiver.URL, Events: []string{"task.deleted"}, Secret: "other"},
+	}}
+	d := newTestDispatcher(store)
+
+	e := events.NewTaskEvent(events.TaskCreated, "abc", &database.Task{Name: "Test Task"}, "alice")
+	d.Dispatch(context.Background(), e)
+	d.Wait()
+
+	assert.Len(t, store.deliveries, 1)
+	assert.Equal(t, database.DeliverySucceeded, store.deliveries[0].Status)
+	assert.Equal(t, 1, store.deliveries[0].Attempts)
+	assert.Equal(t, Sign("s3cret", body), header.Get(SignatureHeader))
+	assert.Equal(t, "task.created", header.Get(EventHeader))
+	assert.Equal(t, e.ID, header.Get(DeliveryHeader))
+}
+
+func TestDispatcher_RetriesThenSucceeds(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	store := &mockStore{webhooks: []database.Webhook{
+		{ID: primitive.NewObjectID(), URL: receiver.URL, Events: []string{"task.updated"}},
+	}}
+	d := newTestDispatcher(store)
+
+	d.Dispatch(context.Background(), events.NewTaskEvent(events.TaskUpdated, "abc", nil, ""))
+	d.Wait()
+
+	assert.Equal(t, int32(3), calls)
+	assert.Equal(t, database.DeliverySucceeded, store.deliveries[0].Status)
+	assert.Equal(t, 3, store.deliveries[0].Attempts)
+}
+
+func TestDispatcher_DeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := &mockStore{webhooks: []database.Webhook{
+		{ID: primitive.NewObjectID(), URL: receiver.URL, Events: []string{"task.deleted"}},
+	}}
+	d := newTestDispatcher(store)
+
+	d.Dispatch(context.Background(), events.NewTaskEvent(events.TaskDeleted, "abc", nil, ""))
+	d.Wait()
+
+	assert.Equal(t, database.DeliveryDead, store.deliveries[0].Status)
+	assert.Equal(t, 3, store.deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, store.deliveries[0].ResponseCode)
+}
+
+func TestDispatcher_Dropped(t *testing.T) {
+	store := &mockStore{webhooks: []database.Webhook{
+		{ID: primitive.NewObjectID(), URL: "http://127.0.0.1:0", Events: []string{"task.created"}},
+		{ID: primitive.NewObjectID(), URL: "http://127.0.0.1:0", Events: []string{"task.deleted"}},
+	}}
+	d := newTestDispatcher(store)
+
+	e := events.NewTaskEvent(events.TaskCreated, "abc", nil, "")
+	d.Dropped(e)
+
+	assert.Len(t, store.deliveries, 1)
+	assert.Equal(t, store.webhooks[0].ID.Hex(), store.deliveries[0].WebhookID)
+	assert.Equal(t, e.ID, store.deliveries[0].EventID)
+	assert.Equal(t, database.DeliveryDead, store.deliveries[0].Status)
+	assert.Equal(t, 0, store.deliveries[0].Attempts)
+	assert.Equal(t, droppedError, store.deliveries[0].Error)
+}
+
+func TestDispatcher_ReusesWebhooks(t *testing.T) {
+	store := &mockStore{}
+	d := newTestDispatcher(store)
+
+	d.Dispatch(context.Background(), events.NewTaskEvent(events.TaskCreated, "a", nil, ""))
+	d.Dispatch(context.Background(), events.NewTaskEvent(events.TaskCreated, "b", nil, ""))
+	assert.Equal(t, 1, store.reads)
+
+	d.WebhooksTTL = 0
+	d.Dispatch(context.Background(), events.NewTaskEvent(events.TaskCreated, "c", nil, ""))
+	assert.Equal(t, 2, store.reads)
+}
+
+func TestDispatcher_Start(t *testing.T) {
+	received := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer receiver.Close()
+
+	store := &mockStore{webhooks: []database.Webhook{
+		{ID: primitive.NewObjectID(), URL: receiver.URL, Events: []string{"task.created"}},
+	}}
+	d := newTestDispatcher(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := events.NewBus(0)
+	d.Start(ctx, bus)
+	bus.Publish(events.NewTaskEvent(events.TaskCreated, "abc", nil, ""))
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestDispatcher_SkipsRemoteEvents(t *testing.T) {
+	store := &mockStore{webhooks: []database.Webhook{
+		{ID: primitive.NewObjectID(), URL: "http://127.0.0.1:0", Events: []string{"task.created"}},
+	}}
+	d := newTestDispatcher(store)
+
+	e := events.NewTaskEvent(events.TaskCreated, "abc", nil, "")
+	e.Remote = true
+	d.Dispatch(context.Background(), e)
+	d.Wait()
+
+	assert.Empty(t, store.deliveries)
+}
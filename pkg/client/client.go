@@ -0,0 +1,226 @@
+// Package client is a Go client for the tasks API.
+//
+// Every method takes a context that bounds the whole call, retries included.
+// Requests that fail with a network error, 429 or 5xx are retried with
+// exponential backoff; POST, PUT and PATCH carry an Idempotency-Key so that a
+// retried write is applied at most once. Failed calls return an *Error, which
+// can be matched against ErrNotFound and the other sentinel errors with
+// errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+const (
+	DefaultMaxAttempts = 4
+	DefaultBaseDelay   = 200 * time.Millisecond
+	DefaultMaxDelay    = 5 * time.Second
+)
+
+// inFlightError is the detail of the 409 the server answers while a write
+// with the same Idempotency-Key is still running.
+const inFlightError = "Request with this Idempotency-Key is being processed, retry later"
+
+// Client calls the tasks API of a running server. It is safe for concurrent
+// use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	user       string
+
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.httpClient = h }
+}
+
+// WithToken sends token as a bearer token with every request.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithUser sends user in the X-User-ID header, which the server records as
+// the actor of every change.
+func WithUser(user string) Option {
+	return func(c *Client) { c.user = user }
+}
+
+// WithRetry sets how many times a request is attempted in total and the
+// delay before the first retry, which doubles on every further retry. A
+// maxAttempts of 1 disables retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = max(maxAttempts, 1)
+		c.baseDelay = baseDelay
+	}
+}
+
+// New returns a client for the server at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		httpClient:  http.DefaultClient,
+		maxAttempts: DefaultMaxAttempts,
+		baseDelay:   DefaultBaseDelay,
+		maxDelay:    DefaultMaxDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.user != "" {
+		req.Header.Set("X-User-ID", c.user)
+	}
+	return req, nil
+}
+
+// do sends the request, retrying it when that is safe, and decodes a
+// successful JSON response into out when it is not nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	idempotencyKey := ""
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := c.newRequest(ctx, method, path, body)
+		if err != nil {
+			return nil, err
+		}
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+
+		resp, err := c.httpClient.Do(req)
+
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			defer resp.Body.Close()
+			if out != nil {
+				if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+					return resp, err
+				}
+			}
+			return resp, nil
+		}
+
+		if err == nil {
+			err = errorFromResponse(resp)
+			resp.Body.Close()
+		}
+
+		if ctx.Err() != nil {
+			return resp, ctx.Err()
+		}
+		if attempt >= c.maxAttempts || !retryable(resp, err, idempotencyKey != "") {
+			return resp, err
+		}
+
+		if err := sleep(ctx, c.backoff(attempt, resp)); err != nil {
+			return resp, err
+		}
+	}
+}
+
+// retryable reports whether a failed attempt may succeed when repeated: the
+// request did not reach the server, was throttled, or hit a server error.
+// For keyed writes, the 409 sent while the first attempt under the same
+// Idempotency-Key is still running is retried too; other conflicts are not.
+func retryable(resp *http.Response, err error, keyed bool) bool {
+	if resp == nil {
+		return true
+	}
+	if resp.StatusCode == http.StatusConflict {
+		var apiErr *Error
+		return keyed && errors.As(err, &apiErr) && apiErr.Detail == inFlightError
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff is the delay before the next attempt: the server's Retry-After when
+// it sent one, otherwise an exponentially growing delay with jitter so that
+// clients failing together do not retry together.
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, c.maxDelay)
+		}
+	}
+
+	delay := min(c.baseDelay<<(attempt-1), c.maxDelay)
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
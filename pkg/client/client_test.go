@@ -0,0 +1,451 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tiffany831101/bs_pretest.git/internal/controller"
+	"github.com/tiffany831101/bs_pretest.git/internal/database"
+	"github.com/tiffany831101/bs_pretest.git/internal/middleware"
+	"github.com/tiffany831101/bs_pretest.git/pkg/client"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type memoryDB struct {
+	database.DBInterface
+	mu      sync.Mutex
+	tasks   map[string]database.Task
+	records map[string]database.IdempotencyRecord
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{
+		tasks:   map[string]database.Task{},
+		records: map[string]database.IdempotencyRecord{},
+	}
+}
+
+func (db *memoryDB) GetTasks() ([]database.Task, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tasks := []database.Task{}
+	for _, t := range db.tasks {
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID.Hex() < tasks[j].ID.Hex() })
+	return tasks, nil
+}
+
+func (db *memoryDB) FindTasks(ctx context.Context, filter database.TaskFilter, offset, limit int64) (*database.TaskCursor, error) {
+	all, _ := db.GetTasks()
+
+	tasks := []database.Task{}
+	for _, t := range all {
+		if filter.Matches(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	tasks = tasks[min(offset, int64(len(tasks))):]
+	if limit > 0 {
+		tasks = tasks[:min(limit, int64(len(tasks)))]
+	}
+	return database.NewTaskCursor(tasks)
+}
+
+func (db *memoryDB) GetSubtasks(parentID string) ([]database.Task, error) {
+	tasks, _ := db.GetTasks()
+
+	subtasks := []database.Task{}
+	for _, t := range tasks {
+		if t.ParentID == parentID {
+			subtasks = append(subtasks, t)
+		}
+	}
+	return subtasks, nil
+}
+
+func (db *memoryDB) GetTaskByID(taskID string) (database.Task, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	task, ok := db.tasks[taskID]
+	if !ok {
+		return task, mongo.ErrNoDocuments
+	}
+	return task, nil
+}
+
+func (db *memoryDB) InsertSingleTask(task database.Task) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.tasks[task.ID.Hex()] = task
+	return nil
+}
+
+func (db *memoryDB) UpdateTaskID(taskID string, task database.Task) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.tasks[taskID] = task
+	return nil
+}
+
+func (db *memoryDB) DeleteTaskByID(taskID string) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.tasks[taskID]; !ok {
+		return 0, nil
+	}
+	delete(db.tasks, taskID)
+	return 1, nil
+}
+
+func (db *memoryDB) InsertAuditEntry(entry database.AuditEntry) error {
+	return nil
+}
+
+func (db *memoryDB) InsertIdempotencyRecord(record database.IdempotencyRecord) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.records[record.Key]; ok {
+		return database.ErrIdempotencyKeyExists
+	}
+	db.records[record.Key] = record
+	return nil
+}
+
+func (db *memoryDB) GetIdempotencyRecord(key string) (database.IdempotencyRecord, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	record, ok := db.records[key]
+	if !ok {
+		return record, mongo.ErrNoDocuments
+	}
+	return record, nil
+}
+
+func (db *memoryDB) SaveIdempotencyRecord(record database.IdempotencyRecord) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.records[record.Key] = record
+	return nil
+}
+
+func (db *memoryDB) DeleteIdempotencyRecord(key string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.records, key)
+	return nil
+}
+
+// newTestServer serves the real task routes backed by an in-memory database.
+// The middleware given runs before the routes, letting tests inject failures.
+func newTestServer(t *testing.T, before ...gin.HandlerFunc) (*httptest.Server, *memoryDB) {
+	gin.SetMode(gin.TestMode)
+	db := newMemoryDB()
+	database.MongoDB = db
+
+	r := gin.New()
+	r.Use(before...)
+	r.Use(middleware.RequestContext())
+	controller.NewTasksController()
+	controller.SetUpTasksRoutes(r)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, db
+}
+
+// failFirst answers the first n requests with status, recording the
+// Idempotency-Key of every request.
+func failFirst(n int, status int, keys *[]string) gin.HandlerFunc {
+	var mu sync.Mutex
+	calls := 0
+
+	return func(c *gin.Context) {
+		mu.Lock()
+		calls++
+		call := calls
+		if keys != nil {
+			*keys = append(*keys, c.GetHeader(middleware.IdempotencyKeyHeader))
+		}
+		mu.Unlock()
+
+		if call <= n {
+			c.Header("Retry-After", "0")
+			middleware.AbortWithProblem(c, status, "try again")
+		}
+	}
+}
+
+func TestClient_CRUD(t *testing.T) {
+	srv, db := newTestServer(t)
+	c := client.New(srv.URL, client.WithUser("alice"))
+	ctx := context.Background()
+
+	id, err := c.Create(ctx, client.TaskInput{Name: "Write docs"})
+	assert.NoError(t, err)
+	assert.Len(t, db.tasks, 1)
+
+	task, err := c.Get(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, client.Task{ID: id, Name: "Write docs", Status: client.Incomplete}, task)
+
+	created, err := c.Update(ctx, id, client.TaskInput{Name: "Write more docs"})
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	status := client.Completed
+	task, err = c.Patch(ctx, id, client.TaskPatch{Status: &status})
+	assert.NoError(t, err)
+	assert.Equal(t, client.Task{ID: id, Name: "Write more docs", Status: client.Completed}, task)
+
+	assert.NoError(t, c.Delete(ctx, id))
+
+	_, err = c.Get(ctx, id)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestClient_ListPaginates(t *testing.T) {
+	var pages int
+	srv, _ := newTestServer(t, func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			pages++
+		}
+	})
+	c := client.New(srv.URL)
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		_, err := c.Create(ctx, client.TaskInput{Name: name})
+		assert.NoError(t, err)
+	}
+
+	tasks, err := c.List(ctx, &client.ListOptions{PageSize: 2}).All()
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 5)
+	assert.Equal(t, 3, pages)
+
+	seen := map[string]bool{}
+	for _, task := range tasks {
+		seen[task.ID] = true
+	}
+	assert.Len(t, seen, 5)
+}
+
+func TestClient_RetriesServerErrorsWithSameIdempotencyKey(t *testing.T) {
+	var keys []string
+	srv, db := newTestServer(t, failFirst(2, http.StatusServiceUnavailable, &keys))
+	c := client.New(srv.URL, client.WithRetry(3, time.Millisecond))
+
+	_, err := c.Create(context.Background(), client.TaskInput{Name: "Write docs"})
+
+	assert.NoError(t, err)
+	assert.Len(t, db.tasks, 1)
+	assert.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+}
+
+func TestClient_RetriesRateLimited(t *testing.T) {
+	srv, _ := newTestServer(t, failFirst(1, http.StatusTooManyRequests, nil))
+	c := client.New(srv.URL, client.WithRetry(2, time.Millisecond))
+
+	_, err := c.List(context.Background(), nil).All()
+
+	assert.NoError(t, err)
+}
+
+func TestClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	var keys []string
+	srv, _ := newTestServer(t, failFirst(10, http.StatusInternalServerError, &keys))
+	c := client.New(srv.URL, client.WithRetry(3, time.Millisecond))
+
+	_, err := c.Get(context.Background(), "65a000000000000000000000")
+
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Len(t, keys, 3)
+
+	var apiErr *client.Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "try again", apiErr.Detail)
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	var keys []string
+	srv, _ := newTestServer(t, failFirst(0, 0, &keys))
+	c := client.New(srv.URL, client.WithRetry(3, time.Millisecond))
+
+	_, err := c.Update(context.Background(), "not-hex", client.TaskInput{Name: "x"})
+
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	assert.Len(t, keys, 1)
+}
+
+func TestClient_RetriesInFlightConflict(t *testing.T) {
+	var keys []string
+	srv, db := newTestServer(t, func(c *gin.Context) {
+		keys = append(keys, c.GetHeader(middleware.IdempotencyKeyHeader))
+		if len(keys) == 1 {
+			middleware.AbortWithProblem(c, http.StatusConflict, middleware.IdempotencyInFlightError)
+		}
+	})
+	c := client.New(srv.URL, client.WithRetry(3, time.Millisecond))
+
+	_, err := c.Create(context.Background(), client.TaskInput{Name: "Write docs"})
+
+	assert.NoError(t, err)
+	assert.Len(t, db.tasks, 1)
+	assert.Len(t, keys, 2)
+}
+
+func TestClient_DoesNotRetryOtherConflicts(t *testing.T) {
+	var keys []string
+	srv, _ := newTestServer(t, func(c *gin.Context) {
+		keys = append(keys, c.GetHeader(middleware.IdempotencyKeyHeader))
+		middleware.AbortWithProblem(c, http.StatusConflict, "task is blocked")
+	})
+	c := client.New(srv.URL, client.WithRetry(3, time.Millisecond))
+
+	_, err := c.Create(context.Background(), client.TaskInput{Name: "Write docs"})
+
+	var apiErr *client.Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.ErrorIs(t, err, client.ErrConflict)
+	assert.Equal(t, &client.Error{StatusCode: http.StatusConflict, Type: "about:blank", Title: "Conflict", Detail: "task is blocked"}, apiErr)
+	assert.Len(t, keys, 1)
+}
+
+func TestClient_ErrorWithoutBody(t *testing.T) {
+	srv, _ := newTestServer(t, func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNotFound)
+	})
+	c := client.New(srv.URL, client.WithRetry(1, 0))
+
+	_, err := c.Get(context.Background(), "1")
+
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	assert.EqualError(t, err, "tasks api: 404 Not Found")
+}
+
+func TestClient_ContextCancelsBackoff(t *testing.T) {
+	srv, _ := newTestServer(t, func(c *gin.Context) {
+		middleware.AbortWithProblem(c, http.StatusServiceUnavailable, "down")
+	})
+	c := client.New(srv.URL, client.WithRetry(5, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Get(ctx, "1")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_Watch(t *testing.T) {
+	srv, _ := newTestServer(t)
+	c := client.New(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan client.Event, 1)
+	go c.Watch(ctx, 0, func(e client.Event) error {
+		received <- e
+		return errors.New("stop")
+	})
+
+	// Keep creating until the stream is subscribed and the event arrives.
+	for {
+		_, err := c.Create(ctx, client.TaskInput{Name: "Write docs"})
+		assert.NoError(t, err)
+
+		select {
+		case e := <-received:
+			assert.Equal(t, client.TaskCreated, e.Type)
+			assert.NotNil(t, e.Task)
+			assert.Equal(t, "Write docs", e.Task.Name)
+			assert.NotZero(t, e.Seq)
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no event received")
+		}
+	}
+}
+
+func TestClient_GetTaskFields(t *testing.T) {
+	srv, db := newTestServer(t)
+	c := client.New(srv.URL)
+
+	id := primitive.NewObjectID()
+	due := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	db.tasks[id.Hex()] = database.Task{
+		ID:          id,
+		Name:        "Write docs",
+		ParentID:    "65a000000000000000000001",
+		BlockedBy:   []string{"65a000000000000000000002"},
+		Due:         &due,
+		Recurrence:  "FREQ=WEEKLY",
+		ProjectID:   "65a000000000000000000003",
+		LabelIDs:    []string{"65a000000000000000000004"},
+		AssigneeIDs: []string{"alice"},
+		WatcherIDs:  []string{"bob"},
+	}
+
+	task, err := c.Get(context.Background(), id.Hex())
+
+	assert.NoError(t, err)
+	assert.Equal(t, client.Task{
+		ID:          id.Hex(),
+		Name:        "Write docs",
+		Status:      client.Incomplete,
+		ParentID:    "65a000000000000000000001",
+		BlockedBy:   []string{"65a000000000000000000002"},
+		Due:         &due,
+		Recurrence:  "FREQ=WEEKLY",
+		ProjectID:   "65a000000000000000000003",
+		LabelIDs:    []string{"65a000000000000000000004"},
+		AssigneeIDs: []string{"alice"},
+		WatcherIDs:  []string{"bob"},
+	}, task)
+}
+
+func TestClient_WatchJoinsDataLines(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: task.created\ndata: {\"seq\": 3, \"type\": \"task.created\",\ndata: \"task\": {\"id\": \"1\", \"name\": \"Write\\ndocs\", \"parent_id\": \"2\"}}\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	c := client.New(srv.URL)
+
+	var events []client.Event
+	err := c.Watch(context.Background(), 0, func(e client.Event) error {
+		events = append(events, e)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, uint64(3), events[0].Seq)
+		assert.Equal(t, &client.Task{ID: "1", Name: "Write\ndocs", ParentID: "2"}, events[0].Task)
+	}
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Sentinel errors matched by *Error through errors.Is.
+var (
+	ErrBadRequest  = errors.New("bad request")
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrUnprocessed = errors.New("unprocessable entity")
+	ErrRateLimited = errors.New("rate limited")
+	ErrServer      = errors.New("server error")
+)
+
+// Error is returned when the server answers with a non-2xx status. Type,
+// Title and Detail are read from the problem details body of the response;
+// Title is the status text when the body has none.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Detail     string `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("tasks api: %d %s", e.StatusCode, e.Title)
+	}
+	return fmt.Sprintf("tasks api: %d %s", e.StatusCode, e.Detail)
+}
+
+// Is maps the status code to one of the sentinel errors.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnprocessed:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+func errorFromResponse(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	json.Unmarshal(b, apiErr)
+
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
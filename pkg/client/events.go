@@ -0,0 +1,91 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	TaskCreated EventType = "task.created"
+	TaskUpdated EventType = "task.updated"
+	TaskDeleted EventType = "task.deleted"
+
+	// Reset is delivered when the server could not replay every event since
+	// the last one seen; the task list should be reloaded.
+	Reset EventType = "reset"
+)
+
+type Event struct {
+	Seq       uint64    `json:"seq" yaml:"seq"`
+	Type      EventType `json:"type" yaml:"type"`
+	TaskID    string    `json:"task_id" yaml:"task_id"`
+	Task      *Task     `json:"task,omitempty" yaml:"task,omitempty"`
+	Actor     string    `json:"actor,omitempty" yaml:"actor,omitempty"`
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+}
+
+// Watch streams task events to handle until ctx is cancelled, handle returns
+// an error or the connection drops. lastSeq resumes after that event; pass 0
+// to receive only new events.
+func (c *Client) Watch(ctx context.Context, lastSeq uint64, handle func(Event) error) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "/tasks/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastSeq > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(lastSeq, 10))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errorFromResponse(resp)
+	}
+
+	// A data field may span several data lines, which are joined with
+	// newlines as the event stream format requires.
+	var eventType string
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if eventType == "" && data == nil {
+				continue
+			}
+			e := Event{Type: EventType(eventType)}
+			if eventType != string(Reset) {
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &e); err != nil {
+					return err
+				}
+			}
+			if err := handle(e); err != nil {
+				return err
+			}
+			eventType, data = "", nil
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
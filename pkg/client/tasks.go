@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+type Status int
+
+const (
+	Incomplete Status = 0
+	Completed  Status = 1
+)
+
+func (s Status) String() string {
+	if s == Completed {
+		return "completed"
+	}
+	return "incomplete"
+}
+
+type Task struct {
+	ID          string     `json:"id" yaml:"id"`
+	Name        string     `json:"name" yaml:"name"`
+	Status      Status     `json:"status" yaml:"status"`
+	ParentID    string     `json:"parent_id,omitempty" yaml:"parent_id,omitempty"`
+	BlockedBy   []string   `json:"blocked_by,omitempty" yaml:"blocked_by,omitempty"`
+	Due         *time.Time `json:"due,omitempty" yaml:"due,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty" yaml:"recurrence,omitempty"`
+	ProjectID   string     `json:"project_id,omitempty" yaml:"project_id,omitempty"`
+	LabelIDs    []string   `json:"label_ids,omitempty" yaml:"label_ids,omitempty"`
+	AssigneeIDs []string   `json:"assignee_ids,omitempty" yaml:"assignee_ids,omitempty"`
+	WatcherIDs  []string   `json:"watcher_ids,omitempty" yaml:"watcher_ids,omitempty"`
+}
+
+// taskResponse is a task as the REST API encodes it, under its Go field
+// names rather than the snake_case names of Task and of task events.
+type taskResponse struct {
+	ID          string
+	Name        string
+	Status      Status
+	ParentID    string
+	BlockedBy   []string
+	Due         *time.Time
+	Recurrence  string
+	ProjectID   string
+	LabelIDs    []string
+	AssigneeIDs []string
+	WatcherIDs  []string
+}
+
+// TaskInput is the body of a create or update request.
+type TaskInput struct {
+	Name       string     `json:"name"`
+	Status     Status     `json:"status"`
+	Due        *time.Time `json:"due,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"`
+	ProjectID  string     `json:"project_id,omitempty"`
+	LabelIDs   []string   `json:"label_ids,omitempty"`
+}
+
+// TaskPatch is the body of a partial update; nil fields are left unchanged.
+// An empty ParentID makes a subtask a top-level task again, BlockedBy and
+// LabelIDs replace the whole list, an empty Recurrence stops the task from
+// recurring and an empty ProjectID takes it out of its project.
+type TaskPatch struct {
+	Name       *string    `json:"name,omitempty"`
+	Status     *Status    `json:"status,omitempty"`
+	ParentID   *string    `json:"parent_id,omitempty"`
+	BlockedBy  *[]string  `json:"blocked_by,omitempty"`
+	Due        *time.Time `json:"due,omitempty"`
+	Recurrence *string    `json:"recurrence,omitempty"`
+	ProjectID  *string    `json:"project_id,omitempty"`
+	LabelIDs   *[]string  `json:"label_ids,omitempty"`
+}
+
+// DefaultPageSize is the number of tasks List fetches per request.
+const DefaultPageSize = 100
+
+// ListOptions configures List.
+type ListOptions struct {
+	// PageSize is the number of tasks fetched per request, DefaultPageSize
+	// when zero.
+	PageSize int
+}
+
+// TaskIterator walks the task list one page at a time:
+//
+//	it := c.List(ctx, nil)
+//	for it.Next() {
+//		task := it.Task()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type TaskIterator struct {
+	ctx      context.Context
+	client   *Client
+	pageSize int
+
+	offset int
+	page   []Task
+	pos    int
+	done   bool
+	err    error
+}
+
+// List returns an iterator over every task, ordered by ID. Pages are
+// requested as the iterator advances.
+func (c *Client) List(ctx context.Context, opts *ListOptions) *TaskIterator {
+	pageSize := DefaultPageSize
+	if opts != nil && opts.PageSize > 0 {
+		pageSize = opts.PageSize
+	}
+	return &TaskIterator{ctx: ctx, client: c, pageSize: pageSize}
+}
+
+// Next advances to the next task, fetching the next page when needed. It
+// returns false at the end of the list or on error.
+func (it *TaskIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.pos+1 < len(it.page) {
+		it.pos++
+		return true
+	}
+
+	if it.done {
+		return false
+	}
+
+	page, err := it.client.ListPage(it.ctx, it.offset, it.pageSize)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.offset += len(page)
+	it.page = page
+	it.pos = 0
+	it.done = len(page) < it.pageSize
+
+	return len(page) > 0
+}
+
+// Task returns the task Next advanced to.
+func (it *TaskIterator) Task() Task {
+	return it.page[it.pos]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *TaskIterator) Err() error {
+	return it.err
+}
+
+// All drains the iterator into a slice.
+func (it *TaskIterator) All() ([]Task, error) {
+	tasks := []Task{}
+	for it.Next() {
+		tasks = append(tasks, it.Task())
+	}
+	return tasks, it.Err()
+}
+
+// ListPage returns up to limit tasks after skipping offset, ordered by ID.
+func (c *Client) ListPage(ctx context.Context, offset, limit int) ([]Task, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	var page []taskResponse
+	if _, err := c.do(ctx, http.MethodGet, "/tasks/?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]Task, len(page))
+	for i, task := range page {
+		tasks[i] = Task(task)
+	}
+	return tasks, nil
+}
+
+// Get returns the task with id.
+func (c *Client) Get(ctx context.Context, id string) (Task, error) {
+	var task taskResponse
+	_, err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id), nil, &task)
+	return Task(task), err
+}
+
+// Create creates a task and returns its ID.
+func (c *Client) Create(ctx context.Context, in TaskInput) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/tasks/", in, nil)
+	if err != nil {
+		return "", err
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", errors.New("tasks api: created task has no Location")
+	}
+	return path.Base(location), nil
+}
+
+// Update replaces the task with id, creating it when it does not exist.
+func (c *Client) Update(ctx context.Context, id string, in TaskInput) (created bool, err error) {
+	resp, err := c.do(ctx, http.MethodPut, "/tasks/"+url.PathEscape(id), in, nil)
+	if err != nil {
+		return false, err
+	}
+	return resp.StatusCode == http.StatusCreated, nil
+}
+
+// Patch changes the fields set in patch and returns the updated task.
+func (c *Client) Patch(ctx context.Context, id string, patch TaskPatch) (Task, error) {
+	var task taskResponse
+	_, err := c.do(ctx, http.MethodPatch, "/tasks/"+url.PathEscape(id), patch, &task)
+	return Task(task), err
+}
+
+// Delete deletes the task with id.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/tasks/"+url.PathEscape(id), nil, nil)
+	return err
+}
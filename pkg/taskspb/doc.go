@@ -0,0 +1,6 @@
+// Package taskspb holds the protobuf messages and gRPC client and server code
+// for the TaskService defined in proto/tasks/v1/tasks.proto. Other services
+// can import it to call the gRPC API.
+package taskspb
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/tiffany831101/bs_pretest.git --go-grpc_out=../.. --go-grpc_opt=module=github.com/tiffany831101/bs_pretest.git tasks/v1/tasks.proto